@@ -0,0 +1,33 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    https://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	// The time zones of the schedule and mute windows do not depend on the image.
+	_ "time/tzdata"
+
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
+
+	"knative.dev/eventing-prometheus/pkg/mtadapter"
+)
+
+func main() {
+	ctx := signals.NewContext()
+	ctx = adapter.WithController(ctx, mtadapter.NewController)
+	adapter.MainWithContext(ctx, "prometheussource-mt-adapter", mtadapter.NewEnvConfig, mtadapter.NewAdapter)
+}
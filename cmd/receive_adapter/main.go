@@ -17,11 +17,18 @@ limitations under the License.
 package main
 
 import (
+	// The time zones of the schedule and mute windows do not depend on the image.
+	_ "time/tzdata"
+
 	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/signals"
 
 	prometheusadapter "knative.dev/eventing-prometheus/pkg/adapter"
 )
 
 func main() {
-	adapter.Main("prometheussource", prometheusadapter.NewEnvConfig, prometheusadapter.NewAdapter)
+	// The adapter records events on its source and elects a leader among its
+	// replicas with the Kubernetes client injected in its context.
+	ctx := adapter.WithInjectorEnabled(signals.NewContext())
+	adapter.MainWithContext(ctx, "prometheussource", prometheusadapter.NewEnvConfig, prometheusadapter.NewAdapter)
 }
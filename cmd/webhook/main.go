@@ -18,27 +18,38 @@ package main
 
 import (
 	"context"
+	// The time zones of the schedules and mute windows are validated without
+	// depending on the image.
+	_ "time/tzdata"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	apisconfig "knative.dev/eventing-prometheus/pkg/apis/config"
 	sourcev1alpha1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	sourcev1beta1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1beta1"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
 	"knative.dev/pkg/injection/sharedmain"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/signals"
 	"knative.dev/pkg/webhook"
 	"knative.dev/pkg/webhook/certificates"
 	"knative.dev/pkg/webhook/resourcesemantics"
+	"knative.dev/pkg/webhook/resourcesemantics/conversion"
 	"knative.dev/pkg/webhook/resourcesemantics/defaulting"
 	"knative.dev/pkg/webhook/resourcesemantics/validation"
 )
 
 var types = map[schema.GroupVersionKind]resourcesemantics.GenericCRD{
 	sourcev1alpha1.SchemeGroupVersion.WithKind("PrometheusSource"): &sourcev1alpha1.PrometheusSource{},
+	sourcev1beta1.SchemeGroupVersion.WithKind("PrometheusSource"):  &sourcev1beta1.PrometheusSource{},
 }
 
 var callbacks = map[schema.GroupVersionKind]validation.Callback{}
 
 func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	store := apisconfig.NewStore(logging.FromContext(ctx).Named("config-store"))
+	store.WatchConfigs(cmw)
+
 	return defaulting.NewAdmissionController(ctx,
 
 		// Name of the resource webhook.
@@ -51,11 +62,7 @@ func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher
 		types,
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			// Here is where you would infuse the context with state
-			// (e.g. attach a store with configmap data)
-			return ctx
-		},
+		store.ToContext,
 
 		// Whether to disallow unknown fields.
 		true,
@@ -63,7 +70,10 @@ func NewDefaultingAdmissionController(ctx context.Context, cmw configmap.Watcher
 }
 
 func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
-	return validation.NewAdmissionController(ctx,
+	store := apisconfig.NewStore(logging.FromContext(ctx).Named("config-store"))
+	store.WatchConfigs(cmw)
+
+	impl := validation.NewAdmissionController(ctx,
 
 		// Name of the resource webhook.
 		"validation.webhook.prometheus.sources.knative.dev",
@@ -75,11 +85,7 @@ func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 		types,
 
 		// A function that infuses the context passed to Validate/SetDefaults with custom metadata.
-		func(ctx context.Context) context.Context {
-			// Here is where you would infuse the context with state
-			// (e.g. attach a store with configmap data)
-			return ctx
-		},
+		store.ToContext,
 
 		// Whether to disallow unknown fields.
 		true,
@@ -87,6 +93,36 @@ func NewValidationAdmissionController(ctx context.Context, cmw configmap.Watcher
 		// Extra validating callbacks to be applied to resources.
 		callbacks,
 	)
+
+	// Report the query policy violations that do not reject the resource as
+	// admission warnings.
+	impl.Reconciler = newWarningAdmissionController(impl.Reconciler, store.ToContext)
+	return impl
+}
+
+func NewConversionController(ctx context.Context, cmw configmap.Watcher) *controller.Impl {
+	return conversion.NewConversionController(ctx,
+
+		// The path on which to serve the webhook
+		"/resource-conversion",
+
+		// Specify the types of custom resource definitions that should be converted
+		map[schema.GroupKind]conversion.GroupKindConversion{
+			sourcev1beta1.Kind("PrometheusSource"): {
+				DefinitionName: "prometheussources.sources.knative.dev",
+				HubVersion:     sourcev1beta1.SchemeGroupVersion.Version,
+				Zygotes: map[string]conversion.ConvertibleObject{
+					sourcev1alpha1.SchemeGroupVersion.Version: &sourcev1alpha1.PrometheusSource{},
+					sourcev1beta1.SchemeGroupVersion.Version:  &sourcev1beta1.PrometheusSource{},
+				},
+			},
+		},
+
+		// A function that infuses the context passed to ConvertTo/ConvertFrom/SetDefaults with custom metadata.
+		func(ctx context.Context) context.Context {
+			return ctx
+		},
+	)
 }
 
 func main() {
@@ -100,5 +136,6 @@ func main() {
 		certificates.NewController,
 		NewDefaultingAdmissionController,
 		NewValidationAdmissionController,
+		NewConversionController,
 	)
 }
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+	"knative.dev/pkg/webhook"
+)
+
+// warner is implemented by resources that can report admission warnings.
+type warner interface {
+	Warnings(ctx context.Context) []string
+}
+
+// admissionReconciler is the set of interfaces implemented by the validation
+// admission controller that the webhook main relies on.
+type admissionReconciler interface {
+	webhook.AdmissionController
+	controller.Reconciler
+	pkgreconciler.LeaderAware
+}
+
+// warningAdmissionController decorates an admission controller, adding to its
+// allowed responses the warnings reported by the admitted resource.
+type warningAdmissionController struct {
+	admissionReconciler
+
+	withContext func(context.Context) context.Context
+}
+
+func newWarningAdmissionController(r controller.Reconciler, wc func(context.Context) context.Context) controller.Reconciler {
+	return &warningAdmissionController{
+		admissionReconciler: r.(admissionReconciler),
+		withContext:         wc,
+	}
+}
+
+// Admit implements webhook.AdmissionController
+func (ac *warningAdmissionController) Admit(ctx context.Context, request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	resp := ac.admissionReconciler.Admit(ctx, request)
+	if !resp.Allowed || len(request.Object.Raw) == 0 {
+		return resp
+	}
+	if request.Operation != admissionv1.Create && request.Operation != admissionv1.Update {
+		return resp
+	}
+
+	gvk := schema.GroupVersionKind{
+		Group:   request.Kind.Group,
+		Version: request.Kind.Version,
+		Kind:    request.Kind.Kind,
+	}
+	handler, ok := types[gvk]
+	if !ok {
+		return resp
+	}
+
+	obj := handler.DeepCopyObject()
+	w, ok := obj.(warner)
+	if !ok {
+		return resp
+	}
+	if err := json.Unmarshal(request.Object.Raw, obj); err != nil {
+		logging.FromContext(ctx).Warnw("Failed to decode resource for admission warnings", "error", err)
+		return resp
+	}
+	resp.Warnings = append(resp.Warnings, w.Warnings(ac.withContext(ctx))...)
+	return resp
+}
@@ -3,12 +3,20 @@ module knative.dev/eventing-prometheus
 go 1.15
 
 require (
+	cloud.google.com/go/iam v0.3.0 // indirect
 	github.com/cloudevents/sdk-go/v2 v2.8.0
-	github.com/google/go-cmp v0.5.6
-	github.com/influxdata/tdigest v0.0.1 // indirect
+	github.com/golang/protobuf v1.5.2
+	github.com/google/go-cmp v0.5.7
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/prometheus/client_model v0.2.0
+	github.com/prometheus/common v0.34.0
+	github.com/prometheus/prometheus v1.8.2-0.20220117154355-4855a0c067e2
+	github.com/rickb777/date v1.13.0
 	github.com/robfig/cron v1.2.0
+	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.19.1
+	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65
+	gopkg.in/yaml.v2 v2.4.0
 	k8s.io/api v0.22.5
 	k8s.io/apimachinery v0.22.5
 	k8s.io/client-go v0.22.5
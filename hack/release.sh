@@ -25,6 +25,7 @@ export GO111MODULE=on
 declare -A COMPONENTS
 COMPONENTS=(
   ["prometheus-source.yaml"]="config"
+  ["prometheus-source-post-install.yaml"]="config/post-install"
 )
 readonly COMPONENTS
 
@@ -23,6 +23,9 @@ import (
 	_ "knative.dev/hack"
 	_ "knative.dev/pkg/hack"
 
+	// Migrate the stored PrometheusSources to the storage version
+	_ "knative.dev/pkg/apiextensions/storageversion/cmd/migrate"
+
 	// Test images from eventing
 	_ "knative.dev/eventing/test/test_images/event-sender"
 	_ "knative.dev/eventing/test/test_images/heartbeats"
@@ -34,7 +34,7 @@ group "Kubernetes Codegen"
 #                  instead of the $GOPATH directly. For normal projects this can be dropped.
 ${CODEGEN_PKG}/generate-groups.sh "deepcopy,client,informer,lister" \
               "knative.dev/eventing-prometheus/pkg/client" "knative.dev/eventing-prometheus/pkg/apis" \
-              "sources:v1alpha1" \
+              "sources:v1alpha1,v1beta1" \
               --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
 
 group "Knative Codegen"
@@ -42,7 +42,7 @@ group "Knative Codegen"
 # Knative Injection
 ${KNATIVE_CODEGEN_PKG}/hack/generate-knative.sh "injection" \
                       "knative.dev/eventing-prometheus/pkg/client" "knative.dev/eventing-prometheus/pkg/apis" \
-                      "sources:v1alpha1" \
+                      "sources:v1alpha1,v1beta1" \
                       --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt
 
 group "Deepcopy Gen"
@@ -51,7 +51,7 @@ group "Deepcopy Gen"
 ${GOPATH}/bin/deepcopy-gen \
   -O zz_generated.deepcopy \
   --go-header-file ${REPO_ROOT_DIR}/hack/boilerplate.go.txt \
-  -i knative.dev/eventing-prometheus/pkg/apis \
+  -i knative.dev/eventing-prometheus/pkg/apis,knative.dev/eventing-prometheus/pkg/apis/config \
 
 group "Update deps post-codegen"
 
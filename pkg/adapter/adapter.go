@@ -18,24 +18,73 @@ package adapter
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
-	"github.com/robfig/cron"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
 	"go.uber.org/zap"
-	"k8s.io/apimachinery/pkg/util/uuid"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	"knative.dev/eventing/pkg/adapter/v2"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	"knative.dev/pkg/logging"
 
 	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
 )
 
+const (
+	// truncatedExtension marks the events carrying a truncated query result.
+	truncatedExtension = "truncated"
+	// limitExceededExtension names the limit the query result exceeded.
+	limitExceededExtension = "limitexceeded"
+	// chunkExtension numbers, from 1, the events a split query result was sent in.
+	chunkExtension = "chunk"
+	// lastChunkExtension marks the last event a split query result was sent in.
+	lastChunkExtension = "lastchunk"
+	// evalTimeExtension is the evaluation time of an instant query, and
+	// evalStartExtension and evalEndExtension the start and end of a range
+	// query, the events of the evaluation were sent for.
+	evalTimeExtension  = "evaltime"
+	evalStartExtension = "evalstart"
+	evalEndExtension   = "evalend"
+	// sequenceExtension orders the events sent by the adapter, by the
+	// evaluation time of the query then from 1 within an evaluation.
+	sequenceExtension = "sequence"
+	// errorDestExtension is the sink an event sent to the dead letter sink
+	// could not be delivered to.
+	errorDestExtension = "knativeerrordest"
+	// errorCodeExtension is the HTTP status code of the last delivery
+	// attempt of an event sent to the dead letter sink.
+	errorCodeExtension = "knativeerrorcode"
+)
+
+// errNotDelivered stops streaming a query result once one of its events
+// could not be created or delivered, the error has already been logged.
+var errNotDelivered = errors.New("event not delivered")
+
 type envConfig struct {
 	adapter.EnvConfig
 
@@ -45,13 +94,90 @@ type envConfig struct {
 	AuthTokenFile   string `envconfig:"PROMETHEUS_AUTH_TOKEN_FILE" required:"false"`
 	CACertConfigMap string `envconfig:"PROMETHEUS_CA_CERT_CONFIG_MAP" required:"false"`
 	Schedule        string `envconfig:"PROMETHEUS_SCHEDULE" required:"true"`
+	TimeZone        string `envconfig:"PROMETHEUS_TIME_ZONE" required:"false"`
 	Step            string `envconfig:"PROMETHEUS_STEP" required:"false"`
+	AlignToStep     bool   `envconfig:"PROMETHEUS_ALIGN_TO_STEP" required:"false"`
+	QueryTimeout    string `envconfig:"PROMETHEUS_QUERY_TIMEOUT" required:"false"`
+	EventType       string `envconfig:"PROMETHEUS_EVENT_TYPE" required:"false"`
+	Subject         string `envconfig:"PROMETHEUS_EVENT_SUBJECT" required:"false"`
+
+	SourceUID        string `envconfig:"PROMETHEUS_SOURCE_UID" required:"false"`
+	MaxSeries        int64  `envconfig:"PROMETHEUS_MAX_SERIES" required:"false"`
+	MaxSamples       int64  `envconfig:"PROMETHEUS_MAX_SAMPLES" required:"false"`
+	MaxResponseBytes int64  `envconfig:"PROMETHEUS_MAX_RESPONSE_BYTES" required:"false"`
+	OverflowPolicy   string `envconfig:"PROMETHEUS_OVERFLOW_POLICY" required:"false"`
+	SeriesPerEvent   int64  `envconfig:"PROMETHEUS_SERIES_PER_EVENT" required:"false"`
+
+	// LabelExtensions maps extension attribute names to the series labels
+	// promoted to them, in the name:label,name:label format.
+	LabelExtensions    map[string]string `envconfig:"PROMETHEUS_LABEL_EXTENSIONS" required:"false"`
+	PartitionKeyLabels []string          `envconfig:"PROMETHEUS_PARTITION_KEY_LABELS" required:"false"`
+
+	// TemplateText or TemplateJSON renders the data of the events.
+	TemplateText    string `envconfig:"PROMETHEUS_TEMPLATE_TEXT" required:"false"`
+	TemplateJSON    string `envconfig:"PROMETHEUS_TEMPLATE_JSON" required:"false"`
+	DataContentType string `envconfig:"PROMETHEUS_DATA_CONTENT_TYPE" required:"false"`
+	DataFormat      string `envconfig:"PROMETHEUS_DATA_FORMAT" required:"false"`
+
+	// RelabelConfigs are the JSON relabel configs of the source.
+	RelabelConfigs string `envconfig:"PROMETHEUS_RELABEL_CONFIGS" required:"false"`
+
+	OnEmptyResult   string `envconfig:"PROMETHEUS_ON_EMPTY_RESULT" required:"false"`
+	NoDataThreshold int64  `envconfig:"PROMETHEUS_NO_DATA_THRESHOLD" required:"false"`
+
+	// The delivery spec of the source, its backoff delay as a Go duration.
+	DeliveryRetry         int32         `envconfig:"PROMETHEUS_DELIVERY_RETRY" required:"false"`
+	DeliveryBackoffPolicy string        `envconfig:"PROMETHEUS_DELIVERY_BACKOFF_POLICY" required:"false"`
+	DeliveryBackoffDelay  time.Duration `envconfig:"PROMETHEUS_DELIVERY_BACKOFF_DELAY" required:"false"`
+	DeadLetterSink        string        `envconfig:"PROMETHEUS_DEAD_LETTER_SINK" required:"false"`
+
+	// Jitter is the maximum delay of the evaluations after the ticks of the
+	// schedule, StartingDeadline how late a missed evaluation may start.
+	Jitter           time.Duration `envconfig:"PROMETHEUS_JITTER" required:"false"`
+	StartingDeadline time.Duration `envconfig:"PROMETHEUS_STARTING_DEADLINE" required:"false"`
+
+	// EvaluationDelay shifts the evaluation time back from the ticks.
+	EvaluationDelay time.Duration `envconfig:"PROMETHEUS_EVALUATION_DELAY" required:"false"`
+
+	// The outbox of the source, in the directory its volume is mounted at.
+	OutboxDir       string        `envconfig:"PROMETHEUS_OUTBOX_DIR" required:"false"`
+	OutboxMaxEvents int64         `envconfig:"PROMETHEUS_OUTBOX_MAX_EVENTS" required:"false"`
+	OutboxMaxBytes  int64         `envconfig:"PROMETHEUS_OUTBOX_MAX_BYTES" required:"false"`
+	OutboxMaxAge    time.Duration `envconfig:"PROMETHEUS_OUTBOX_MAX_AGE" required:"false"`
+
+	// The rate limit of the source, none if RateLimitEvents is zero.
+	RateLimitEvents   int64         `envconfig:"PROMETHEUS_RATE_LIMIT_EVENTS" required:"false"`
+	RateLimitInterval time.Duration `envconfig:"PROMETHEUS_RATE_LIMIT_INTERVAL" required:"false"`
+	RateLimitBurst    int64         `envconfig:"PROMETHEUS_RATE_LIMIT_BURST" required:"false"`
+
+	// MuteWindows are the JSON mute windows of the source. AnnotationsFile is
+	// the downward API file of the annotations of the pod, which holds the
+	// mute-until annotation of the source.
+	MuteWindows     string `envconfig:"PROMETHEUS_MUTE_WINDOWS" required:"false"`
+	AnnotationsFile string `envconfig:"PROMETHEUS_ANNOTATIONS_FILE" required:"false"`
+
+	// SuspendedAt and ResumedAt are when the source was last suspended and
+	// resumed, if it was resumed since.
+	SuspendedAt time.Time `envconfig:"PROMETHEUS_SUSPENDED_AT" required:"false"`
+	ResumedAt   time.Time `envconfig:"PROMETHEUS_RESUMED_AT" required:"false"`
+
+	// RunOnce evaluates the query once and exits, in the Jobs of the CronJob
+	// of a source with the cronJob run mode. JobName is the name of the Job,
+	// after the tick of the schedule it was created for.
+	RunOnce bool   `envconfig:"PROMETHEUS_RUN_ONCE" required:"false"`
+	JobName string `envconfig:"PROMETHEUS_JOB_NAME" required:"false"`
+
+	// LeaseName is the Lease the replicas of the receive adapter elect the
+	// one evaluating the query with, PodName identifies the replica.
+	LeaseName string `envconfig:"PROMETHEUS_LEASE_NAME" required:"false"`
+	PodName   string `envconfig:"POD_NAME" required:"false"`
 }
 
 type prometheusAdapter struct {
 	source          string
 	ce              cloudevents.Client
 	namespace       string
+	name            string
 	logger          *zap.SugaredLogger
 	serverURL       string
 	promQL          string
@@ -59,10 +185,116 @@ type prometheusAdapter struct {
 	authToken       string
 	caCertConfigMap string
 	schedule        string
+	timeZone        string
 	step            string
+	queryTimeout    string
+	eventType       string
+	subject         string
+	// subjectTemplate is the parsed subject, nil when the subject of the
+	// events is the name of the source.
+	subjectTemplate *template.Template
 	lastRun         time.Time
 	req             *http.Request
 	client          *http.Client
+	limits          resultLimits
+	seriesPerEvent  int64
+	reporter        statsReporter
+	// recorder records Kubernetes events on the PrometheusSource, it is nil
+	// when the adapter does not know which PrometheusSource it runs for.
+	recorder  record.EventRecorder
+	sourceRef *v1alpha1.PrometheusSource
+	sourceUID string
+	// labelExtensions maps extension attribute names to series labels.
+	labelExtensions    map[string]string
+	partitionKeyLabels []string
+	templateText       string
+	templateJSON       string
+	// dataTemplate renders the data of the events, it is nil when the data
+	// is the query response.
+	dataTemplate    *datatemplate.Template
+	dataContentType string
+	dataFormat      v1alpha1.DataFormat
+	// relabelConfigs relabel the series of the query results, they are
+	// parsed into relabeling when the adapter starts.
+	relabelConfigs string
+	relabeling     []*relabel.Config
+	onEmptyResult  v1alpha1.EmptyResultPolicy
+	// noDataThreshold is the number of consecutive empty evaluations after
+	// which the nodata event is sent.
+	noDataThreshold int64
+	// emptyEvaluations counts the consecutive evaluations which returned no
+	// series, since emptySince.
+	emptyEvaluations int64
+	emptySince       time.Time
+	// noData is true once the nodata event has been sent, until the
+	// datareturned event is.
+	noData bool
+	// sink is the sink the events are sent to, recorded on the events sent
+	// to the dead letter sink.
+	sink string
+	// retry is the number of times an event is retried before it is sent to
+	// the dead letter sink, if any, with the backoff policy and delay.
+	retry          int
+	backoffPolicy  eventingduckv1.BackoffPolicyType
+	backoffDelay   time.Duration
+	deadLetterSink string
+	// outbox queues the events while the sink is unavailable, it is nil when
+	// the source has no outbox. It is opened from outboxDir when the adapter
+	// starts.
+	outbox          *outbox
+	outboxDir       string
+	outboxMaxEvents int64
+	outboxMaxBytes  int64
+	outboxMaxAge    time.Duration
+	// rateLimiter suppresses the query result events exceeding the rate
+	// limit of the source, it is nil when the source has none.
+	rateLimiter *rateLimiter
+	// muteWindowsJSON are the mute windows of the source, parsed into
+	// muteWindows when the adapter starts. readMuteUntil reads the time the
+	// mute-until annotation mutes the source until, into muteUntil before
+	// every evaluation. muted is true while the source is muted by them or
+	// until muteUntil.
+	muteWindowsJSON string
+	muteWindows     []muteWindow
+	readMuteUntil   func() (time.Time, error)
+	muteUntil       time.Time
+	muted           bool
+	// jitter is the maximum delay of the evaluations of the source, and
+	// startingDeadline how late a missed evaluation may start.
+	jitter           time.Duration
+	startingDeadline time.Duration
+	// alignToStep aligns the range queries to multiples of the step, which
+	// is parsed into stepDuration when the adapter starts.
+	alignToStep     bool
+	stepDuration    time.Duration
+	evaluationDelay time.Duration
+	// evalStart and evalEnd are the start and end of the range query of the
+	// evaluation in progress, evalStart is zero for an instant query.
+	evalStart time.Time
+	evalEnd   time.Time
+	// sequenceTime is the evaluation time of the last event sent, and
+	// sequenceIndex the number of events of that evaluation sent so far.
+	sequenceTime  time.Time
+	sequenceIndex int
+	// suspendedAt and resumedAt are when the source was last suspended and
+	// resumed, the adapter catches up from the suspension when it starts.
+	suspendedAt time.Time
+	resumedAt   time.Time
+	// runOnce evaluates the query once, at the tick of the schedule the Job
+	// jobName was created for, instead of running the schedule.
+	runOnce bool
+	jobName string
+	// caCert, when set, replaces the CA certificate ConfigMap of the source,
+	// and transport wraps the transport of the queries, in the receive
+	// adapters run by the shared adapter.
+	caCert    func() ([]byte, error)
+	transport func(http.RoundTripper) http.RoundTripper
+	// leaseName is the Lease the replicas of the receive adapter elect the
+	// one evaluating the query with, in leases, none if it is empty. identity
+	// identifies the replica.
+	leaseName string
+	identity  string
+	leases    coordinationv1client.LeasesGetter
 }
 
 func NewEnvConfig() adapter.EnvConfigAccessor {
@@ -71,26 +303,166 @@ func NewEnvConfig() adapter.EnvConfigAccessor {
 
 // NewAdapter creates an adapter to convert PromQL replies to CloudEvents
 func NewAdapter(ctx context.Context, processed adapter.EnvConfigAccessor, ceClient cloudevents.Client) adapter.Adapter {
-	logger := logging.FromContext(ctx)
 	env := processed.(*envConfig)
+	a := newAdapter(ctx, env, ceClient)
+	// Recording events on the source is best effort, without a Kubernetes
+	// client in the context they are only logged.
+	if a.sourceRef != nil && ctx.Value(kubeclient.Key{}) != nil {
+		a.recorder = NewEventRecorder(ctx, env.Namespace)
+	}
+	if env.LeaseName != "" {
+		a.leaseName = env.LeaseName
+		a.identity = env.PodName
+		if a.identity == "" {
+			a.identity, _ = os.Hostname()
+		}
+		a.leases = kubeclient.Get(ctx).CoordinationV1()
+	}
+	return a
+}
+
+// SourceOptions isolate the receive adapter of a source run by the shared
+// adapter from the receive adapters of the other sources it runs.
+type SourceOptions struct {
+	// CACert returns the PEM CA certificate of the Prometheus server, in
+	// place of the one mounted from the CA certificate ConfigMap of the
+	// source.
+	CACert func() ([]byte, error)
+	// Transport wraps the transport of the queries of the source.
+	Transport func(http.RoundTripper) http.RoundTripper
+	// Recorder records the Kubernetes events on the source.
+	Recorder record.EventRecorder
+	// MuteUntil returns the time the mute-until annotation of the source
+	// mutes it until, in place of the annotations file of the pod.
+	MuteUntil func() (time.Time, error)
+}
+
+// NewSourceAdapter creates the receive adapter of a source run by the shared
+// adapter, configured by the given environment rather than the one of the
+// process. The token file of a source is not supported, it would be read
+// from the file system of the shared adapter.
+func NewSourceAdapter(ctx context.Context, vars map[string]string, ceClient cloudevents.Client, opts SourceOptions) (adapter.Adapter, error) {
+	env := &envConfig{}
+	if err := processEnv(vars, env); err != nil {
+		return nil, err
+	}
+	if env.AuthTokenFile != "" {
+		return nil, errors.New("the token file of the source is not supported by the shared adapter")
+	}
+	a := newAdapter(ctx, env, ceClient)
+	a.caCert = opts.CACert
+	a.transport = opts.Transport
+	a.readMuteUntil = opts.MuteUntil
+	if a.sourceRef != nil {
+		a.recorder = opts.Recorder
+	}
+	return a, nil
+}
+
+func newAdapter(ctx context.Context, env *envConfig, ceClient cloudevents.Client) *prometheusAdapter {
+	logger := logging.FromContext(ctx)
 
 	a := &prometheusAdapter{
 		source:          env.EventSource,
 		ce:              ceClient,
 		logger:          logger,
 		namespace:       env.Namespace,
+		name:            env.Name,
 		serverURL:       env.ServerURL,
 		promQL:          env.PromQL,
 		authTokenFile:   env.AuthTokenFile,
 		caCertConfigMap: env.CACertConfigMap,
 		schedule:        env.Schedule,
+		timeZone:        env.TimeZone,
 		step:            env.Step,
-		lastRun:         time.Now(),
+		queryTimeout:    env.QueryTimeout,
+		eventType:       env.EventType,
+		subject:         env.Subject,
+		lastRun:         time.Now().Add(-env.EvaluationDelay),
+		sourceUID:       env.SourceUID,
+		limits: resultLimits{
+			maxSeries:  env.MaxSeries,
+			maxSamples: env.MaxSamples,
+			maxBytes:   env.MaxResponseBytes,
+			policy:     v1alpha1.OverflowPolicy(env.OverflowPolicy),
+		},
+		seriesPerEvent:     env.SeriesPerEvent,
+		labelExtensions:    env.LabelExtensions,
+		partitionKeyLabels: env.PartitionKeyLabels,
+		templateText:       env.TemplateText,
+		templateJSON:       env.TemplateJSON,
+		dataContentType:    env.DataContentType,
+		dataFormat:         v1alpha1.DataFormat(env.DataFormat),
+		relabelConfigs:     env.RelabelConfigs,
+		onEmptyResult:      v1alpha1.EmptyResultPolicy(env.OnEmptyResult),
+		noDataThreshold:    env.NoDataThreshold,
+		sink:               env.GetSink(),
+		retry:              int(env.DeliveryRetry),
+		backoffPolicy:      eventingduckv1.BackoffPolicyType(env.DeliveryBackoffPolicy),
+		backoffDelay:       env.DeliveryBackoffDelay,
+		deadLetterSink:     env.DeadLetterSink,
+		outboxDir:          env.OutboxDir,
+		outboxMaxEvents:    env.OutboxMaxEvents,
+		outboxMaxBytes:     env.OutboxMaxBytes,
+		outboxMaxAge:       env.OutboxMaxAge,
+		muteWindowsJSON:    env.MuteWindows,
+		jitter:             env.Jitter,
+		startingDeadline:   env.StartingDeadline,
+		alignToStep:        env.AlignToStep,
+		evaluationDelay:    env.EvaluationDelay,
+		suspendedAt:        env.SuspendedAt,
+		resumedAt:          env.ResumedAt,
+		runOnce:            env.RunOnce,
+		jobName:            env.JobName,
+	}
+	if a.eventType == "" {
+		a.eventType = v1alpha1.PromQLPrometheusSourceEventType
+	}
+	if env.RateLimitEvents > 0 {
+		a.rateLimiter = newRateLimiter(env.RateLimitEvents, env.RateLimitInterval, env.RateLimitBurst)
+	}
+	if env.AnnotationsFile != "" {
+		a.readMuteUntil = annotationsMuteUntil(env.AnnotationsFile)
+	}
+
+	reporter, err := newStatsReporter()
+	if err != nil {
+		logger.Errorw("Error building statsreporter", zap.Error(err))
+	}
+	a.reporter = reporter
+
+	if env.SourceUID != "" {
+		a.sourceRef = &v1alpha1.PrometheusSource{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: v1alpha1.SchemeGroupVersion.String(),
+				Kind:       "PrometheusSource",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: env.Namespace,
+				Name:      env.Name,
+				UID:       types.UID(env.SourceUID),
+			},
+		}
 	}
 
 	return a
 }
 
+// NewEventRecorder creates a recorder of Kubernetes events in the given
+// namespace, or in the namespaces of the objects if it is empty.
+func NewEventRecorder(ctx context.Context, namespace string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartLogging(logging.FromContext(ctx).Named("event-broadcaster").Infof)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: kubeclient.Get(ctx).CoreV1().Events(namespace),
+	})
+	go func() {
+		<-ctx.Done()
+		eventBroadcaster.Shutdown()
+	}()
+	return eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: v1alpha1.AdapterEventComponent})
+}
+
 func (a *prometheusAdapter) Start(ctx context.Context) error {
 	return a.start(ctx.Done())
 }
@@ -99,71 +471,515 @@ func (a *prometheusAdapter) start(stopCh <-chan struct{}) error {
 	if err := a.readAuthTokenIfNeeded(); err != nil {
 		return err
 	}
-	// pre-make an immutable HTTP Request for an instant query
-	if a.step == "" {
-		if err := a.makeHTTPRequest(); err != nil {
+	if a.subject != "" {
+		tmpl, err := v1alpha1.ParseSubjectTemplate(a.subject)
+		if err != nil {
+			a.logger.Errorf("Unparseable subject %s: %v", a.subject, err)
 			return err
 		}
+		a.subjectTemplate = tmpl
+	}
+	if err := a.parseDataTemplate(); err != nil {
+		a.logger.Error("Unparseable data template", zap.Error(err))
+		return err
+	}
+	if err := a.parseRelabelConfigs(); err != nil {
+		a.logger.Error("Invalid relabel configs", zap.Error(err))
+		return err
+	}
+	if a.muteWindowsJSON != "" {
+		windows, err := parseMuteWindows(a.muteWindowsJSON)
+		if err != nil {
+			a.logger.Error("Invalid mute windows", zap.Error(err))
+			return err
+		}
+		a.muteWindows = windows
+	}
+	if a.alignToStep && a.step != "" {
+		step, err := v1alpha1.ParseStep(a.step)
+		if err != nil {
+			a.logger.Errorf("Unparseable step %s: %v", a.step, err)
+			return err
+		}
+		a.stepDuration = time.Duration(step)
 	}
 	if err := a.makeHTTPClient(); err != nil {
 		return err
 	}
+	if err := a.openOutbox(); err != nil {
+		a.logger.Error("Unable to open the outbox", zap.Error(err))
+		return err
+	}
 
-	sched, err := cron.ParseStandard(a.schedule)
+	// The jitter of the source is the same for every replica of its adapter.
+	jitterKey := a.sourceUID
+	if jitterKey == "" {
+		jitterKey = a.namespace + "/" + a.name
+	}
+	sched, err := newScheduler(a.schedule, a.timeZone, sourceJitter(jitterKey, a.jitter), a.startingDeadline)
 	if err != nil {
-		a.logger.Errorf("Unparseable schedule %s: %v", a.schedule, err)
+		a.logger.Errorf("Unparseable schedule %s in time zone %q: %v", a.schedule, a.timeZone, err)
 		return err
 	}
 
-	c := cron.New()
-	c.Schedule(sched, cron.FuncJob(a.send))
-	c.Start()
-	<-stopCh
-	c.Stop()
+	if a.runOnce {
+		return a.evaluateOnce(sched, stopCh)
+	}
+	if a.leaseName != "" {
+		return a.runElected(sched, stopCh)
+	}
+	a.run(sched, stopCh)
 	return nil
 }
 
-func (a *prometheusAdapter) send() {
-	// range query
+// send evaluates the query at the time of the tick shifted back by the
+// evaluation delay, which is also the time of the events, even if the
+// evaluation is delayed by the jitter or late. It returns the error, already
+// logged, of an evaluation that failed.
+func (a *prometheusAdapter) send(tick time.Time) error {
+	evalTime := tick.Add(-a.evaluationDelay).UTC().Truncate(time.Second)
+	defer a.reportRateLimited(evalTime)
+	if a.outbox != nil {
+		// Replay the queued events even if the query fails.
+		a.outbox.replay(a.deliver)
+	}
+	// The source is muted at the time of the tick, when its events would be
+	// sent.
+	a.refreshMuteUntil()
+	mute := a.muteAction(tick)
+	a.reportMuted(mute)
+	if mute == v1alpha1.MuteActionSkip {
+		// The next range query does not cover the skipped evaluation.
+		a.lastRun = evalTime
+		return nil
+	}
+	// The query result of a muted evaluation is read, to keep track of the
+	// empty results, but not sent.
+	muted := mute != ""
+	a.evalStart, a.evalEnd = time.Time{}, evalTime
 	if a.step != "" {
-		if err := a.makeHTTPRequest(); err != nil {
-			return
+		var ok bool
+		if a.evalStart, a.evalEnd, ok = a.queryRange(evalTime); !ok {
+			a.logger.Debugw("No new step to query", zap.Time("start", a.evalStart), zap.Time("end", a.evalEnd))
+			return nil
 		}
-		a.lastRun = time.Now()
 	}
+	if err := a.makeHTTPRequest(a.evalStart, a.evalEnd); err != nil {
+		return err
+	}
+
 	resp, err := a.client.Do(a.req)
 	if err != nil {
 		a.logger.Error("HTTP invocation error", zap.Error(err))
-		return
+		return err
 	}
 	defer resp.Body.Close()
 
-	reply, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	var empty bool
+	result, err := streamResult(resp.Body, &a.limits, a.seriesPerEvent, a.relabeling, func(resp *queryResponse, c *chunk) error {
+		skipEmpty := a.onEmptyResult == v1alpha1.EmptyResultPolicySkip || a.onEmptyResult == v1alpha1.EmptyResultPolicyEmitNoData
+		if skipEmpty && c.emptyResult(resp) {
+			// The empty result is not sent, nor counted as delivered.
+			empty = true
+			return nil
+		}
+		if muted {
+			return nil
+		}
+		return a.sendChunk(resp, c, evalTime)
+	})
+	if result.exceeded != "" {
+		a.reportLimitExceeded(result.exceeded)
+	}
+	if err != nil && !errors.Is(err, errNotDelivered) {
 		a.logger.Error("HTTP reply error", zap.Error(err))
-		return
+		return err
+	}
+	// The next range query only starts after this one once its response is
+	// read. The events not delivered are left to the dead letter sink and
+	// the outbox.
+	a.lastRun = evalTime
+	if err != nil {
+		return err
 	}
-	if len(reply) > 0 {
-		event, err := a.makeEvent(reply)
+
+	if result.dropped && !muted {
+		event, err := a.makeErrorEvent(fmt.Sprintf("query result exceeded the %s limit of %d",
+			result.exceeded, a.limits.value(result.exceeded)), evalTime)
 		if err != nil {
 			a.logger.Error("Cloud Event creation error", zap.Error(err))
-			return
+			return err
+		}
+		a.sendEvent(event)
+	}
+
+	if a.onEmptyResult == v1alpha1.EmptyResultPolicyEmitNoData && result.response.Status == "success" {
+		a.trackEmptyResult(empty, muted, evalTime)
+	}
+	return nil
+}
+
+// trackEmptyResult sends a nodata event once the query has returned no series
+// for noDataThreshold consecutive evaluations, and a datareturned event when
+// it returns series again. The events of a muted evaluation are not sent, and
+// are sent by the first evaluation after it if its result is still empty, or
+// no longer is.
+func (a *prometheusAdapter) trackEmptyResult(empty, muted bool, evalTime time.Time) {
+	if !empty {
+		if a.noData && !muted {
+			event, err := a.makeNoDataEvent(a.eventType+".datareturned", "datareturned", evalTime)
+			if err != nil {
+				a.logger.Error("Cloud Event creation error", zap.Error(err))
+			} else if a.sendEvent(event) {
+				a.noData = false
+			}
+		}
+		a.emptyEvaluations = 0
+		return
+	}
+
+	if a.emptyEvaluations == 0 {
+		a.emptySince = evalTime
+	}
+	a.emptyEvaluations++
+	threshold := a.noDataThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+	if a.noData || muted || a.emptyEvaluations < threshold {
+		return
+	}
+	event, err := a.makeNoDataEvent(a.eventType+".nodata", "nodata", evalTime)
+	if err != nil {
+		a.logger.Error("Cloud Event creation error", zap.Error(err))
+		return
+	}
+	a.noData = a.sendEvent(event)
+}
+
+// sendChunk sends a chunk of a query result as an event, it is called while
+// the query response is being decoded.
+func (a *prometheusAdapter) sendChunk(resp *queryResponse, c *chunk, evalTime time.Time) error {
+	contentType, payload, err := a.renderData(resp, c, evalTime)
+	if err != nil {
+		a.logger.Error("Cloud Event creation error", zap.Error(err))
+		return errNotDelivered
+	}
+	// Every series is sent in its own event in per-series mode.
+	perSeries := a.seriesPerEvent == 1 && len(c.series) == 1
+	var labels map[string]string
+	if perSeries || a.subjectTemplate != nil || len(a.labelExtensions) > 0 || len(a.partitionKeyLabels) > 0 {
+		if labels, err = c.commonLabels(); err != nil {
+			a.logger.Error("Cloud Event creation error", zap.Error(err))
+			return errNotDelivered
 		}
-		result := a.ce.Send(context.Background(), *event)
-		if !cloudevents.IsACK(result) {
-			a.logger.Error("Cloud Event delivery error", zap.Error(result))
-			return
+	}
+	part := "chunk/" + strconv.Itoa(c.index)
+	if perSeries {
+		part = "series/" + strconv.FormatUint(model.LabelsToSignature(labels), 16)
+	}
+	event, err := a.makeEvent(a.eventID(evalTime, part), contentType, payload, labels, evalTime)
+	if err != nil {
+		a.logger.Error("Cloud Event creation error", zap.Error(err))
+		return errNotDelivered
+	}
+	a.setLabelExtensions(event, labels)
+	if c.truncated {
+		event.SetExtension(truncatedExtension, true)
+	}
+	if c.exceeded != "" {
+		event.SetExtension(limitExceededExtension, c.exceeded)
+	}
+	if c.index > 1 || !c.last {
+		event.SetExtension(chunkExtension, c.index)
+		if c.last {
+			event.SetExtension(lastChunkExtension, true)
 		}
 	}
+	if a.rateLimiter != nil && !a.rateLimiter.allow(event, labels, time.Now()) {
+		// The suppressed events are summed up at the end of the evaluation.
+		return nil
+	}
+	if !a.sendEvent(event) {
+		return errNotDelivered
+	}
+	return nil
 }
 
-func (a *prometheusAdapter) makeEvent(payload interface{}) (*cloudevents.Event, error) {
+// reportRateLimited sends a ratelimited event summing up the events of the
+// evaluation suppressed by the rate limit, and records it on the
+// PrometheusSource.
+func (a *prometheusAdapter) reportRateLimited(evalTime time.Time) {
+	if a.rateLimiter == nil {
+		return
+	}
+	summary := a.rateLimiter.summary()
+	if summary == nil {
+		return
+	}
+	a.logger.Warnw("Suppressed events exceeding the rate limit", zap.Int64("suppressed", summary.Suppressed))
+	if a.recorder != nil {
+		a.recorder.Eventf(a.sourceRef, corev1.EventTypeWarning, v1alpha1.RateLimitedReason,
+			"Suppressed %d events exceeding the rate limit of %d events per %s",
+			summary.Suppressed, summary.Limit.Events, summary.Limit.Interval)
+	}
+	event, err := a.makeStatusEvent(a.eventType+".ratelimited", "ratelimited", summary, evalTime)
+	if err != nil {
+		a.logger.Error("Cloud Event creation error", zap.Error(err))
+		return
+	}
+	a.sendEvent(event)
+}
+
+// parseDataTemplate parses the text or JSON data template of the source, if any.
+func (a *prometheusAdapter) parseDataTemplate() error {
+	var err error
+	switch {
+	case a.templateText != "":
+		a.dataTemplate, err = datatemplate.Parse(a.templateText)
+	case a.templateJSON != "":
+		a.dataTemplate, err = datatemplate.ParseJSON(a.templateJSON)
+	default:
+		return nil
+	}
+	if err == nil && a.dataContentType == "" {
+		a.dataContentType = a.dataTemplate.ContentType()
+	}
+	return err
+}
+
+// parseRelabelConfigs parses the relabel configs of the source, if any.
+func (a *prometheusAdapter) parseRelabelConfigs() error {
+	if a.relabelConfigs == "" {
+		return nil
+	}
+	var configs []v1alpha1.RelabelConfig
+	if err := json.Unmarshal([]byte(a.relabelConfigs), &configs); err != nil {
+		return err
+	}
+	relabeling, err := v1alpha1.ParseRelabelConfigs(configs)
+	if err != nil {
+		return err
+	}
+	a.relabeling = relabeling
+	return nil
+}
+
+// renderData renders the data of the event carrying the chunk, for a
+// successful query: the output of the data template of the source, or the
+// query result encoded in its data format. The data is the query response
+// restricted to the chunk otherwise.
+func (a *prometheusAdapter) renderData(resp *queryResponse, c *chunk, evalTime time.Time) (string, []byte, error) {
+	encoded := a.dataFormat != "" && a.dataFormat != v1alpha1.DataFormatPrometheusJSON
+	if a.dataTemplate == nil && !encoded || resp.Status != "success" {
+		payload, err := c.payload(resp)
+		return cloudevents.ApplicationJSON, payload, err
+	}
+	data, err := c.templateData(resp)
+	if err != nil {
+		return "", nil, err
+	}
+	data.Name = a.name
+	data.Namespace = a.namespace
+	data.Time = evalTime
+	if a.dataTemplate == nil {
+		if !encodable(data) {
+			payload, err := c.payload(resp)
+			return cloudevents.ApplicationJSON, payload, err
+		}
+		return encodeData(a.dataFormat, data)
+	}
+	payload, err := a.dataTemplate.Execute(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to execute the data template: %w", err)
+	}
+	return a.dataContentType, payload, nil
+}
+
+// setLabelExtensions promotes the labels to the extension attributes of the
+// event, labels are those shared by every series of the event.
+func (a *prometheusAdapter) setLabelExtensions(event *cloudevents.Event, labels map[string]string) {
+	for name, label := range a.labelExtensions {
+		if value := labels[label]; value != "" {
+			event.SetExtension(name, value)
+		}
+	}
+	if len(a.partitionKeyLabels) > 0 {
+		values := make([]string, len(a.partitionKeyLabels))
+		for i, label := range a.partitionKeyLabels {
+			values[i] = labels[label]
+		}
+		if key := strings.Join(values, "/"); key != "" {
+			event.SetExtension(v1alpha1.PartitionKeyExtension, key)
+		}
+	}
+}
+
+// sendEvent sends the event to the sink and returns true if it was delivered,
+// possibly to the dead letter sink, or queued in the outbox. Every event is
+// numbered by the sequence extension attribute, so that the events not
+// delivered leave a gap.
+func (a *prometheusAdapter) sendEvent(event *cloudevents.Event) bool {
+	event.SetExtension(sequenceExtension, a.nextSequence(event.Time()))
+	if a.outbox != nil {
+		return a.outbox.send(*event, a.deliver)
+	}
+	return a.deliver(*event) == delivered
+}
+
+// nextSequence returns the sequence of the next event of the evaluation at the
+// time. It is derived from the evaluation time, which the adapter resumes from
+// when it restarts or fails over and which a Job run by a CronJob is created
+// for, rather than from a counter it would lose, and it is zero-padded so that
+// the sequences sort lexicographically.
+func (a *prometheusAdapter) nextSequence(evalTime time.Time) string {
+	if !evalTime.Equal(a.sequenceTime) {
+		a.sequenceTime, a.sequenceIndex = evalTime, 0
+	}
+	a.sequenceIndex++
+	return fmt.Sprintf("%012d-%06d", evalTime.Unix(), a.sequenceIndex)
+}
+
+// deliver sends the event to the sink, and to the dead letter sink if the sink
+// does not accept it. With an outbox, the events the sink could not receive
+// are left for the outbox to queue instead.
+func (a *prometheusAdapter) deliver(event cloudevents.Event) delivery {
+	result := a.ce.Send(a.deliveryContext(), event)
+	if cloudevents.IsACK(result) {
+		return delivered
+	}
+	a.logger.Error("Cloud Event delivery error", zap.Error(result))
+	if a.outbox != nil && sinkUnavailable(result) {
+		return unavailable
+	}
+	if a.deadLetterSink != "" && a.sendDeadLetter(&event, result) {
+		return delivered
+	}
+	return rejected
+}
+
+// deliveryContext returns the context the events are sent with, retrying
+// them with the backoff policy of the source.
+func (a *prometheusAdapter) deliveryContext() context.Context {
+	ctx := context.Background()
+	if a.retry <= 0 {
+		return ctx
+	}
+	if a.backoffPolicy == eventingduckv1.BackoffPolicyLinear {
+		return cloudevents.ContextWithRetriesLinearBackoff(ctx, a.backoffDelay, a.retry)
+	}
+	return cloudevents.ContextWithRetriesExponentialBackoff(ctx, a.backoffDelay, a.retry)
+}
+
+// sendDeadLetter sends an event the sink did not accept to the dead letter
+// sink, with the extension attributes of the failed delivery, and returns true
+// if it was delivered.
+func (a *prometheusAdapter) sendDeadLetter(event *cloudevents.Event, result error) bool {
+	dead := event.Clone()
+	if a.sink != "" {
+		dead.SetExtension(errorDestExtension, a.sink)
+	}
+	if code := statusCode(result); code != 0 {
+		dead.SetExtension(errorCodeExtension, strconv.Itoa(code))
+	}
+	ctx := cloudevents.ContextWithTarget(a.deliveryContext(), a.deadLetterSink)
+	if result := a.ce.Send(ctx, dead); !cloudevents.IsACK(result) {
+		a.logger.Error("Cloud Event dead letter delivery error", zap.Error(result))
+		return false
+	}
+	return true
+}
+
+// sinkUnavailable returns true if the delivery failed without a response, or
+// with a status code telling the sink may accept the event later.
+func sinkUnavailable(result error) bool {
+	code := statusCode(result)
+	return code == 0 || code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// openOutbox opens the outbox of the source, if it has one.
+func (a *prometheusAdapter) openOutbox() error {
+	if a.outboxDir == "" {
+		return nil
+	}
+	o, err := newOutbox(a.outboxDir, a.outboxMaxEvents, a.outboxMaxBytes, a.outboxMaxAge, a.logger)
+	if err != nil {
+		return err
+	}
+	if a.reporter != nil {
+		args := &reportArgs{
+			namespace:   a.namespace,
+			eventSource: a.source,
+			name:        a.name,
+		}
+		o.onDepth = func(depth int) {
+			if err := a.reporter.reportOutboxDepth(args, depth); err != nil {
+				a.logger.Errorw("Failed to record the outbox depth", zap.Error(err))
+			}
+		}
+		o.onDrop = func(reason string) {
+			dropArgs := *args
+			dropArgs.dropReason = reason
+			if err := a.reporter.reportOutboxDropped(&dropArgs); err != nil {
+				a.logger.Errorw("Failed to record the dropped outbox event", zap.Error(err))
+			}
+		}
+		o.reportDepth()
+	}
+	a.outbox = o
+	return nil
+}
+
+// statusCode returns the HTTP status code of the last attempt of a delivery,
+// 0 if it did not get a response.
+func statusCode(result error) int {
+	var retries *cehttp.RetriesResult
+	if cloudevents.ResultAs(result, &retries) {
+		result = retries.Result
+	}
+	var res *cehttp.Result
+	if cloudevents.ResultAs(result, &res) {
+		return res.StatusCode
+	}
+	return 0
+}
+
+// reportLimitExceeded counts a query result exceeding the named limit and
+// records it on the PrometheusSource.
+func (a *prometheusAdapter) reportLimitExceeded(limit string) {
+	a.logger.Warnw("Query result exceeded the source limits", zap.String("limit", limit),
+		zap.Int64("value", a.limits.value(limit)), zap.String("overflowPolicy", string(a.limits.policy)))
+	if a.reporter != nil {
+		if err := a.reporter.reportLimitExceeded(&reportArgs{
+			namespace:      a.namespace,
+			eventSource:    a.source,
+			name:           a.name,
+			limit:          limit,
+			overflowPolicy: string(a.limits.policy),
+		}); err != nil {
+			a.logger.Warnw("Failed to report metrics", zap.Error(err))
+		}
+	}
+	if a.recorder != nil {
+		a.recorder.Eventf(a.sourceRef, corev1.EventTypeWarning, v1alpha1.ResultLimitExceededReason,
+			"Query result exceeded the %s limit of %d, overflow policy: %s", limit, a.limits.value(limit), a.limits.policy)
+	}
+}
+
+// makeEvent creates an event carrying a query result, labels are those shared
+// by every series of the result.
+func (a *prometheusAdapter) makeEvent(id, contentType string, payload []byte, labels map[string]string, evalTime time.Time) (*cloudevents.Event, error) {
 	event := cloudevents.NewEvent(cloudevents.VersionV1)
 	event.SetSource(a.source)
-	event.SetID(string(uuid.NewUUID()))
-	event.SetType(v1alpha1.PromQLPrometheusSourceEventType)
+	event.SetID(id)
+	event.SetType(a.eventType)
+	event.SetTime(evalTime)
+	a.setEvalExtensions(&event)
+	if err := a.setSubject(&event, labels); err != nil {
+		return nil, err
+	}
 
-	if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
+	if err := event.SetData(contentType, payload); err != nil {
 		return nil, fmt.Errorf("failed to marshal event data: %w", err)
 	}
 
@@ -172,7 +988,114 @@ func (a *prometheusAdapter) makeEvent(payload interface{}) (*cloudevents.Event,
 	return &event, nil
 }
 
-func (a *prometheusAdapter) makeInvocationURL() string {
+// makeErrorEvent creates an event reporting a query result that could not be sent.
+func (a *prometheusAdapter) makeErrorEvent(message string, evalTime time.Time) (*cloudevents.Event, error) {
+	return a.makeStatusEvent(a.eventType+".error", "error", map[string]string{"error": message}, evalTime)
+}
+
+// makeNoDataEvent creates an event reporting the consecutive evaluations of
+// the query which returned no series.
+func (a *prometheusAdapter) makeNoDataEvent(eventType, part string, evalTime time.Time) (*cloudevents.Event, error) {
+	return a.makeStatusEvent(eventType, part, map[string]interface{}{
+		"emptyEvaluations": a.emptyEvaluations,
+		"since":            a.emptySince,
+	}, evalTime)
+}
+
+// makeStatusEvent creates an event reporting on the query rather than
+// carrying its result.
+func (a *prometheusAdapter) makeStatusEvent(eventType, part string, data interface{}, evalTime time.Time) (*cloudevents.Event, error) {
+	event := cloudevents.NewEvent(cloudevents.VersionV1)
+	event.SetSource(a.source)
+	event.SetID(a.eventID(evalTime, part))
+	event.SetType(eventType)
+	event.SetTime(evalTime)
+	a.setEvalExtensions(&event)
+	if err := a.setSubject(&event, nil); err != nil {
+		return nil, err
+	}
+
+	if err := event.SetData(cloudevents.ApplicationJSON, data); err != nil {
+		return nil, fmt.Errorf("failed to marshal event data: %w", err)
+	}
+	return &event, nil
+}
+
+// setEvalExtensions records on the event the exact evaluation timestamps of
+// the query of the evaluation in progress.
+func (a *prometheusAdapter) setEvalExtensions(event *cloudevents.Event) {
+	switch {
+	case a.evalEnd.IsZero():
+	case a.evalStart.IsZero():
+		event.SetExtension(evalTimeExtension, a.evalEnd)
+	default:
+		event.SetExtension(evalStartExtension, a.evalStart)
+		event.SetExtension(evalEndExtension, a.evalEnd)
+	}
+}
+
+// eventID derives the ID of an event from the source, the evaluation time of
+// the query and the part of the query result the event carries, so that the
+// events of an evaluation retried or run by another adapter have the same IDs.
+func (a *prometheusAdapter) eventID(evalTime time.Time, part string) string {
+	h := sha256.New()
+	for _, s := range []string{a.sourceUID, a.namespace, a.name, evalTime.UTC().Format(time.RFC3339Nano), part} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// setSubject sets the subject of the event, executing the subject template
+// of the source with the given labels if it has one.
+func (a *prometheusAdapter) setSubject(event *cloudevents.Event, labels map[string]string) error {
+	if a.subjectTemplate == nil {
+		if a.name != "" {
+			event.SetSubject(a.name)
+		}
+		return nil
+	}
+	var subject strings.Builder
+	if err := a.subjectTemplate.Execute(&subject, &v1alpha1.SubjectTemplateData{
+		Name:      a.name,
+		Namespace: a.namespace,
+		Labels:    labels,
+	}); err != nil {
+		return fmt.Errorf("failed to execute the subject template: %w", err)
+	}
+	if subject.Len() > 0 {
+		event.SetSubject(subject.String())
+	}
+	return nil
+}
+
+// queryRange returns the start and end of the range query evaluated at the
+// time, from the evaluation time of the previous query. Aligned to the step,
+// the range starts at the step following the end of the previous range, and
+// it returns false if there is no new step to query.
+func (a *prometheusAdapter) queryRange(evalTime time.Time) (time.Time, time.Time, bool) {
+	start, end := a.lastRun.UTC(), evalTime
+	if a.alignToStep && a.stepDuration > 0 {
+		start = alignToStep(start, a.stepDuration).Add(a.stepDuration)
+		end = alignToStep(end, a.stepDuration)
+	}
+	return start, end, !start.After(end)
+}
+
+// alignToStep returns the latest multiple of the step since the Unix epoch
+// not after the time, as Prometheus aligns the range queries it caches.
+func alignToStep(t time.Time, step time.Duration) time.Time {
+	ns := t.UnixNano()
+	aligned := ns - ns%int64(step)
+	if ns < 0 && aligned != ns {
+		aligned -= int64(step)
+	}
+	return time.Unix(0, aligned).UTC()
+}
+
+// makeInvocationURL returns the URL of the query, a range query from start to
+// end with a step, an instant query at end otherwise.
+func (a *prometheusAdapter) makeInvocationURL(start, end time.Time) string {
 	rangeQuery := (a.step != "")
 	ret := a.serverURL + `/api/v1/query`
 	if rangeQuery {
@@ -180,32 +1103,54 @@ func (a *prometheusAdapter) makeInvocationURL() string {
 	}
 	ret += `?query=` + a.promQL
 	if rangeQuery {
-		ret += `&start=` + a.lastRun.Format(time.RFC3339) +
-			`&end=` + time.Now().Format(time.RFC3339) +
+		ret += `&start=` + start.Format(time.RFC3339Nano) +
+			`&end=` + end.Format(time.RFC3339Nano) +
 			`&step=` + a.step
+	} else {
+		ret += `&time=` + end.Format(time.RFC3339Nano)
+	}
+	if a.queryTimeout != "" {
+		ret += `&timeout=` + a.queryTimeout
 	}
 	return ret
 }
 
-func (a *prometheusAdapter) makeHTTPRequest() error {
+func (a *prometheusAdapter) makeHTTPRequest(start, end time.Time) error {
 	var err error
-	if a.req, err = http.NewRequest(`GET`, a.makeInvocationURL(), nil); err != nil {
+	if a.req, err = http.NewRequest(`GET`, a.makeInvocationURL(start, end), nil); err != nil {
 		a.logger.Error("HTTP request error", zap.Error(err))
 		return err
 	}
 	if a.authToken != "" {
 		a.req.Header.Set("Authorization", "Bearer "+a.authToken)
 	}
-	a.logger.Info(a.req)
+	// The headers hold the token of the source.
+	a.logger.Debugw("Prometheus query", zap.String("url", a.req.URL.Redacted()))
 	return nil
 }
 
 func (a *prometheusAdapter) makeHTTPClient() error {
 	a.client = &http.Client{}
 
+	if a.queryTimeout != "" {
+		timeout, err := model.ParseDuration(a.queryTimeout)
+		if err != nil {
+			a.logger.Error("Error parsing query timeout "+a.queryTimeout+": ", zap.Error(err))
+			return err
+		}
+		a.client.Timeout = time.Duration(timeout)
+	}
+
 	if a.caCertConfigMap != "" {
 		caCertFile := "/etc/" + a.caCertConfigMap + "/service-ca.crt"
-		caCert, err := ioutil.ReadFile(caCertFile)
+		readCACert := func() ([]byte, error) {
+			return ioutil.ReadFile(caCertFile)
+		}
+		if a.caCert != nil {
+			caCertFile = "ConfigMap " + a.caCertConfigMap
+			readCACert = a.caCert
+		}
+		caCert, err := readCACert()
 		if err != nil {
 			a.logger.Error("Error reading CA certificate from "+caCertFile+": ", zap.Error(err))
 			return err
@@ -213,7 +1158,7 @@ func (a *prometheusAdapter) makeHTTPClient() error {
 		caCertPool := x509.NewCertPool()
 		if !caCertPool.AppendCertsFromPEM(caCert) {
 			a.logger.Error("Error parsing CA certificate from " + caCertFile)
-			return err
+			return fmt.Errorf("no CA certificate found in %s", caCertFile)
 		}
 		a.client.Transport = &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -221,6 +1166,13 @@ func (a *prometheusAdapter) makeHTTPClient() error {
 			},
 		}
 	}
+	if a.transport != nil {
+		transport := a.client.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		a.client.Transport = a.transport(transport)
+	}
 	return nil
 }
 
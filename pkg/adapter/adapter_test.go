@@ -23,15 +23,21 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	cecontext "github.com/cloudevents/sdk-go/v2/context"
 	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/google/go-cmp/cmp"
 	"go.uber.org/zap"
 	"knative.dev/eventing/pkg/adapter/v2"
 	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/logging"
 	pkgtesting "knative.dev/pkg/reconciler/testing"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
 )
 
 func TestNewAdaptor(t *testing.T) {
@@ -52,6 +58,7 @@ func TestNewAdaptor(t *testing.T) {
 				CACertConfigMap: "ca_cert_config_map",
 				Schedule:        "* * * * *",
 				Step:            "30s",
+				QueryTimeout:    "10s",
 			},
 		},
 	}
@@ -94,10 +101,34 @@ func TestNewAdaptor(t *testing.T) {
 			if diff := cmp.Diff(tc.opt.Step, got.step); diff != "" {
 				t.Errorf("unexpected step diff (-want, +got) = %v", diff)
 			}
+			if diff := cmp.Diff(tc.opt.QueryTimeout, got.queryTimeout); diff != "" {
+				t.Errorf("unexpected queryTimeout diff (-want, +got) = %v", diff)
+			}
 		})
 	}
 }
 
+func TestNewAdaptorWithoutKubeClient(t *testing.T) {
+	ctx := logging.WithLogger(context.Background(), zap.NewExample().Sugar())
+	a := NewAdapter(ctx, &envConfig{
+		EnvConfig: adapter.EnvConfig{
+			Namespace: "test-ns",
+			Name:      "test-name",
+		},
+		EventSource: "test-source",
+		ServerURL:   "http://server.url",
+		PromQL:      "prom-ql",
+		Schedule:    "* * * * *",
+		SourceUID:   "uid-1",
+	}, adaptertest.NewTestClient()).(*prometheusAdapter)
+	if a.sourceRef == nil {
+		t.Error("expected the adapter to know its source")
+	}
+	if a.recorder != nil {
+		t.Error("expected no event recorder without a Kubernetes client")
+	}
+}
+
 func TestStartAdaptor(t *testing.T) {
 	ce := adaptertest.NewTestClient()
 
@@ -128,6 +159,19 @@ func TestStartAdaptor(t *testing.T) {
 			},
 			wantErrMsg: "Empty spec string",
 		},
+		"bad-time-zone": {
+			opt: envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "test-ns",
+				},
+				EventSource: "test-source",
+				ServerURL:   "http://server.url",
+				PromQL:      "prom-ql",
+				Schedule:    "* * * * *",
+				TimeZone:    "Mars/Olympus_Mons",
+			},
+			wantErrMsg: "unknown time zone Mars/Olympus_Mons",
+		},
 		"bad-auth-token-file": {
 			opt: envConfig{
 				EnvConfig: adapter.EnvConfig{
@@ -194,13 +238,15 @@ func (c *adapterTestClient) Send(ctx context.Context, event cloudevents.Event) p
 func TestReceiveEventPoll(t *testing.T) {
 	const promQL = `promQL`
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fmt.Fprintln(w, "{\"request_uri\":\""+r.RequestURI+"\"}")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"request_uri":%q},"value":[1435781451.781,"1"]}]}}`, r.RequestURI)
 	}))
 	defer ts.Close()
 
 	testCases := map[string]struct {
 		opt            envConfig
 		wantRequestURI string
+		wantType       string
+		wantSubject    string
 	}{
 		"instant-query": {
 			opt: envConfig{
@@ -212,7 +258,8 @@ func TestReceiveEventPoll(t *testing.T) {
 				PromQL:      promQL,
 				Schedule:    "* * * * *",
 			},
-			wantRequestURI: "/api/v1/query?query=" + promQL,
+			wantRequestURI: "/api/v1/query?query=" + promQL + "&time=",
+			wantType:       "dev.knative.prometheus.promql",
 		},
 		"range-query": {
 			opt: envConfig{
@@ -226,6 +273,24 @@ func TestReceiveEventPoll(t *testing.T) {
 				Step:        "5s",
 			},
 			wantRequestURI: "/api/v1/query_range?query=" + promQL + "&start=",
+			wantType:       "dev.knative.prometheus.promql",
+		},
+		"event attributes": {
+			opt: envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace: "test-ns",
+					Name:      "test-name",
+				},
+				EventSource: "test-source",
+				ServerURL:   ts.URL,
+				PromQL:      promQL,
+				Schedule:    "* * * * *",
+				EventType:   "com.example.promql",
+				Subject:     "{{.Namespace}}/{{.Name}}/{{.Labels.request_uri}}{{.Labels.missing}}",
+			},
+			wantRequestURI: "/api/v1/query?query=" + promQL,
+			wantType:       "com.example.promql",
+			wantSubject:    "test-ns/test-name//api/v1/query?query=" + promQL + "&time=",
 		},
 	}
 
@@ -249,6 +314,17 @@ func TestReceiveEventPoll(t *testing.T) {
 			<-done
 
 			validateSent(t, ce, tc.wantRequestURI)
+
+			event := ce.Sent()[0]
+			if got := event.Type(); got != tc.wantType {
+				t.Errorf("Type() = %q, want %q", got, tc.wantType)
+			}
+			if got := event.Subject(); !strings.HasPrefix(got, tc.wantSubject) {
+				t.Errorf("Subject() = %q, want prefix %q", got, tc.wantSubject)
+			}
+			if got := event.Time(); got.IsZero() || !got.Equal(got.Truncate(time.Second)) {
+				t.Errorf("Time() = %v, want the evaluation time of the query", got)
+			}
 		})
 	}
 }
@@ -262,3 +338,495 @@ func validateSent(t *testing.T, ce *adapterTestClient, wantData string) {
 		t.Errorf("Expected %q event to be sent, got %q", wantData, string(got))
 	}
 }
+
+func TestSetLabelExtensions(t *testing.T) {
+	testCases := map[string]struct {
+		labelExtensions    map[string]string
+		partitionKeyLabels []string
+		labels             map[string]string
+		want               map[string]interface{}
+	}{
+		"no labels": {
+			labelExtensions:    map[string]string{"severity": "severity"},
+			partitionKeyLabels: []string{"namespace"},
+		},
+		"promoted labels": {
+			labelExtensions: map[string]string{"alertname": "alert_name", "ns": "namespace", "pod": "pod"},
+			labels:          map[string]string{"alert_name": "HighLatency", "namespace": "payments", "job": "api"},
+			want:            map[string]interface{}{"alertname": "HighLatency", "ns": "payments"},
+		},
+		"partition key": {
+			partitionKeyLabels: []string{"namespace", "pod"},
+			labels:             map[string]string{"namespace": "payments", "pod": "api-0"},
+			want:               map[string]interface{}{"partitionkey": "payments/api-0"},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{
+				labelExtensions:    tc.labelExtensions,
+				partitionKeyLabels: tc.partitionKeyLabels,
+			}
+			event := cloudevents.NewEvent()
+			a.setLabelExtensions(&event, tc.labels)
+			if diff := cmp.Diff(tc.want, event.Extensions()); diff != "" {
+				t.Errorf("unexpected extensions (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestEventIDs(t *testing.T) {
+	evalTime := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+	newAdapter := func(sourceUID string) (*prometheusAdapter, *adaptertest.TestCloudEventsClient) {
+		ce := adaptertest.NewTestClient()
+		return &prometheusAdapter{
+			ce:             ce,
+			namespace:      "test-ns",
+			name:           "test-name",
+			sourceUID:      sourceUID,
+			eventType:      "dev.knative.prometheus.promql",
+			seriesPerEvent: 1,
+			logger:         zap.NewExample().Sugar(),
+		}, ce
+	}
+	send := func(a *prometheusAdapter, evalTime time.Time, series string) {
+		c := &chunk{index: 1, last: true}
+		c.add([]byte(series), 2, int64(len(series)))
+		if err := a.sendChunk(&queryResponse{Status: "success", Data: &queryData{ResultType: "matrix"}}, c, evalTime); err != nil {
+			t.Fatal("sendChunk() =", err)
+		}
+	}
+
+	a, ce := newAdapter("uid-1")
+	send(a, evalTime, seriesA)
+	send(a, evalTime, seriesB)
+	send(a, evalTime.Add(time.Minute), seriesA)
+	// Another adapter running the same source.
+	replica, replicaCE := newAdapter("uid-1")
+	send(replica, evalTime, seriesA)
+	// Another source.
+	other, otherCE := newAdapter("uid-2")
+	send(other, evalTime, seriesA)
+
+	sent := ce.Sent()
+	ids := []string{sent[0].ID(), sent[1].ID(), sent[2].ID(), replicaCE.Sent()[0].ID(), otherCE.Sent()[0].ID()}
+	if ids[0] != ids[3] {
+		t.Errorf("ID() = %q for the same series evaluated by another adapter, want %q", ids[3], ids[0])
+	}
+	for i, id := range []string{ids[1], ids[2], ids[4]} {
+		if id == ids[0] {
+			t.Errorf("ID() #%d = %q, want an ID distinct from the first event", i, id)
+		}
+	}
+
+	var sequence []interface{}
+	for _, event := range sent {
+		sequence = append(sequence, event.Extensions()[sequenceExtension])
+	}
+	if diff := cmp.Diff([]interface{}{"001646136000-000001", "001646136000-000002", "001646136060-000001"}, sequence); diff != "" {
+		t.Errorf("unexpected sequence (-want, +got) = %v", diff)
+	}
+}
+
+func TestRenderData(t *testing.T) {
+	evalTime := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		templateText    string
+		templateJSON    string
+		dataContentType string
+		dataFormat      v1alpha1.DataFormat
+		response        string
+		wantType        string
+		wantData        string
+	}{
+		"no template": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"node":"a"},"value":[1,"0.93"]}]}}`,
+			wantType: cloudevents.ApplicationJSON,
+			wantData: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"node":"a"},"value":[1,"0.93"]}]}}`,
+		},
+		"text template": {
+			templateText: `{{ range .Series }}CPU on node {{ label "node" . }} is {{ humanizePercentage (value .) }}{{ end }}`,
+			response:     `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"node":"a"},"value":[1,"0.93"]}]}}`,
+			wantType:     "text/plain",
+			wantData:     "CPU on node a is 93%",
+		},
+		"json template with content type": {
+			templateJSON:    `{"title": "{{ .Name }} at {{ .Time.Unix }}"}`,
+			dataContentType: "application/vnd.example+json",
+			response:        `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			wantType:        "application/vnd.example+json",
+			wantData:        `{"title":"cpu at 1646136000"}`,
+		},
+		"data format": {
+			dataFormat: v1alpha1.DataFormatCSV,
+			response:   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"node":"a"},"value":[1,"0.93"]}]}}`,
+			wantType:   "text/csv",
+			wantData:   "node,timestamp,value\na,1970-01-01T00:00:01Z,0.93\n",
+		},
+		"data format of a string result": {
+			dataFormat: v1alpha1.DataFormatPrometheusText,
+			response:   `{"status":"success","data":{"resultType":"string","result":[1,"hello"]}}`,
+			wantType:   cloudevents.ApplicationJSON,
+			wantData:   `{"status":"success","data":{"resultType":"string","result":[1,"hello"]}}`,
+		},
+		"failed query": {
+			templateText: `{{ .Name }}`,
+			response:     `{"status":"error","errorType":"timeout","error":"query timed out"}`,
+			wantType:     cloudevents.ApplicationJSON,
+			wantData:     `{"status":"error","errorType":"timeout","error":"query timed out"}`,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{
+				name:            "cpu",
+				templateText:    tc.templateText,
+				templateJSON:    tc.templateJSON,
+				dataContentType: tc.dataContentType,
+				dataFormat:      tc.dataFormat,
+			}
+			if err := a.parseDataTemplate(); err != nil {
+				t.Fatal("parseDataTemplate() =", err)
+			}
+			emit := func(resp *queryResponse, c *chunk) error {
+				gotType, gotData, err := a.renderData(resp, c, evalTime)
+				if err != nil {
+					return err
+				}
+				if gotType != tc.wantType {
+					t.Errorf("content type = %q, want %q", gotType, tc.wantType)
+				}
+				if diff := cmp.Diff(tc.wantData, string(gotData)); diff != "" {
+					t.Errorf("unexpected data (-want, +got) = %v", diff)
+				}
+				return nil
+			}
+			if _, err := streamResult(strings.NewReader(tc.response), &resultLimits{}, 0, nil, emit); err != nil {
+				t.Fatal("streamResult() =", err)
+			}
+		})
+	}
+}
+
+func TestOnEmptyResult(t *testing.T) {
+	const (
+		empty  = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+		data   = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1,"1"]}]}}`
+		failed = `{"status":"error","errorType":"timeout","error":"query timed out"}`
+	)
+	testCases := map[string]struct {
+		policy    v1alpha1.EmptyResultPolicy
+		threshold int64
+		responses []string
+		wantTypes []string
+	}{
+		"send": {
+			responses: []string{empty, data},
+			wantTypes: []string{"com.example.up", "com.example.up"},
+		},
+		"skip": {
+			policy:    v1alpha1.EmptyResultPolicySkip,
+			responses: []string{empty, data, empty},
+			wantTypes: []string{"com.example.up"},
+		},
+		"emit no data": {
+			policy:    v1alpha1.EmptyResultPolicyEmitNoData,
+			responses: []string{empty, empty, data, data},
+			wantTypes: []string{
+				"com.example.up.nodata",
+				"com.example.up", "com.example.up.datareturned",
+				"com.example.up",
+			},
+		},
+		"emit no data after consecutive empty results": {
+			policy:    v1alpha1.EmptyResultPolicyEmitNoData,
+			threshold: 3,
+			// A failed query neither counts as empty nor as returning data.
+			responses: []string{empty, data, empty, failed, empty, empty, empty},
+			wantTypes: []string{
+				"com.example.up",
+				"com.example.up",
+				"com.example.up.nodata",
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var requests int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tc.responses[requests])
+				requests++
+			}))
+			defer ts.Close()
+
+			ce := adaptertest.NewTestClient()
+			a := &prometheusAdapter{
+				ce:              ce,
+				name:            "test-name",
+				logger:          zap.NewExample().Sugar(),
+				serverURL:       ts.URL,
+				promQL:          "up",
+				eventType:       "com.example.up",
+				client:          &http.Client{},
+				onEmptyResult:   tc.policy,
+				noDataThreshold: tc.threshold,
+			}
+			for range tc.responses {
+				a.send(time.Now())
+			}
+
+			var gotTypes []string
+			for _, event := range ce.Sent() {
+				gotTypes = append(gotTypes, event.Type())
+			}
+			if diff := cmp.Diff(tc.wantTypes, gotTypes); diff != "" {
+				t.Errorf("unexpected event types (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestSendLastRun(t *testing.T) {
+	lastRun := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+	tick := lastRun.Add(time.Minute)
+
+	testCases := map[string]struct {
+		handler     http.HandlerFunc
+		wantErr     bool
+		wantLastRun time.Time
+	}{
+		"response read": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			},
+			wantLastRun: tick,
+		},
+		"request failed": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantErr:     true,
+			wantLastRun: lastRun,
+		},
+		"response not read": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[`)
+			},
+			wantErr:     true,
+			wantLastRun: lastRun,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ts := httptest.NewServer(tc.handler)
+			defer ts.Close()
+
+			a := &prometheusAdapter{
+				ce:        adaptertest.NewTestClient(),
+				name:      "test-name",
+				logger:    zap.NewExample().Sugar(),
+				serverURL: ts.URL,
+				promQL:    "up",
+				eventType: "com.example.up",
+				client:    &http.Client{},
+				lastRun:   lastRun,
+			}
+			if err := a.send(tick); (err != nil) != tc.wantErr {
+				t.Errorf("send() = %v, want error %t", err, tc.wantErr)
+			}
+			if !a.lastRun.Equal(tc.wantLastRun) {
+				t.Errorf("lastRun = %v, want %v", a.lastRun, tc.wantLastRun)
+			}
+		})
+	}
+}
+
+// deliveryTestClient rejects the events sent to the sink and records those
+// sent to another target.
+type deliveryTestClient struct {
+	*adaptertest.TestCloudEventsClient
+	// accept is the result of the sends to another target.
+	accept  protocol.Result
+	targets []string
+}
+
+func (c *deliveryTestClient) Send(ctx context.Context, event cloudevents.Event) protocol.Result {
+	target := cecontext.TargetFrom(ctx)
+	if target == nil {
+		return cehttp.NewResult(http.StatusServiceUnavailable, "unavailable")
+	}
+	c.targets = append(c.targets, target.String())
+	if c.accept != nil {
+		return c.accept
+	}
+	return c.TestCloudEventsClient.Send(ctx, event)
+}
+
+func TestSendEventDeadLetterSink(t *testing.T) {
+	testCases := map[string]struct {
+		deadLetterSink string
+		accept         protocol.Result
+		want           bool
+		wantTargets    []string
+		wantExtensions map[string]interface{}
+	}{
+		"no dead letter sink": {
+			want: false,
+		},
+		"dead letter sink": {
+			deadLetterSink: "http://dls.example.com",
+			want:           true,
+			wantTargets:    []string{"http://dls.example.com"},
+			wantExtensions: map[string]interface{}{
+				sequenceExtension:  "001646136000-000001",
+				errorDestExtension: "http://sink.example.com",
+				errorCodeExtension: "503",
+			},
+		},
+		"dead letter sink rejecting the event": {
+			deadLetterSink: "http://dls.example.com",
+			accept:         cehttp.NewResult(http.StatusBadRequest, "bad request"),
+			want:           false,
+			wantTargets:    []string{"http://dls.example.com"},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ce := &deliveryTestClient{TestCloudEventsClient: adaptertest.NewTestClient(), accept: tc.accept}
+			a := &prometheusAdapter{
+				ce:             ce,
+				logger:         zap.NewExample().Sugar(),
+				sink:           "http://sink.example.com",
+				deadLetterSink: tc.deadLetterSink,
+			}
+			event := cloudevents.NewEvent()
+			event.SetID("1")
+			event.SetType("com.example.up")
+			event.SetSource("com.example")
+			event.SetTime(time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC))
+
+			if got := a.sendEvent(&event); got != tc.want {
+				t.Errorf("sendEvent() = %t, want %t", got, tc.want)
+			}
+			if diff := cmp.Diff(tc.wantTargets, ce.targets); diff != "" {
+				t.Errorf("unexpected targets (-want, +got) = %v", diff)
+			}
+			if tc.wantExtensions == nil {
+				return
+			}
+			if diff := cmp.Diff(tc.wantExtensions, ce.Sent()[0].Extensions()); diff != "" {
+				t.Errorf("unexpected extensions (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestDeliveryContext(t *testing.T) {
+	testCases := map[string]struct {
+		retry         int
+		backoffPolicy eventingduckv1.BackoffPolicyType
+		want          cecontext.RetryParams
+	}{
+		"no retry": {
+			want: cecontext.DefaultRetryParams,
+		},
+		"exponential backoff": {
+			retry: 3,
+			want:  cecontext.RetryParams{Strategy: cecontext.BackoffStrategyExponential, Period: time.Second, MaxTries: 3},
+		},
+		"linear backoff": {
+			retry:         2,
+			backoffPolicy: eventingduckv1.BackoffPolicyLinear,
+			want:          cecontext.RetryParams{Strategy: cecontext.BackoffStrategyLinear, Period: time.Second, MaxTries: 2},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{
+				retry:         tc.retry,
+				backoffPolicy: tc.backoffPolicy,
+				backoffDelay:  time.Second,
+			}
+			got := cecontext.RetriesFrom(a.deliveryContext())
+			if diff := cmp.Diff(tc.want, *got); diff != "" {
+				t.Errorf("unexpected retry params (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewSourceAdapter(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+	}))
+	defer ts.Close()
+
+	ctx, _ := pkgtesting.SetupFakeContext(t)
+	ctx = logging.WithLogger(ctx, zap.NewExample().Sugar())
+	ce := adaptertest.NewTestClient()
+
+	var wrapped int
+	a, err := NewSourceAdapter(ctx, map[string]string{
+		"NAMESPACE":             "test-ns",
+		"NAME":                  "test-name",
+		"EVENT_SOURCE":          "test-source",
+		"PROMETHEUS_SERVER_URL": ts.URL,
+		"PROMETHEUS_PROM_QL":    "up",
+		"PROMETHEUS_SCHEDULE":   "* * * * *",
+	}, ce, SourceOptions{
+		Transport: func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				wrapped++
+				return rt.RoundTrip(req)
+			})
+		},
+	})
+	if err != nil {
+		t.Fatal("NewSourceAdapter() =", err)
+	}
+	pa := a.(*prometheusAdapter)
+	if got, want := pa.namespace, "test-ns"; got != want {
+		t.Errorf("namespace = %q, want %q", got, want)
+	}
+
+	if err := pa.makeHTTPClient(); err != nil {
+		t.Fatal("makeHTTPClient() =", err)
+	}
+	if err := pa.send(time.Now()); err != nil {
+		t.Fatal("send() =", err)
+	}
+	if wrapped != 1 {
+		t.Errorf("wrapped transport called %d times, want 1", wrapped)
+	}
+
+	if _, err := NewSourceAdapter(ctx, map[string]string{}, ce, SourceOptions{}); err == nil {
+		t.Error("NewSourceAdapter() = nil, want an error for the missing required keys")
+	}
+
+	// The token file of the source would be read from the file system of
+	// the shared adapter.
+	if _, err := NewSourceAdapter(ctx, map[string]string{
+		"NAMESPACE":                  "test-ns",
+		"NAME":                       "test-name",
+		"EVENT_SOURCE":               "test-source",
+		"PROMETHEUS_SERVER_URL":      ts.URL,
+		"PROMETHEUS_PROM_QL":         "up",
+		"PROMETHEUS_SCHEDULE":        "* * * * *",
+		"PROMETHEUS_AUTH_TOKEN_FILE": "/var/run/secrets/kubernetes.io/serviceaccount/token",
+	}, ce, SourceOptions{}); err == nil {
+		t.Error("NewSourceAdapter() = nil, want an error for the token file")
+	}
+}
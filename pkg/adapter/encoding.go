@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
+)
+
+const (
+	// csvContentType is the content type of the csv data format.
+	csvContentType = "text/csv"
+	// protobufContentType is the content type of the protobuf data format.
+	protobufContentType = "application/x-protobuf; proto=prometheus.WriteRequest"
+)
+
+// encodable returns true if the query result can be encoded in the data
+// formats other than prometheus-json, string results cannot.
+func encodable(data *datatemplate.Data) bool {
+	return data.ResultType != "string"
+}
+
+// encodeData encodes the series of a query result in the data format and
+// returns the content type of the encoded data. The series without a metric
+// name are named after the source.
+func encodeData(format v1alpha1.DataFormat, data *datatemplate.Data) (string, []byte, error) {
+	switch format {
+	case v1alpha1.DataFormatPrometheusText:
+		payload, err := encodeExposition(expfmt.FmtText, data)
+		return string(expfmt.FmtText), payload, err
+	case v1alpha1.DataFormatOpenMetricsText:
+		payload, err := encodeExposition(expfmt.FmtOpenMetrics, data)
+		return string(expfmt.FmtOpenMetrics), payload, err
+	case v1alpha1.DataFormatCSV:
+		payload, err := encodeCSV(data)
+		return csvContentType, payload, err
+	case v1alpha1.DataFormatProtobuf:
+		payload, err := encodeProtobuf(data)
+		return protobufContentType, payload, err
+	}
+	return "", nil, fmt.Errorf("unsupported data format %q", format)
+}
+
+// encodeExposition encodes the series in a text exposition format, as untyped
+// metrics grouped by metric name, one sample per line.
+func encodeExposition(format expfmt.Format, data *datatemplate.Data) ([]byte, error) {
+	var families []*dto.MetricFamily
+	byName := map[string]*dto.MetricFamily{}
+	for _, series := range data.Series {
+		name := metricName(series.Labels, data.Name)
+		family, ok := byName[name]
+		if !ok {
+			family = &dto.MetricFamily{
+				Name: proto.String(name),
+				Type: dto.MetricType_UNTYPED.Enum(),
+			}
+			byName[name] = family
+			families = append(families, family)
+		}
+		labels := labelPairs(series.Labels)
+		for _, sample := range samples(series) {
+			family.Metric = append(family.Metric, &dto.Metric{
+				Label:       labels,
+				Untyped:     &dto.Untyped{Value: proto.Float64(sample.Value)},
+				TimestampMs: proto.Int64(timestampMs(sample.Timestamp)),
+			})
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, format)
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return nil, err
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeCSV encodes the series as one row per sample, with a column per label
+// in alphabetical order followed by the timestamp and value columns.
+func encodeCSV(data *datatemplate.Data) ([]byte, error) {
+	names := map[string]struct{}{}
+	for _, series := range data.Series {
+		for name := range series.Labels {
+			names[name] = struct{}{}
+		}
+	}
+	columns := make([]string, 0, len(names)+2)
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	labelColumns := len(columns)
+	columns = append(columns, "timestamp", "value")
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, series := range data.Series {
+		row := make([]string, len(columns))
+		for i, name := range columns[:labelColumns] {
+			row[i] = series.Labels[name]
+		}
+		for _, sample := range samples(series) {
+			row[labelColumns] = sample.Timestamp.UTC().Format(time.RFC3339Nano)
+			row[labelColumns+1] = strconv.FormatFloat(sample.Value, 'g', -1, 64)
+			if err := w.Write(row); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// encodeProtobuf encodes the series as a Prometheus remote write request.
+func encodeProtobuf(data *datatemplate.Data) ([]byte, error) {
+	req := &prompb.WriteRequest{
+		Timeseries: make([]prompb.TimeSeries, 0, len(data.Series)),
+	}
+	for _, series := range data.Series {
+		ts := prompb.TimeSeries{
+			Labels: []prompb.Label{{Name: model.MetricNameLabel, Value: metricName(series.Labels, data.Name)}},
+		}
+		for _, pair := range labelPairs(series.Labels) {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: pair.GetName(), Value: pair.GetValue()})
+		}
+		sort.Slice(ts.Labels, func(i, j int) bool { return ts.Labels[i].Name < ts.Labels[j].Name })
+		for _, sample := range samples(series) {
+			ts.Samples = append(ts.Samples, prompb.Sample{
+				Value:     sample.Value,
+				Timestamp: timestampMs(sample.Timestamp),
+			})
+		}
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+	return req.Marshal()
+}
+
+// samples returns the samples of a range query series, or the single sample
+// of an instant query series.
+func samples(series datatemplate.Series) []datatemplate.Sample {
+	if series.Samples != nil {
+		return series.Samples
+	}
+	return []datatemplate.Sample{{Value: series.Value, Timestamp: series.Timestamp}}
+}
+
+// labelPairs returns the labels other than the metric name, sorted by name.
+func labelPairs(labels map[string]string) []*dto.LabelPair {
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		if name == model.MetricNameLabel {
+			continue
+		}
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+	return pairs
+}
+
+// metricName returns the metric name of the series, or the fallback made a
+// valid metric name if the series has none, as for the result of an expression.
+func metricName(labels map[string]string, fallback string) string {
+	if name := labels[model.MetricNameLabel]; name != "" {
+		return name
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, fallback)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+func timestampMs(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
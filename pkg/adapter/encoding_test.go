@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/prometheus/prompb"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
+)
+
+var encodingData = &datatemplate.Data{
+	Name:       "cpu-usage",
+	ResultType: "matrix",
+	Series: []datatemplate.Series{{
+		Labels:    map[string]string{"__name__": "up", "job": "a", "instance": "x:9090"},
+		Value:     1,
+		Timestamp: time.Unix(2, 0),
+		Samples: []datatemplate.Sample{
+			{Value: 0, Timestamp: time.Unix(1, 0)},
+			{Value: 1, Timestamp: time.Unix(2, 0)},
+		},
+	}, {
+		Labels:    map[string]string{"job": "b"},
+		Value:     0.5,
+		Timestamp: time.Unix(2, 500000000),
+	}},
+}
+
+func TestEncodeData(t *testing.T) {
+	testCases := map[string]struct {
+		format   v1alpha1.DataFormat
+		wantType string
+		wantData string
+	}{
+		"prometheus text": {
+			format:   v1alpha1.DataFormatPrometheusText,
+			wantType: "text/plain; version=0.0.4; charset=utf-8",
+			wantData: `# TYPE up untyped
+up{instance="x:9090",job="a"} 0 1000
+up{instance="x:9090",job="a"} 1 2000
+# TYPE cpu_usage untyped
+cpu_usage{job="b"} 0.5 2500
+`,
+		},
+		"openmetrics text": {
+			format:   v1alpha1.DataFormatOpenMetricsText,
+			wantType: "application/openmetrics-text; version=0.0.1; charset=utf-8",
+			wantData: `# TYPE up unknown
+up{instance="x:9090",job="a"} 0.0 1.0
+up{instance="x:9090",job="a"} 1.0 2.0
+# TYPE cpu_usage unknown
+cpu_usage{job="b"} 0.5 2.5
+# EOF
+`,
+		},
+		"csv": {
+			format:   v1alpha1.DataFormatCSV,
+			wantType: "text/csv",
+			wantData: `__name__,instance,job,timestamp,value
+up,x:9090,a,1970-01-01T00:00:01Z,0
+up,x:9090,a,1970-01-01T00:00:02Z,1
+,,b,1970-01-01T00:00:02.5Z,0.5
+`,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			gotType, gotData, err := encodeData(tc.format, encodingData)
+			if err != nil {
+				t.Fatal("encodeData() =", err)
+			}
+			if gotType != tc.wantType {
+				t.Errorf("content type = %q, want %q", gotType, tc.wantType)
+			}
+			if diff := cmp.Diff(tc.wantData, string(gotData)); diff != "" {
+				t.Errorf("unexpected data (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestEncodeDataProtobuf(t *testing.T) {
+	gotType, gotData, err := encodeData(v1alpha1.DataFormatProtobuf, encodingData)
+	if err != nil {
+		t.Fatal("encodeData() =", err)
+	}
+	if want := "application/x-protobuf; proto=prometheus.WriteRequest"; gotType != want {
+		t.Errorf("content type = %q, want %q", gotType, want)
+	}
+
+	var got prompb.WriteRequest
+	if err := got.Unmarshal(gotData); err != nil {
+		t.Fatal("Unmarshal() =", err)
+	}
+	want := prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "up"},
+				{Name: "instance", Value: "x:9090"},
+				{Name: "job", Value: "a"},
+			},
+			Samples: []prompb.Sample{{Value: 0, Timestamp: 1000}, {Value: 1, Timestamp: 2000}},
+		}, {
+			Labels:  []prompb.Label{{Name: "__name__", Value: "cpu_usage"}, {Name: "job", Value: "b"}},
+			Samples: []prompb.Sample{{Value: 0.5, Timestamp: 2500}},
+		}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected write request (-want, +got) = %v", diff)
+	}
+}
+
+func TestEncodeDataUnsupported(t *testing.T) {
+	if _, _, err := encodeData("yaml", encodingData); err == nil {
+		t.Error("encodeData() = nil, want an error")
+	}
+}
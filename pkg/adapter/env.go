@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processEnv fills the fields of spec, a pointer to a struct, from the given
+// environment rather than the one of the process, the way envconfig does: the
+// fields are named by their envconfig tag, take the value of their default tag
+// when they are not set, and are required when their required tag is true.
+// The fields of the embedded structs are filled as well.
+func processEnv(vars map[string]string, spec interface{}) error {
+	v := reflect.ValueOf(spec)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("the specification must be a struct pointer, not %T", spec)
+	}
+	return processStruct(vars, v.Elem())
+}
+
+func processStruct(vars map[string]string, s reflect.Value) error {
+	t := s.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			// Unexported field.
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := processStruct(vars, s.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		key := f.Tag.Get("envconfig")
+		if key == "" {
+			continue
+		}
+		value, ok := vars[key]
+		if def := f.Tag.Get("default"); !ok && def != "" {
+			value, ok = def, true
+		}
+		if !ok {
+			if required, _ := strconv.ParseBool(f.Tag.Get("required")); required {
+				return fmt.Errorf("required key %s missing value", key)
+			}
+			continue
+		}
+		if err := processField(value, s.Field(i)); err != nil {
+			return fmt.Errorf("envconfig.Process: assigning %s to %s: converting '%s' to type %s. details: %v",
+				key, f.Name, value, f.Type, err)
+		}
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func processField(value string, field reflect.Value) error {
+	if field.CanAddr() {
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if field.Type() == durationType {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return err
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 0, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		sl := reflect.MakeSlice(field.Type(), 0, 0)
+		if strings.TrimSpace(value) != "" {
+			vals := strings.Split(value, ",")
+			sl = reflect.MakeSlice(field.Type(), len(vals), len(vals))
+			for i, val := range vals {
+				if err := processField(val, sl.Index(i)); err != nil {
+					return err
+				}
+			}
+		}
+		field.Set(sl)
+	case reflect.Map:
+		mp := reflect.MakeMap(field.Type())
+		if strings.TrimSpace(value) != "" {
+			for _, pair := range strings.Split(value, ",") {
+				kv := strings.Split(pair, ":")
+				if len(kv) != 2 {
+					return fmt.Errorf("invalid map item: %q", pair)
+				}
+				k := reflect.New(field.Type().Key()).Elem()
+				if err := processField(kv[0], k); err != nil {
+					return err
+				}
+				v := reflect.New(field.Type().Elem()).Elem()
+				if err := processField(kv[1], v); err != nil {
+					return err
+				}
+				mp.SetMapIndex(k, v)
+			}
+		}
+		field.Set(mp)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/eventing/pkg/adapter/v2"
+)
+
+func TestProcessEnv(t *testing.T) {
+	required := map[string]string{
+		"EVENT_SOURCE":          "test-source",
+		"PROMETHEUS_SERVER_URL": "http://server.url",
+		"PROMETHEUS_PROM_QL":    "up",
+		"PROMETHEUS_SCHEDULE":   "* * * * *",
+	}
+	withRequired := func(vars map[string]string) map[string]string {
+		for k, v := range required {
+			vars[k] = v
+		}
+		return vars
+	}
+	defaults := adapter.EnvConfig{
+		Name:              "adapter",
+		ResourceGroup:     "adapter.sources.knative.dev",
+		MetricsConfigJson: "{}",
+		LoggingConfigJson: "{}",
+	}
+
+	testCases := map[string]struct {
+		vars    map[string]string
+		want    envConfig
+		wantErr string
+	}{
+		"required": {
+			vars: withRequired(map[string]string{}),
+			want: envConfig{
+				EnvConfig:   defaults,
+				EventSource: "test-source",
+				ServerURL:   "http://server.url",
+				PromQL:      "up",
+				Schedule:    "* * * * *",
+			},
+		},
+		"missing required": {
+			vars:    map[string]string{"EVENT_SOURCE": "test-source"},
+			wantErr: "required key PROMETHEUS_SERVER_URL missing value",
+		},
+		"embedded and typed": {
+			vars: withRequired(map[string]string{
+				"NAMESPACE":                       "test-ns",
+				"NAME":                            "test-name",
+				"K_SINK":                          "http://sink.url",
+				"PROMETHEUS_ALIGN_TO_STEP":        "true",
+				"PROMETHEUS_MAX_SERIES":           "100",
+				"PROMETHEUS_DELIVERY_RETRY":       "3",
+				"PROMETHEUS_JITTER":               "1m30s",
+				"PROMETHEUS_LABEL_EXTENSIONS":     "job:job,pod:pod",
+				"PROMETHEUS_PARTITION_KEY_LABELS": "job,instance",
+				"PROMETHEUS_ANNOTATIONS_FILE":     "/etc/podinfo/annotations",
+			}),
+			want: envConfig{
+				EnvConfig: adapter.EnvConfig{
+					Namespace:         "test-ns",
+					Name:              "test-name",
+					ResourceGroup:     defaults.ResourceGroup,
+					Sink:              "http://sink.url",
+					MetricsConfigJson: "{}",
+					LoggingConfigJson: "{}",
+				},
+				EventSource:        "test-source",
+				ServerURL:          "http://server.url",
+				PromQL:             "up",
+				Schedule:           "* * * * *",
+				AlignToStep:        true,
+				MaxSeries:          100,
+				DeliveryRetry:      3,
+				Jitter:             90 * time.Second,
+				LabelExtensions:    map[string]string{"job": "job", "pod": "pod"},
+				PartitionKeyLabels: []string{"job", "instance"},
+				AnnotationsFile:    "/etc/podinfo/annotations",
+			},
+		},
+		"invalid value": {
+			vars:    withRequired(map[string]string{"PROMETHEUS_MAX_SERIES": "many"}),
+			wantErr: `envconfig.Process: assigning PROMETHEUS_MAX_SERIES to MaxSeries: converting 'many' to type int64. details: strconv.ParseInt: parsing "many": invalid syntax`,
+		},
+		"invalid map": {
+			vars:    withRequired(map[string]string{"PROMETHEUS_LABEL_EXTENSIONS": "job"}),
+			wantErr: `envconfig.Process: assigning PROMETHEUS_LABEL_EXTENSIONS to LabelExtensions: converting 'job' to type map[string]string. details: invalid map item: "job"`,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var got envConfig
+			err := processEnv(tc.vars, &got)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("processEnv() = %v, want %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("processEnv() =", err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(adapter.EnvConfig{})); diff != "" {
+				t.Error("processEnv() (-want, +got) =", diff)
+			}
+		})
+	}
+}
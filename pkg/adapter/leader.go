@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
+)
+
+// The replicas of the receive adapter elect the one evaluating the query with
+// a Lease renewed every retryPeriod. A leader which could not renew it for
+// renewDeadline stops evaluating, and another replica takes over once it has
+// not been renewed for leaseDuration.
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// lastTickAnnotation records on the Lease the last tick the leader evaluated,
+// from which the next leader resumes the evaluations.
+const lastTickAnnotation = "sources.knative.dev/last-tick"
+
+// leaseLock is the Lease lock of the replicas of the receive adapter. The
+// leader records the last tick it evaluated on the Lease right after the
+// evaluation, and again when it renews the Lease if that failed. The writes
+// are serialized, so that recording the tick does not conflict with renewing
+// the Lease.
+type leaseLock struct {
+	client    coordinationv1client.LeasesGetter
+	namespace string
+	name      string
+	identity  string
+	// labels and owner are set on the Lease, so that the controller watches
+	// it and it is deleted with the source.
+	labels map[string]string
+	owner  *metav1.OwnerReference
+
+	// writeMu serializes the writes to the Lease, mu guards the fields.
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	lease   *coordinationv1.Lease
+	// tick is the last tick evaluated by the replica, recorded on the Lease
+	// unless a later one already is.
+	tick time.Time
+}
+
+var _ resourcelock.Interface = (*leaseLock)(nil)
+
+// Get returns the leader election record of the Lease.
+func (l *leaseLock) Get(ctx context.Context) (*resourcelock.LeaderElectionRecord, []byte, error) {
+	lease, err := l.client.Leases(l.namespace).Get(ctx, l.name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	l.setLease(lease)
+	record := resourcelock.LeaseSpecToLeaderElectionRecord(&lease.Spec)
+	recordByte, err := json.Marshal(*record)
+	if err != nil {
+		return nil, nil, err
+	}
+	return record, recordByte, nil
+}
+
+// Create creates the Lease with the leader election record.
+func (l *leaseLock) Create(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: l.namespace,
+			Name:      l.name,
+		},
+		Spec: resourcelock.LeaderElectionRecordToLeaseSpec(&ler),
+	}
+	l.mu.Lock()
+	l.own(lease)
+	l.annotateTick(lease)
+	l.mu.Unlock()
+	lease, err := l.client.Leases(l.namespace).Create(ctx, lease, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	l.setLease(lease)
+	return nil
+}
+
+// Update updates the leader election record of the Lease, and records the
+// last tick evaluated by the replica.
+func (l *leaseLock) Update(ctx context.Context, ler resourcelock.LeaderElectionRecord) error {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	l.mu.Lock()
+	if l.lease == nil {
+		l.mu.Unlock()
+		return errors.New("lease not initialized, call get or create first")
+	}
+	lease := l.lease.DeepCopy()
+	l.own(lease)
+	l.annotateTick(lease)
+	l.mu.Unlock()
+	lease.Spec = resourcelock.LeaderElectionRecordToLeaseSpec(&ler)
+	lease, err := l.client.Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	l.setLease(lease)
+	return nil
+}
+
+func (l *leaseLock) setLease(lease *coordinationv1.Lease) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lease = lease
+}
+
+// own sets the labels and the owner of the Lease, which a Lease created by an
+// earlier receive adapter may miss.
+func (l *leaseLock) own(lease *coordinationv1.Lease) {
+	for k, v := range l.labels {
+		if lease.Labels == nil {
+			lease.Labels = make(map[string]string, len(l.labels))
+		}
+		lease.Labels[k] = v
+	}
+	if l.owner != nil && len(lease.OwnerReferences) == 0 {
+		lease.OwnerReferences = []metav1.OwnerReference{*l.owner}
+	}
+}
+
+// annotateTick records the last tick evaluated by the replica on the Lease,
+// unless a later leader recorded a later tick.
+func (l *leaseLock) annotateTick(lease *coordinationv1.Lease) {
+	if l.tick.IsZero() || !l.tick.After(leaseTick(lease)) {
+		return
+	}
+	if lease.Annotations == nil {
+		lease.Annotations = make(map[string]string, 1)
+	}
+	lease.Annotations[lastTickAnnotation] = l.tick.UTC().Format(time.RFC3339)
+}
+
+// RecordEvent does not record the leader elections, which are logged.
+func (l *leaseLock) RecordEvent(string) {}
+
+// Describe returns the namespace and name of the Lease.
+func (l *leaseLock) Describe() string {
+	return l.namespace + "/" + l.name
+}
+
+// Identity returns the identity of the replica.
+func (l *leaseLock) Identity() string {
+	return l.identity
+}
+
+// recordTick records the tick evaluated by the leader on the Lease, so that
+// the next leader resumes after it even if this one crashes right away. A
+// tick which could not be recorded is recorded when the Lease is next renewed.
+func (l *leaseLock) recordTick(ctx context.Context, tick time.Time) error {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	l.mu.Lock()
+	l.tick = tick
+	if l.lease == nil {
+		l.mu.Unlock()
+		return errors.New("lease not initialized, call get or create first")
+	}
+	lease := l.lease.DeepCopy()
+	l.annotateTick(lease)
+	l.mu.Unlock()
+	lease, err := l.client.Leases(l.namespace).Update(ctx, lease, metav1.UpdateOptions{})
+	if err != nil {
+		return err
+	}
+	l.setLease(lease)
+	return nil
+}
+
+// lastTick returns the last tick recorded on the Lease, zero if there is
+// none.
+func (l *leaseLock) lastTick() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return leaseTick(l.lease)
+}
+
+func leaseTick(lease *coordinationv1.Lease) time.Time {
+	if lease == nil {
+		return time.Time{}
+	}
+	tick, err := time.Parse(time.RFC3339, lease.Annotations[lastTickAnnotation])
+	if err != nil {
+		return time.Time{}
+	}
+	return tick
+}
+
+// runElected evaluates the query at the ticks of the schedule while the
+// replica is the leader of the replicas of the receive adapter, until stopCh
+// is closed. The Lease is released once the evaluation in progress, if any,
+// is over, so that another replica takes over right away.
+func (a *prometheusAdapter) runElected(s *scheduler, stopCh <-chan struct{}) error {
+	lock := &leaseLock{
+		client:    a.leases,
+		namespace: a.namespace,
+		name:      a.leaseName,
+		identity:  a.identity,
+		labels:    resources.Labels(a.name),
+	}
+	if a.sourceRef != nil {
+		// The owner does not block the deletion of the source, which would
+		// require the receive adapter to update its finalizers.
+		lock.owner = &metav1.OwnerReference{
+			APIVersion: a.sourceRef.APIVersion,
+			Kind:       a.sourceRef.Kind,
+			Name:       a.sourceRef.Name,
+			UID:        a.sourceRef.UID,
+			Controller: ptr.Bool(true),
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		mu sync.Mutex
+		// term is closed when the replica stops evaluating as the leader.
+		term chan struct{}
+	)
+	go func() {
+		<-stopCh
+		mu.Lock()
+		t := term
+		mu.Unlock()
+		if t != nil {
+			<-t
+		}
+		cancel()
+	}()
+
+	for ctx.Err() == nil {
+		le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+			Lock:            lock,
+			LeaseDuration:   leaseDuration,
+			RenewDeadline:   renewDeadline,
+			RetryPeriod:     retryPeriod,
+			ReleaseOnCancel: true,
+			Name:            a.leaseName,
+			Callbacks: leaderelection.LeaderCallbacks{
+				OnStartedLeading: func(leading context.Context) {
+					t := make(chan struct{})
+					mu.Lock()
+					term = t
+					mu.Unlock()
+					defer close(t)
+					a.lead(leading, s, lock, stopCh)
+				},
+				OnStoppedLeading: func() {
+					a.logger.Infow("Not leading", zap.String("identity", a.identity))
+				},
+			},
+		})
+		if err != nil {
+			a.logger.Error("Invalid leader election", zap.Error(err))
+			return err
+		}
+		le.Run(ctx)
+
+		// A replica which lost the Lease campaigns again, once its
+		// evaluation in progress, if any, is over.
+		mu.Lock()
+		t := term
+		term = nil
+		mu.Unlock()
+		if t != nil {
+			<-t
+		}
+	}
+	return nil
+}
+
+// lead evaluates the query at the ticks of the schedule until the replica
+// stops leading or stopCh is closed.
+func (a *prometheusAdapter) lead(ctx context.Context, s *scheduler, lock *leaseLock, stopCh <-chan struct{}) {
+	a.logger.Infow("Leading", zap.String("identity", a.identity))
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopCh:
+		}
+		close(stop)
+	}()
+
+	now := time.Now()
+	tick, missed := s.next(a.leaderCatchUpFrom(s, lock.lastTick(), now), now)
+	if missed > 0 {
+		a.logger.Warnw("Skipped evaluations missed before leading", zap.Int("missed", missed), zap.Time("next", tick))
+	}
+	a.evaluate(s, tick, stop, func(tick time.Time) {
+		if err := lock.recordTick(ctx, tick); err != nil {
+			a.logger.Warnw("Failed to record the last tick on the Lease", zap.Time("tick", tick), zap.Error(err))
+		}
+	})
+}
+
+// leaderCatchUpFrom returns the time after which the ticks of the schedule
+// are evaluated by the replica elected leader at the time. It resumes from the
+// last tick evaluated by the previous leader, so that the first range query
+// covers the failover and none of the windows already sent, unless the source
+// was resumed since. The range query starts no earlier than the starting
+// deadline allows the evaluation of a missed tick to start.
+func (a *prometheusAdapter) leaderCatchUpFrom(s *scheduler, lastTick, now time.Time) time.Time {
+	resumed := !a.suspendedAt.IsZero() && !a.resumedAt.Before(a.suspendedAt) && !lastTick.After(a.suspendedAt)
+	if lastTick.IsZero() || resumed {
+		return a.catchUpFrom(now)
+	}
+	a.logger.Infow("Catching up from the last tick of the previous leader", zap.Time("lastTick", lastTick))
+	from := lastTick
+	if s.startingDeadline > 0 {
+		if earliest := now.Add(-s.startingDeadline - s.jitter); from.Before(earliest) {
+			from = earliest
+		}
+	}
+	a.lastRun = from.Add(-a.evaluationDelay)
+	return lastTick
+}
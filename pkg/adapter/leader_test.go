@@ -0,0 +1,239 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// fakeLeases stores the Leases of a namespace, it only implements Get,
+// Create and Update.
+type fakeLeases struct {
+	coordinationv1client.LeaseInterface
+	leases map[string]*coordinationv1.Lease
+}
+
+func (f *fakeLeases) Leases(string) coordinationv1client.LeaseInterface {
+	return f
+}
+
+func (f *fakeLeases) Get(_ context.Context, name string, _ metav1.GetOptions) (*coordinationv1.Lease, error) {
+	lease, ok := f.leases[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}, name)
+	}
+	return lease.DeepCopy(), nil
+}
+
+func (f *fakeLeases) Create(_ context.Context, lease *coordinationv1.Lease, _ metav1.CreateOptions) (*coordinationv1.Lease, error) {
+	f.leases[lease.Name] = lease.DeepCopy()
+	return lease, nil
+}
+
+func (f *fakeLeases) Update(_ context.Context, lease *coordinationv1.Lease, _ metav1.UpdateOptions) (*coordinationv1.Lease, error) {
+	f.leases[lease.Name] = lease.DeepCopy()
+	return lease, nil
+}
+
+func TestLeaseLock(t *testing.T) {
+	ctx := context.Background()
+	leases := &fakeLeases{leases: make(map[string]*coordinationv1.Lease)}
+	lock := &leaseLock{client: leases, namespace: "test-ns", name: "test-lease", identity: "replica-a"}
+	tick := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, _, err := lock.Get(ctx); !apierrors.IsNotFound(err) {
+		t.Fatalf("Get() = %v, want not found", err)
+	}
+	if err := lock.Create(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "replica-a", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatal("Create() =", err)
+	}
+	if got := lock.lastTick(); !got.IsZero() {
+		t.Errorf("lastTick() = %v, want none", got)
+	}
+
+	// The tick is recorded right away, and kept when the Lease is renewed.
+	if err := lock.recordTick(ctx, tick); err != nil {
+		t.Fatal("recordTick() =", err)
+	}
+	if got, want := leases.leases["test-lease"].Annotations[lastTickAnnotation], "2022-03-01T12:00:00Z"; got != want {
+		t.Errorf("%s = %q, want %q", lastTickAnnotation, got, want)
+	}
+	if err := lock.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "replica-a", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if got, want := leases.leases["test-lease"].Annotations[lastTickAnnotation], "2022-03-01T12:00:00Z"; got != want {
+		t.Errorf("%s = %q, want %q", lastTickAnnotation, got, want)
+	}
+	if got := *leases.leases["test-lease"].Spec.HolderIdentity; got != "replica-a" {
+		t.Errorf("HolderIdentity = %q, want replica-a", got)
+	}
+
+	// Another replica takes over and reads the tick.
+	other := &leaseLock{client: leases, namespace: "test-ns", name: "test-lease", identity: "replica-b"}
+	record, _, err := other.Get(ctx)
+	if err != nil {
+		t.Fatal("Get() =", err)
+	}
+	if record.HolderIdentity != "replica-a" {
+		t.Errorf("HolderIdentity = %q, want replica-a", record.HolderIdentity)
+	}
+	if got := other.lastTick(); !got.Equal(tick) {
+		t.Errorf("lastTick() = %v, want %v", got, tick)
+	}
+	if err := other.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "replica-b", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if err := other.recordTick(ctx, tick.Add(time.Minute)); err != nil {
+		t.Fatal("recordTick() =", err)
+	}
+
+	// The former leader does not record an earlier tick.
+	if _, _, err := lock.Get(ctx); err != nil {
+		t.Fatal("Get() =", err)
+	}
+	if err := lock.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "replica-a", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if got, want := lock.lastTick(), tick.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("lastTick() = %v, want %v", got, want)
+	}
+}
+
+func TestLeaseLockOwner(t *testing.T) {
+	ctx := context.Background()
+	owner := metav1.OwnerReference{
+		APIVersion: "sources.knative.dev/v1alpha1",
+		Kind:       "PrometheusSource",
+		Name:       "test-name",
+		UID:        "test-uid",
+	}
+	leases := &fakeLeases{leases: map[string]*coordinationv1.Lease{
+		// A Lease created by an earlier receive adapter.
+		"test-lease": {ObjectMeta: metav1.ObjectMeta{Namespace: "test-ns", Name: "test-lease"}},
+	}}
+	lock := &leaseLock{
+		client:    leases,
+		namespace: "test-ns",
+		name:      "test-lease",
+		identity:  "replica-a",
+		labels:    map[string]string{"knative-eventing-source-name": "test-name"},
+		owner:     &owner,
+	}
+
+	if _, _, err := lock.Get(ctx); err != nil {
+		t.Fatal("Get() =", err)
+	}
+	if err := lock.Update(ctx, resourcelock.LeaderElectionRecord{HolderIdentity: "replica-a", LeaseDurationSeconds: 15}); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	lease := leases.leases["test-lease"]
+	if got := lease.Labels["knative-eventing-source-name"]; got != "test-name" {
+		t.Errorf("knative-eventing-source-name = %q, want test-name", got)
+	}
+	if diff := cmp.Diff([]metav1.OwnerReference{owner}, lease.OwnerReferences); diff != "" {
+		t.Error("OwnerReferences (-want, +got) =", diff)
+	}
+}
+
+func TestLeaderCatchUpFrom(t *testing.T) {
+	now := time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC)
+	suspendedAt := now.Add(-time.Hour)
+
+	testCases := map[string]struct {
+		lastTick         time.Time
+		startingDeadline time.Duration
+		suspendedAt      time.Time
+		resumedAt        time.Time
+		want             time.Time
+		wantLastRun      time.Time
+	}{
+		"first leader": {
+			want: now,
+		},
+		"failover": {
+			lastTick:    now.Add(-90 * time.Second),
+			want:        now.Add(-90 * time.Second),
+			wantLastRun: now.Add(-100 * time.Second),
+		},
+		"last tick long ago": {
+			lastTick:    now.Add(-time.Hour),
+			want:        now.Add(-time.Hour),
+			wantLastRun: now.Add(-time.Hour - 10*time.Second),
+		},
+		"last tick before the starting deadline": {
+			lastTick:         now.Add(-time.Hour),
+			startingDeadline: 5 * time.Minute,
+			want:             now.Add(-time.Hour),
+			wantLastRun:      now.Add(-5*time.Minute - 15*time.Second),
+		},
+		"last tick within the starting deadline": {
+			lastTick:         now.Add(-90 * time.Second),
+			startingDeadline: 5 * time.Minute,
+			want:             now.Add(-90 * time.Second),
+			wantLastRun:      now.Add(-100 * time.Second),
+		},
+		"last tick before the suspension": {
+			lastTick:    suspendedAt.Add(-time.Minute),
+			suspendedAt: suspendedAt,
+			resumedAt:   now.Add(-time.Minute),
+			want:        suspendedAt,
+			wantLastRun: suspendedAt.Add(-10 * time.Second),
+		},
+		"last tick before a suspension out of the catch up window": {
+			lastTick:    suspendedAt.Add(-time.Minute),
+			suspendedAt: suspendedAt,
+			resumedAt:   now.Add(-catchUpWindow),
+			want:        now,
+		},
+		"last tick since the resumption": {
+			lastTick:    now.Add(-30 * time.Second),
+			suspendedAt: suspendedAt,
+			resumedAt:   now.Add(-time.Minute),
+			want:        now.Add(-30 * time.Second),
+			wantLastRun: now.Add(-40 * time.Second),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{
+				logger:          zap.NewExample().Sugar(),
+				evaluationDelay: 10 * time.Second,
+				suspendedAt:     tc.suspendedAt,
+				resumedAt:       tc.resumedAt,
+			}
+			s := &scheduler{jitter: 5 * time.Second, startingDeadline: tc.startingDeadline}
+			if got := a.leaderCatchUpFrom(s, tc.lastTick, now); !got.Equal(tc.want) {
+				t.Errorf("leaderCatchUpFrom() = %v, want %v", got, tc.want)
+			}
+			if !a.lastRun.Equal(tc.wantLastRun) {
+				t.Errorf("lastRun = %v, want %v", a.lastRun, tc.wantLastRun)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// muteWindow is a window during which the source is muted.
+type muteWindow struct {
+	// schedule starts the recurring window, which then lasts duration, in
+	// the location. It is nil for an absolute window from start to end.
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+	start    time.Time
+	end      time.Time
+	action   v1alpha1.MuteAction
+}
+
+// parseMuteWindows parses the JSON mute windows of the source.
+func parseMuteWindows(data string) ([]muteWindow, error) {
+	var specs []v1alpha1.PrometheusSourceMuteWindow
+	if err := json.Unmarshal([]byte(data), &specs); err != nil {
+		return nil, err
+	}
+	windows := make([]muteWindow, len(specs))
+	for i, spec := range specs {
+		w := &windows[i]
+		w.action = spec.Action
+		if w.action == "" {
+			w.action = v1alpha1.MuteActionSuppress
+		}
+		if spec.Schedule == "" {
+			if spec.Start != nil {
+				w.start = spec.Start.Time
+			}
+			if spec.End != nil {
+				w.end = spec.End.Time
+			}
+			continue
+		}
+		sched, err := cron.ParseStandard(spec.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		w.schedule = sched
+		if spec.Duration != nil {
+			w.duration = spec.Duration.Duration
+		}
+		// An empty time zone is UTC.
+		if w.location, err = time.LoadLocation(spec.TimeZone); err != nil {
+			return nil, err
+		}
+	}
+	return windows, nil
+}
+
+// active returns true if the time is within the window.
+func (w *muteWindow) active(now time.Time) bool {
+	if w.schedule == nil {
+		return !now.Before(w.start) && now.Before(w.end)
+	}
+	// The window is active if the schedule started it within its duration
+	// before now, the schedule runs in the time zone of the time.
+	return !w.schedule.Next(now.In(w.location).Add(-w.duration)).After(now)
+}
+
+// annotationsMuteUntil returns the function reading the time the mute-until
+// annotation mutes the source until from the downward API annotations file of
+// the pod, which the kubelet updates without restarting it. The time is zero
+// when the pod has no such annotation.
+func annotationsMuteUntil(path string) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		// Each line holds an annotation, as key="quoted value".
+		for _, line := range strings.Split(string(data), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 || kv[0] != v1alpha1.MuteUntilAnnotation {
+				continue
+			}
+			value, err := strconv.Unquote(kv[1])
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid %s annotation %s: %w", v1alpha1.MuteUntilAnnotation, kv[1], err)
+			}
+			return time.Parse(time.RFC3339, value)
+		}
+		return time.Time{}, nil
+	}
+}
+
+// refreshMuteUntil reads the time the mute-until annotation mutes the source
+// until, which changes while the adapter runs. The source stays muted until
+// the last time read if it cannot be read.
+func (a *prometheusAdapter) refreshMuteUntil() {
+	if a.readMuteUntil == nil {
+		return
+	}
+	muteUntil, err := a.readMuteUntil()
+	if err != nil {
+		a.logger.Warnw("Unable to read the mute-until annotation", zap.Error(err))
+		return
+	}
+	a.muteUntil = muteUntil
+}
+
+// muteAction returns what the adapter does with the evaluation at the time,
+// an empty action if the source is not muted. Skipping the evaluation takes
+// precedence over suppressing its events.
+func (a *prometheusAdapter) muteAction(now time.Time) v1alpha1.MuteAction {
+	var action v1alpha1.MuteAction
+	if now.Before(a.muteUntil) {
+		action = v1alpha1.MuteActionSuppress
+	}
+	for i := range a.muteWindows {
+		w := &a.muteWindows[i]
+		if !w.active(now) {
+			continue
+		}
+		if w.action == v1alpha1.MuteActionSkip {
+			return v1alpha1.MuteActionSkip
+		}
+		action = v1alpha1.MuteActionSuppress
+	}
+	return action
+}
+
+// reportMuted records on the PrometheusSource that it was muted or unmuted,
+// when the mute action of the evaluation changes whether it is.
+func (a *prometheusAdapter) reportMuted(action v1alpha1.MuteAction) {
+	muted := action != ""
+	if muted == a.muted {
+		return
+	}
+	a.muted = muted
+	if !muted {
+		a.logger.Info("Source unmuted")
+		if a.recorder != nil {
+			a.recorder.Event(a.sourceRef, corev1.EventTypeNormal, "Unmuted", "Sending events again")
+		}
+		return
+	}
+	a.logger.Infow("Source muted", zap.String("action", string(action)))
+	if a.recorder != nil {
+		a.recorder.Eventf(a.sourceRef, corev1.EventTypeNormal, "Muted", "Muted, mute action: %s", action)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+func TestMuteWindowActive(t *testing.T) {
+	windows, err := parseMuteWindows(`[
+		{"schedule": "0 2 * * 6", "duration": "2h", "timeZone": "Europe/Paris"},
+		{"start": "2022-03-01T10:00:00Z", "end": "2022-03-01T11:00:00Z", "action": "skip"}
+	]`)
+	if err != nil {
+		t.Fatal("parseMuteWindows() =", err)
+	}
+
+	testCases := map[string]struct {
+		window int
+		now    string
+		want   bool
+	}{
+		"before the schedule": {
+			now: "2022-03-05T00:59:59Z",
+		},
+		"schedule start in its time zone": {
+			now:  "2022-03-05T01:00:00Z",
+			want: true,
+		},
+		"within the duration": {
+			now:  "2022-03-05T02:59:59Z",
+			want: true,
+		},
+		"after the duration": {
+			now: "2022-03-05T03:00:00Z",
+		},
+		"another day": {
+			now: "2022-03-04T01:30:00Z",
+		},
+		"before the absolute window": {
+			window: 1,
+			now:    "2022-03-01T09:59:59Z",
+		},
+		"absolute window start": {
+			window: 1,
+			now:    "2022-03-01T10:00:00Z",
+			want:   true,
+		},
+		"absolute window end": {
+			window: 1,
+			now:    "2022-03-01T11:00:00Z",
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tc.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := windows[tc.window].active(now); got != tc.want {
+				t.Errorf("active(%s) = %t, want %t", tc.now, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMuteAction(t *testing.T) {
+	now := time.Date(2022, time.March, 1, 10, 30, 0, 0, time.UTC)
+	window := func(start, end time.Time, action v1alpha1.MuteAction) muteWindow {
+		return muteWindow{start: start, end: end, action: action}
+	}
+
+	testCases := map[string]struct {
+		windows   []muteWindow
+		muteUntil time.Time
+		want      v1alpha1.MuteAction
+	}{
+		"not muted": {
+			windows:   []muteWindow{window(now.Add(time.Hour), now.Add(2*time.Hour), v1alpha1.MuteActionSkip)},
+			muteUntil: now,
+		},
+		"muted until": {
+			muteUntil: now.Add(time.Minute),
+			want:      v1alpha1.MuteActionSuppress,
+		},
+		"skip takes precedence": {
+			windows: []muteWindow{
+				window(now.Add(-time.Hour), now.Add(time.Hour), v1alpha1.MuteActionSuppress),
+				window(now.Add(-time.Hour), now.Add(time.Hour), v1alpha1.MuteActionSkip),
+			},
+			muteUntil: now.Add(time.Minute),
+			want:      v1alpha1.MuteActionSkip,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{muteWindows: tc.windows, muteUntil: tc.muteUntil}
+			if got := a.muteAction(now); got != tc.want {
+				t.Errorf("muteAction() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAnnotationsMuteUntil(t *testing.T) {
+	testCases := map[string]struct {
+		annotations string
+		want        time.Time
+		wantErr     bool
+	}{
+		"no annotations": {},
+		"not muted": {
+			annotations: `kubernetes.io/config.seen="2022-03-01T10:00:00Z"` + "\n",
+		},
+		"muted until": {
+			annotations: `kubernetes.io/config.seen="2022-03-01T10:00:00Z"` + "\n" +
+				`prometheus.sources.knative.dev/mute-until="2022-03-01T18:00:00Z"` + "\n",
+			want: time.Date(2022, time.March, 1, 18, 0, 0, 0, time.UTC),
+		},
+		"invalid time": {
+			annotations: `prometheus.sources.knative.dev/mute-until="tomorrow"`,
+			wantErr:     true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "annotations")
+			if err := ioutil.WriteFile(path, []byte(tc.annotations), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := annotationsMuteUntil(path)()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("annotationsMuteUntil() = %v, wantErr %t", err, tc.wantErr)
+			}
+			if !got.Equal(tc.want) {
+				t.Errorf("annotationsMuteUntil() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRefreshMuteUntil(t *testing.T) {
+	muteUntil := time.Now().Add(time.Hour)
+	var readErr error
+	a := &prometheusAdapter{
+		logger: zap.NewExample().Sugar(),
+		readMuteUntil: func() (time.Time, error) {
+			return muteUntil, readErr
+		},
+	}
+
+	a.refreshMuteUntil()
+	if got := a.muteAction(time.Now()); got != v1alpha1.MuteActionSuppress {
+		t.Errorf("muteAction() = %q, want %q", got, v1alpha1.MuteActionSuppress)
+	}
+	// The source stays muted when the annotation cannot be read.
+	muteUntil, readErr = time.Time{}, errors.New("read error")
+	a.refreshMuteUntil()
+	if got := a.muteAction(time.Now()); got != v1alpha1.MuteActionSuppress {
+		t.Errorf("muteAction() = %q, want %q", got, v1alpha1.MuteActionSuppress)
+	}
+	// Removing the annotation unmutes the source.
+	readErr = nil
+	a.refreshMuteUntil()
+	if got := a.muteAction(time.Now()); got != "" {
+		t.Errorf("muteAction() = %q, want not muted", got)
+	}
+}
+
+func TestMutedEvaluation(t *testing.T) {
+	empty := `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	data := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1,"1"]}]}}`
+
+	testCases := map[string]struct {
+		responses []string
+		muted     []bool
+		skip      bool
+		wantTypes []string
+		wantQuery int
+	}{
+		"no data while muted": {
+			responses: []string{data, empty, empty, empty},
+			muted:     []bool{false, true, true, false},
+			wantTypes: []string{"com.example.up", "com.example.up.nodata"},
+			wantQuery: 4,
+		},
+		"data returned while muted": {
+			responses: []string{empty, data, data},
+			muted:     []bool{false, true, false},
+			wantTypes: []string{"com.example.up.nodata", "com.example.up", "com.example.up.datareturned"},
+			wantQuery: 3,
+		},
+		"no data only while muted": {
+			responses: []string{data, empty, data, data},
+			muted:     []bool{false, true, true, false},
+			wantTypes: []string{"com.example.up", "com.example.up"},
+			wantQuery: 4,
+		},
+		"skipped evaluations": {
+			responses: []string{data, data},
+			muted:     []bool{true, false},
+			skip:      true,
+			wantTypes: []string{"com.example.up"},
+			wantQuery: 1,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var queries int
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tc.responses[queries])
+				queries++
+			}))
+			defer ts.Close()
+
+			ce := adaptertest.NewTestClient()
+			a := &prometheusAdapter{
+				ce:            ce,
+				name:          "test-name",
+				logger:        zap.NewExample().Sugar(),
+				serverURL:     ts.URL,
+				promQL:        "up",
+				eventType:     "com.example.up",
+				client:        &http.Client{},
+				onEmptyResult: v1alpha1.EmptyResultPolicyEmitNoData,
+			}
+			for _, muted := range tc.muted {
+				a.muteWindows, a.muteUntil = nil, time.Time{}
+				if muted && tc.skip {
+					a.muteWindows = []muteWindow{{
+						start:  time.Now().Add(-time.Hour),
+						end:    time.Now().Add(time.Hour),
+						action: v1alpha1.MuteActionSkip,
+					}}
+				} else if muted {
+					a.muteUntil = time.Now().Add(time.Hour)
+				}
+				a.send(time.Now())
+			}
+
+			var gotTypes []string
+			for _, event := range ce.Sent() {
+				gotTypes = append(gotTypes, event.Type())
+			}
+			if diff := cmp.Diff(tc.wantTypes, gotTypes); diff != "" {
+				t.Errorf("unexpected event types (-want, +got) = %v", diff)
+			}
+			if queries != tc.wantQuery {
+				t.Errorf("queries = %d, want %d", queries, tc.wantQuery)
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultOutboxMaxBytes bounds the size of the outbox when the source
+	// does not.
+	defaultOutboxMaxBytes = 64 << 20
+
+	// The reasons the outbox drops an event for, the dropReason tag of the
+	// dropped events metric.
+	dropReasonCount = "count"
+	dropReasonSize  = "size"
+	dropReasonAge   = "age"
+)
+
+// delivery is the outcome of sending an event.
+type delivery int
+
+const (
+	// delivered is an event accepted by the sink or the dead letter sink.
+	delivered delivery = iota
+	// rejected is an event that will not be accepted by retrying it.
+	rejected
+	// unavailable is an event the sink could not receive, which may be
+	// accepted once it recovers.
+	unavailable
+)
+
+// outbox is the on-disk queue of the events the sink could not receive. Each
+// event is a file named after its position in the queue and the time it was
+// queued at, so that the queue survives restarts of the receive adapter.
+type outbox struct {
+	dir       string
+	maxEvents int64
+	maxBytes  int64
+	maxAge    time.Duration
+	logger    *zap.SugaredLogger
+	// onDepth is called with the number of events in the outbox when it
+	// changes, and onDrop with the reason of every event it drops.
+	onDepth func(depth int)
+	onDrop  func(reason string)
+	// now returns the current time, replaced by the tests.
+	now func() time.Time
+
+	// mu serializes the deliveries, so that the events are replayed in order
+	// before the new ones.
+	mu      sync.Mutex
+	entries []outboxEntry
+	bytes   int64
+	next    uint64
+}
+
+// outboxEntry is an event in the outbox.
+type outboxEntry struct {
+	name   string
+	size   int64
+	queued time.Time
+}
+
+// newOutbox opens the outbox in the directory, with the events a previous
+// receive adapter left in it.
+func newOutbox(dir string, maxEvents, maxBytes int64, maxAge time.Duration, logger *zap.SugaredLogger) (*outbox, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultOutboxMaxBytes
+	}
+	o := &outbox{
+		dir:       dir,
+		maxEvents: maxEvents,
+		maxBytes:  maxBytes,
+		maxAge:    maxAge,
+		logger:    logger,
+		now:       time.Now,
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		position, queued, ok := parseOutboxName(f.Name())
+		if !ok {
+			// The hidden file of an event whose write was interrupted is
+			// removed, the files the outbox did not write are left alone.
+			if _, _, ok := parseOutboxName(strings.TrimPrefix(f.Name(), ".")); ok {
+				if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		o.entries = append(o.entries, outboxEntry{name: f.Name(), size: f.Size(), queued: queued})
+		o.bytes += f.Size()
+		if position >= o.next {
+			o.next = position + 1
+		}
+	}
+	// The names sort in queue order.
+	sort.Slice(o.entries, func(i, j int) bool { return o.entries[i].name < o.entries[j].name })
+	return o, nil
+}
+
+// send replays the events in the outbox, then delivers the event, queuing it
+// if the sink is unavailable or if events queued before it could not be
+// replayed. It returns true unless the event was rejected.
+func (o *outbox) send(event cloudevents.Event, deliver func(cloudevents.Event) delivery) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.replayLocked(deliver)
+	if len(o.entries) == 0 {
+		switch deliver(event) {
+		case delivered:
+			return true
+		case rejected:
+			return false
+		}
+	}
+	if err := o.pushLocked(event); err != nil {
+		o.logger.Errorw("Failed to queue the event in the outbox", zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// replay delivers the events in the outbox, in order, until the sink is
+// unavailable.
+func (o *outbox) replay(deliver func(cloudevents.Event) delivery) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.replayLocked(deliver)
+}
+
+func (o *outbox) replayLocked(deliver func(cloudevents.Event) delivery) {
+	if len(o.entries) == 0 {
+		return
+	}
+	defer o.reportDepth()
+	for len(o.entries) > 0 {
+		entry := o.entries[0]
+		if o.maxAge > 0 && o.now().Sub(entry.queued) > o.maxAge {
+			o.dropLocked(dropReasonAge)
+			continue
+		}
+		event, err := o.read(entry)
+		if err != nil {
+			o.logger.Errorw("Dropping an unreadable event from the outbox", zap.String("file", entry.name), zap.Error(err))
+			o.removeLocked()
+			continue
+		}
+		if deliver(event) == unavailable {
+			return
+		}
+		o.removeLocked()
+	}
+}
+
+// pushLocked queues the event, dropping the oldest events to stay within the
+// limits.
+func (o *outbox) pushLocked(event cloudevents.Event) error {
+	defer o.reportDepth()
+	data, err := event.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > o.maxBytes {
+		o.reportDrop(dropReasonSize)
+		return nil
+	}
+
+	queued := o.now()
+	name := outboxName(o.next, queued)
+	// The event is written to a hidden file first, so that an interrupted
+	// write is not replayed.
+	tmp := filepath.Join(o.dir, "."+name)
+	if err := ioutil.WriteFile(tmp, data, 0o600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(o.dir, name)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	o.next++
+	o.entries = append(o.entries, outboxEntry{name: name, size: int64(len(data)), queued: queued})
+	o.bytes += int64(len(data))
+
+	for o.maxEvents > 0 && int64(len(o.entries)) > o.maxEvents {
+		o.dropLocked(dropReasonCount)
+	}
+	for o.bytes > o.maxBytes {
+		o.dropLocked(dropReasonSize)
+	}
+	return nil
+}
+
+// depth returns the number of events in the outbox.
+func (o *outbox) depth() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+// outboxName returns the name of the file of the event at the position in
+// the queue, queued at the time.
+func outboxName(position uint64, queued time.Time) string {
+	return fmt.Sprintf("%020d-%d.json", position, queued.UnixNano())
+}
+
+// parseOutboxName returns the position and queuing time of the event in the
+// file, false if it is not the file of an event.
+func parseOutboxName(name string) (uint64, time.Time, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".json"), "-")
+	if len(parts) != 2 || !strings.HasSuffix(name, ".json") {
+		return 0, time.Time{}, false
+	}
+	position, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	queued, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	return position, time.Unix(0, queued), true
+}
+
+func (o *outbox) read(entry outboxEntry) (cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	data, err := ioutil.ReadFile(filepath.Join(o.dir, entry.name))
+	if err != nil {
+		return event, err
+	}
+	err = event.UnmarshalJSON(data)
+	return event, err
+}
+
+// dropLocked drops the oldest event for the reason.
+func (o *outbox) dropLocked(reason string) {
+	o.logger.Warnw("Dropping an event from the outbox", zap.String("file", o.entries[0].name), zap.String("reason", reason))
+	o.removeLocked()
+	o.reportDrop(reason)
+}
+
+// removeLocked removes the oldest event.
+func (o *outbox) removeLocked() {
+	entry := o.entries[0]
+	if err := os.Remove(filepath.Join(o.dir, entry.name)); err != nil && !os.IsNotExist(err) {
+		o.logger.Errorw("Failed to remove an event from the outbox", zap.String("file", entry.name), zap.Error(err))
+	}
+	o.entries = o.entries[1:]
+	o.bytes -= entry.size
+}
+
+func (o *outbox) reportDepth() {
+	if o.onDepth != nil {
+		o.onDepth(len(o.entries))
+	}
+}
+
+func (o *outbox) reportDrop(reason string) {
+	if o.onDrop != nil {
+		o.onDrop(reason)
+	}
+}
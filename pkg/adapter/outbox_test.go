@@ -0,0 +1,277 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+)
+
+// resultTestClient fails the sends with the result, and records the events
+// once it is nil.
+type resultTestClient struct {
+	*adaptertest.TestCloudEventsClient
+	result error
+}
+
+func (c *resultTestClient) Send(ctx context.Context, event cloudevents.Event) protocol.Result {
+	if c.result != nil {
+		return c.result
+	}
+	return c.TestCloudEventsClient.Send(ctx, event)
+}
+
+func newOutboxEvent(id string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetType("com.example.up")
+	event.SetSource("com.example")
+	return event
+}
+
+// outboxSink records the IDs of the events it receives while it is available.
+type outboxSink struct {
+	available bool
+	received  []string
+}
+
+func (s *outboxSink) deliver(event cloudevents.Event) delivery {
+	if !s.available {
+		return unavailable
+	}
+	s.received = append(s.received, event.ID())
+	return delivered
+}
+
+func newTestOutbox(t *testing.T, dir string, maxEvents, maxBytes int64, maxAge time.Duration) *outbox {
+	t.Helper()
+	o, err := newOutbox(dir, maxEvents, maxBytes, maxAge, zap.NewExample().Sugar())
+	if err != nil {
+		t.Fatal("newOutbox() =", err)
+	}
+	return o
+}
+
+func TestOutboxReplaysInOrder(t *testing.T) {
+	o := newTestOutbox(t, t.TempDir(), 0, 0, 0)
+	sink := &outboxSink{}
+
+	for _, id := range []string{"1", "2", "3"} {
+		if !o.send(newOutboxEvent(id), sink.deliver) {
+			t.Errorf("send(%s) = false, want the event queued", id)
+		}
+	}
+	if got := o.depth(); got != 3 {
+		t.Errorf("depth() = %d, want 3", got)
+	}
+
+	// The queued events are sent before the new one.
+	sink.available = true
+	if !o.send(newOutboxEvent("4"), sink.deliver) {
+		t.Error("send(4) = false, want true")
+	}
+	if diff := cmp.Diff([]string{"1", "2", "3", "4"}, sink.received); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+	if got := o.depth(); got != 0 {
+		t.Errorf("depth() = %d, want 0", got)
+	}
+}
+
+func TestOutboxRejectedEvent(t *testing.T) {
+	o := newTestOutbox(t, t.TempDir(), 0, 0, 0)
+	if o.send(newOutboxEvent("1"), func(cloudevents.Event) delivery { return rejected }) {
+		t.Error("send() = true, want false")
+	}
+	if got := o.depth(); got != 0 {
+		t.Errorf("depth() = %d, want the rejected event not queued", got)
+	}
+}
+
+func TestOutboxLimits(t *testing.T) {
+	size := func() int64 {
+		data, _ := newOutboxEvent("1").MarshalJSON()
+		return int64(len(data))
+	}()
+
+	testCases := map[string]struct {
+		maxEvents   int64
+		maxBytes    int64
+		maxAge      time.Duration
+		wantEvents  []string
+		wantDropped []string
+	}{
+		"unbounded": {
+			wantEvents: []string{"1", "2", "3", "4"},
+		},
+		"max events": {
+			maxEvents:   2,
+			wantEvents:  []string{"3", "4"},
+			wantDropped: []string{dropReasonCount, dropReasonCount},
+		},
+		"max bytes": {
+			maxBytes:    3 * size,
+			wantEvents:  []string{"2", "3", "4"},
+			wantDropped: []string{dropReasonSize},
+		},
+		"event larger than max bytes": {
+			maxBytes:    size - 1,
+			wantDropped: []string{dropReasonSize, dropReasonSize, dropReasonSize, dropReasonSize},
+		},
+		"max age": {
+			// The events are queued a minute apart and replayed a minute
+			// after the last one.
+			maxAge:      150 * time.Second,
+			wantEvents:  []string{"3", "4"},
+			wantDropped: []string{dropReasonAge, dropReasonAge},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			o := newTestOutbox(t, t.TempDir(), tc.maxEvents, tc.maxBytes, tc.maxAge)
+			now := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+			o.now = func() time.Time { return now }
+			var dropped []string
+			o.onDrop = func(reason string) { dropped = append(dropped, reason) }
+
+			sink := &outboxSink{}
+			for _, id := range []string{"1", "2", "3", "4"} {
+				o.send(newOutboxEvent(id), sink.deliver)
+				now = now.Add(time.Minute)
+			}
+			sink.available = true
+			o.replay(sink.deliver)
+
+			if diff := cmp.Diff(tc.wantEvents, sink.received); diff != "" {
+				t.Errorf("unexpected events (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantDropped, dropped); diff != "" {
+				t.Errorf("unexpected dropped events (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestOutboxReopen(t *testing.T) {
+	dir := t.TempDir()
+	o := newTestOutbox(t, dir, 0, 0, 0)
+	sink := &outboxSink{}
+	o.send(newOutboxEvent("1"), sink.deliver)
+	o.send(newOutboxEvent("2"), sink.deliver)
+
+	// An event whose write was interrupted, and a file of someone else.
+	interrupted := "." + outboxName(2, time.Now())
+	for _, name := range []string{interrupted, "README"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("{"), 0o600); err != nil {
+			t.Fatal("WriteFile() =", err)
+		}
+	}
+
+	// The receive adapter restarts.
+	o = newTestOutbox(t, dir, 0, 0, 0)
+	if got := o.depth(); got != 2 {
+		t.Errorf("depth() = %d, want 2", got)
+	}
+	o.send(newOutboxEvent("3"), sink.deliver)
+	sink.available = true
+	o.replay(sink.deliver)
+	if diff := cmp.Diff([]string{"1", "2", "3"}, sink.received); diff != "" {
+		t.Errorf("unexpected events (-want, +got) = %v", diff)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal("ReadDir() =", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+	if diff := cmp.Diff([]string{"README"}, names); diff != "" {
+		t.Errorf("unexpected files (-want, +got) = %v", diff)
+	}
+}
+
+func TestSendEventOutbox(t *testing.T) {
+	testCases := map[string]struct {
+		result    error
+		want      bool
+		wantDepth int
+	}{
+		"sink unavailable": {
+			result:    cehttp.NewResult(http.StatusServiceUnavailable, "unavailable"),
+			want:      true,
+			wantDepth: 1,
+		},
+		"sink unreachable": {
+			result:    os.ErrDeadlineExceeded,
+			want:      true,
+			wantDepth: 1,
+		},
+		"event rejected": {
+			result: cehttp.NewResult(http.StatusBadRequest, "bad request"),
+			want:   false,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			ce := &resultTestClient{TestCloudEventsClient: adaptertest.NewTestClient(), result: tc.result}
+			a := &prometheusAdapter{
+				ce:        ce,
+				logger:    zap.NewExample().Sugar(),
+				outboxDir: filepath.Join(t.TempDir(), "outbox"),
+			}
+			if err := a.openOutbox(); err != nil {
+				t.Fatal("openOutbox() =", err)
+			}
+			event := newOutboxEvent("1")
+			if got := a.sendEvent(&event); got != tc.want {
+				t.Errorf("sendEvent() = %t, want %t", got, tc.want)
+			}
+			if got := a.outbox.depth(); got != tc.wantDepth {
+				t.Errorf("depth() = %d, want %d", got, tc.wantDepth)
+			}
+
+			// The sink recovers.
+			ce.result = nil
+			a.outbox.replay(a.deliver)
+			var ids []string
+			for _, event := range ce.Sent() {
+				ids = append(ids, event.ID())
+			}
+			if tc.wantDepth > 0 {
+				if diff := cmp.Diff([]string{"1"}, ids); diff != "" {
+					t.Errorf("unexpected replayed events (-want, +got) = %v", diff)
+				}
+			}
+		})
+	}
+}
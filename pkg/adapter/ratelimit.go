@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultRateLimitInterval is the interval of a rate limit without one.
+	defaultRateLimitInterval = time.Minute
+
+	// rateLimitSampleSize is the number of suppressed events described in a
+	// ratelimited event.
+	rateLimitSampleSize = 5
+)
+
+// rateLimiter suppresses the query result events exceeding the rate limit of
+// the source, and keeps count of them until they are summed up.
+type rateLimiter struct {
+	limiter  *rate.Limiter
+	events   int64
+	interval time.Duration
+	burst    int
+
+	mu         sync.Mutex
+	suppressed int64
+	since      time.Time
+	sample     []suppressedEvent
+}
+
+// suppressedEvent describes an event suppressed by the rate limit.
+type suppressedEvent struct {
+	ID      string            `json:"id"`
+	Subject string            `json:"subject,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// rateLimitSummary sums up the events suppressed since the last summary, it
+// is the data of a ratelimited event.
+type rateLimitSummary struct {
+	Suppressed int64             `json:"suppressed"`
+	Since      time.Time         `json:"since"`
+	Limit      rateLimitSpec     `json:"limit"`
+	Sample     []suppressedEvent `json:"sample"`
+}
+
+// rateLimitSpec is the rate limit of the source.
+type rateLimitSpec struct {
+	Events   int64  `json:"events"`
+	Interval string `json:"interval"`
+	Burst    int    `json:"burst"`
+}
+
+// newRateLimiter creates a token bucket refilled with events tokens every
+// interval, holding up to burst tokens.
+func newRateLimiter(events int64, interval time.Duration, burst int64) *rateLimiter {
+	if interval <= 0 {
+		interval = defaultRateLimitInterval
+	}
+	if burst <= 0 {
+		burst = events
+	}
+	return &rateLimiter{
+		limiter:  rate.NewLimiter(rate.Limit(float64(events)/interval.Seconds()), int(burst)),
+		events:   events,
+		interval: interval,
+		burst:    int(burst),
+	}
+}
+
+// allow returns true if the event may be sent at the time, and counts it as
+// suppressed otherwise.
+func (l *rateLimiter) allow(event *cloudevents.Event, labels map[string]string, now time.Time) bool {
+	if l.limiter.AllowN(now, 1) {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.suppressed == 0 {
+		l.since = now
+	}
+	l.suppressed++
+	if len(l.sample) < rateLimitSampleSize {
+		l.sample = append(l.sample, suppressedEvent{
+			ID:      event.ID(),
+			Subject: event.Subject(),
+			Labels:  labels,
+		})
+	}
+	return false
+}
+
+// summary returns the summary of the events suppressed since the last one,
+// nil if there are none.
+func (l *rateLimiter) summary() *rateLimitSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.suppressed == 0 {
+		return nil
+	}
+	s := &rateLimitSummary{
+		Suppressed: l.suppressed,
+		Since:      l.since,
+		Limit: rateLimitSpec{
+			Events:   l.events,
+			Interval: l.interval.String(),
+			Burst:    l.burst,
+		},
+		Sample: l.sample,
+	}
+	l.suppressed = 0
+	l.sample = nil
+	return s
+}
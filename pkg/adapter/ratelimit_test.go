@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+)
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(6, time.Minute, 2)
+
+	var allowed []string
+	for i := 1; i <= 10; i++ {
+		event := cloudevents.NewEvent()
+		event.SetID(strconv.Itoa(i))
+		if l.allow(&event, map[string]string{"job": "a"}, start) {
+			allowed = append(allowed, event.ID())
+		}
+	}
+	if diff := cmp.Diff([]string{"1", "2"}, allowed); diff != "" {
+		t.Errorf("unexpected allowed events (-want, +got) = %v", diff)
+	}
+
+	got := l.summary()
+	want := &rateLimitSummary{
+		Suppressed: 8,
+		Since:      start,
+		Limit:      rateLimitSpec{Events: 6, Interval: "1m0s", Burst: 2},
+		Sample: []suppressedEvent{
+			{ID: "3", Labels: map[string]string{"job": "a"}},
+			{ID: "4", Labels: map[string]string{"job": "a"}},
+			{ID: "5", Labels: map[string]string{"job": "a"}},
+			{ID: "6", Labels: map[string]string{"job": "a"}},
+			{ID: "7", Labels: map[string]string{"job": "a"}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected summary (-want, +got) = %v", diff)
+	}
+	if got := l.summary(); got != nil {
+		t.Errorf("summary() = %v, want nil once summed up", got)
+	}
+
+	// A token is added every 10 seconds.
+	event := cloudevents.NewEvent()
+	if !l.allow(&event, nil, start.Add(10*time.Second)) {
+		t.Error("allow() = false, want true after the bucket refilled")
+	}
+	if l.allow(&event, nil, start.Add(10*time.Second)) {
+		t.Error("allow() = true, want false once the refilled token is used")
+	}
+}
+
+func TestRateLimiterDefaults(t *testing.T) {
+	l := newRateLimiter(30, 0, 0)
+	if l.interval != time.Minute {
+		t.Errorf("interval = %v, want %v", l.interval, time.Minute)
+	}
+	if l.burst != 30 {
+		t.Errorf("burst = %d, want 30", l.burst)
+	}
+}
+
+func TestRateLimitedEvent(t *testing.T) {
+	var series []string
+	for i := 0; i < 5; i++ {
+		series = append(series, fmt.Sprintf(`{"metric":{"pod":"p%d"},"value":[1,"1"]}`, i))
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"vector","result":[%s]}}`, strings.Join(series, ","))
+	}))
+	defer ts.Close()
+
+	ce := adaptertest.NewTestClient()
+	a := &prometheusAdapter{
+		ce:             ce,
+		name:           "test-name",
+		logger:         zap.NewExample().Sugar(),
+		serverURL:      ts.URL,
+		promQL:         "up",
+		eventType:      "com.example.up",
+		client:         &http.Client{},
+		seriesPerEvent: 1,
+		rateLimiter:    newRateLimiter(1, time.Hour, 2),
+	}
+	a.send(time.Now())
+
+	var gotTypes []string
+	for _, event := range ce.Sent() {
+		gotTypes = append(gotTypes, event.Type())
+	}
+	if diff := cmp.Diff([]string{"com.example.up", "com.example.up", "com.example.up.ratelimited"}, gotTypes); diff != "" {
+		t.Fatalf("unexpected event types (-want, +got) = %v", diff)
+	}
+
+	var summary rateLimitSummary
+	if err := json.Unmarshal(ce.Sent()[2].Data(), &summary); err != nil {
+		t.Fatal("Unmarshal() =", err)
+	}
+	if summary.Suppressed != 3 {
+		t.Errorf("suppressed = %d, want 3", summary.Suppressed)
+	}
+	var pods []string
+	for _, s := range summary.Sample {
+		pods = append(pods, s.Labels["pod"])
+	}
+	if diff := cmp.Diff([]string{"p2", "p3", "p4"}, pods); diff != "" {
+		t.Errorf("unexpected sample (-want, +got) = %v", diff)
+	}
+	// The suppressed events are not numbered.
+	if got, want := ce.Sent()[2].Extensions()[sequenceExtension], ce.Sent()[0].Time().Unix(); got != fmt.Sprintf("%012d-000003", want) {
+		t.Errorf("sequence = %v, want %012d-000003", got, want)
+	}
+}
@@ -0,0 +1,632 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
+)
+
+const (
+	limitSeries  = "series"
+	limitSamples = "samples"
+	limitBytes   = "bytes"
+)
+
+// maxChunkBytes is the size of the series after which a chunk is complete,
+// whatever the number of series per event, so that the memory a query result
+// takes is bounded by the size of a chunk.
+const maxChunkBytes = 1 << 20
+
+// errLimitReached stops the decoding of a query response once the rest of it
+// is of no use.
+var errLimitReached = errors.New("query result limit reached")
+
+// queryResponse is the envelope of a Prometheus HTTP API query response.
+type queryResponse struct {
+	Status    string     `json:"status"`
+	Data      *queryData `json:"data,omitempty"`
+	ErrorType string     `json:"errorType,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Warnings  []string   `json:"warnings,omitempty"`
+}
+
+// queryData is the data of a Prometheus HTTP API query response. The result
+// of vector and matrix queries is streamed series by series rather than held
+// in Result.
+type queryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+// seriesSamples is the part of a vector or matrix series holding its samples.
+type seriesSamples struct {
+	Value  json.RawMessage   `json:"value"`
+	Values []json.RawMessage `json:"values"`
+}
+
+// seriesMetric is the part of a vector or matrix series holding its labels.
+type seriesMetric struct {
+	Metric map[string]string `json:"metric"`
+}
+
+// seriesData is a vector or matrix series, as decoded for the data templates.
+type seriesData struct {
+	Metric map[string]string  `json:"metric"`
+	Value  *model.SamplePair  `json:"value"`
+	Values []model.SamplePair `json:"values"`
+}
+
+// resultLimits bounds the size of the query results sent in one event.
+type resultLimits struct {
+	maxSeries  int64
+	maxSamples int64
+	maxBytes   int64
+	policy     v1alpha1.OverflowPolicy
+}
+
+// value returns the value of the named limit.
+func (l *resultLimits) value(limit string) int64 {
+	switch limit {
+	case limitSeries:
+		return l.maxSeries
+	case limitSamples:
+		return l.maxSamples
+	default:
+		return l.maxBytes
+	}
+}
+
+// chunk is a part of a query result fitting within the limits, sent as one event.
+type chunk struct {
+	// index numbers the chunks of a query result from 1.
+	index int
+	// last is true for the last chunk of a query result.
+	last bool
+
+	series []json.RawMessage
+	resultSize
+
+	// truncated is true when part of the query result is missing from the chunk.
+	truncated bool
+	// exceeded is the name of the limit that ended the chunk, if any.
+	exceeded string
+}
+
+// resultSize is the size of a query result, or of a part of it, counted
+// against the limits.
+type resultSize struct {
+	seriesCount int64
+	samples     int64
+	bytes       int64
+}
+
+// exceeds returns the name of the first limit the size would exceed if a
+// series of the given samples and bytes was added to it, or the empty string
+// if it fits.
+func (z *resultSize) exceeds(l *resultLimits, samples, bytes int64) string {
+	switch {
+	case l.maxSeries > 0 && z.seriesCount+1 > l.maxSeries:
+		return limitSeries
+	case l.maxSamples > 0 && z.samples+samples > l.maxSamples:
+		return limitSamples
+	case l.maxBytes > 0 && z.bytes+bytes > l.maxBytes:
+		return limitBytes
+	}
+	return ""
+}
+
+func (z *resultSize) add(samples, bytes int64) {
+	z.seriesCount++
+	z.samples += samples
+	z.bytes += bytes
+}
+
+func (c *chunk) add(series json.RawMessage, samples, bytes int64) {
+	c.series = append(c.series, series)
+	c.resultSize.add(samples, bytes)
+}
+
+// emptyResult returns true if the chunk is the whole of a successful vector or
+// matrix query result without any series.
+func (c *chunk) emptyResult(resp *queryResponse) bool {
+	return c.index == 1 && c.last && len(c.series) == 0 && !c.truncated &&
+		resp.Status == "success" && resp.Data != nil && resp.Data.Result == nil
+}
+
+// commonLabels returns the labels shared, with the same value, by every
+// series of the chunk.
+func (c *chunk) commonLabels() (map[string]string, error) {
+	var labels map[string]string
+	for i, series := range c.series {
+		var s seriesMetric
+		if err := json.Unmarshal(series, &s); err != nil {
+			return nil, fmt.Errorf("failed to decode series labels: %w", err)
+		}
+		if i == 0 {
+			labels = s.Metric
+			continue
+		}
+		for name, value := range labels {
+			if v, ok := s.Metric[name]; !ok || v != value {
+				delete(labels, name)
+			}
+		}
+	}
+	return labels, nil
+}
+
+// templateData decodes the query response restricted to the chunk for the
+// data template of the source.
+func (c *chunk) templateData(resp *queryResponse) (*datatemplate.Data, error) {
+	data := &datatemplate.Data{}
+	if c.last {
+		data.Warnings = resp.Warnings
+	}
+	if resp.Data == nil {
+		return data, nil
+	}
+	data.ResultType = resp.Data.ResultType
+
+	switch resp.Data.ResultType {
+	case "scalar":
+		var scalar model.Scalar
+		if err := json.Unmarshal(resp.Data.Result, &scalar); err != nil {
+			return nil, fmt.Errorf("failed to decode scalar result: %w", err)
+		}
+		data.Series = []datatemplate.Series{{
+			Value:     float64(scalar.Value),
+			Timestamp: scalar.Timestamp.Time(),
+		}}
+		return data, nil
+	case "string":
+		var str model.String
+		if err := json.Unmarshal(resp.Data.Result, &str); err != nil {
+			return nil, fmt.Errorf("failed to decode string result: %w", err)
+		}
+		data.String = str.Value
+		return data, nil
+	}
+
+	data.Series = make([]datatemplate.Series, 0, len(c.series))
+	for _, raw := range c.series {
+		var s seriesData
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, fmt.Errorf("failed to decode series: %w", err)
+		}
+		series := datatemplate.Series{Labels: s.Metric}
+		if s.Value != nil {
+			series.Value = float64(s.Value.Value)
+			series.Timestamp = s.Value.Timestamp.Time()
+		}
+		for _, sample := range s.Values {
+			series.Samples = append(series.Samples, datatemplate.Sample{
+				Value:     float64(sample.Value),
+				Timestamp: sample.Timestamp.Time(),
+			})
+			series.Value = float64(sample.Value)
+			series.Timestamp = sample.Timestamp.Time()
+		}
+		data.Series = append(data.Series, series)
+	}
+	return data, nil
+}
+
+// resultStream cuts a query result into chunks while the query response is
+// decoded. A chunk is passed to emit as soon as the next one is complete, so
+// that no more than two chunks are held in memory, and so that the last chunk
+// is known when it is emitted and carries the trailing warnings of the response.
+// With the drop overflow policy, the chunks are held until the whole result is
+// known to fit within the limits, so that no part of a dropped result is sent.
+type resultStream struct {
+	limits *resultLimits
+	// seriesPerEvent is the number of series after which a chunk is
+	// complete, zero leaves chunks bounded by maxChunkBytes and the limits
+	// only.
+	seriesPerEvent int64
+	// relabeling relabels the series before they are cut into chunks.
+	relabeling []*relabel.Config
+	emit       func(resp *queryResponse, c *chunk) error
+
+	response queryResponse
+	pending  *chunk
+	current  *chunk
+	// held are the chunks completed before pending, until the result is
+	// known not to be dropped.
+	held []*chunk
+	// total is the size of the query result read so far, which the limits
+	// bound unless the result is split.
+	total resultSize
+
+	// exceeded is the name of the first limit the query result exceeded.
+	exceeded string
+	// dropped is true when the rest of the query result was dropped because
+	// it exceeded the limits.
+	dropped bool
+}
+
+// streamResult streams a Prometheus HTTP API query response, relabeling its
+// series and cutting its result into chunks according to the limits, their
+// overflow policy and the number of series per event, and passing each chunk
+// to emit. When the result is truncated or dropped, decoding stops as soon as
+// a limit is exceeded, leaving the rest of the response unread.
+func streamResult(r io.Reader, limits *resultLimits, seriesPerEvent int64, relabeling []*relabel.Config,
+	emit func(*queryResponse, *chunk) error) (*resultStream, error) {
+	s := &resultStream{
+		limits:         limits,
+		seriesPerEvent: seriesPerEvent,
+		relabeling:     relabeling,
+		emit:           emit,
+		current:        &chunk{index: 1},
+	}
+
+	if limits.maxBytes > 0 && limits.policy != v1alpha1.OverflowPolicySplit {
+		r = &limitedReader{r: r, n: limits.maxBytes}
+	}
+
+	err := decodeQueryResponse(r, &s.response, s.onSeries)
+	switch {
+	case errors.Is(err, errLimitReached):
+	case errors.Is(err, errResponseTooLarge):
+		s.overflow(limitBytes)
+	case err != nil:
+		return s, err
+	}
+	return s, s.finish()
+}
+
+func (s *resultStream) onSeries(series json.RawMessage) error {
+	if len(s.relabeling) > 0 {
+		relabeled, err := relabelSeries(series, s.relabeling)
+		if err != nil {
+			return err
+		}
+		if relabeled == nil {
+			// The series is dropped.
+			return nil
+		}
+		series = relabeled
+	}
+
+	var samples int64
+	if s.limits.maxSamples > 0 {
+		var ss seriesSamples
+		if err := json.Unmarshal(series, &ss); err != nil {
+			return fmt.Errorf("failed to decode series: %w", err)
+		}
+		samples = int64(len(ss.Values))
+		if ss.Value != nil {
+			samples++
+		}
+	}
+	bytes := int64(len(series))
+
+	if s.limits.policy != v1alpha1.OverflowPolicySplit {
+		// The limits bound the whole result.
+		if limit := s.total.exceeds(s.limits, samples, bytes); limit != "" {
+			s.overflow(limit)
+			return errLimitReached
+		}
+		s.total.add(samples, bytes)
+	} else if limit := s.current.exceeds(s.limits, samples, bytes); limit != "" {
+		// The limits bound every chunk.
+		if s.exceeded == "" {
+			s.exceeded = limit
+		}
+		s.current.exceeded = limit
+		if (&chunk{}).exceeds(s.limits, samples, bytes) != "" {
+			// The series alone exceeds the limits, leave it out.
+			s.current.truncated = true
+			return nil
+		}
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	s.current.add(series, samples, bytes)
+	if s.seriesPerEvent > 0 && int64(len(s.current.series)) >= s.seriesPerEvent || s.current.bytes >= maxChunkBytes {
+		return s.rotate()
+	}
+	return nil
+}
+
+// relabelSeries applies the relabel configs to the labels of a series, leaving
+// its samples as they were received. It returns nil when the series is dropped.
+func relabelSeries(series json.RawMessage, relabeling []*relabel.Config) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(series, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode series: %w", err)
+	}
+	var metric map[string]string
+	if raw, ok := fields["metric"]; ok {
+		if err := json.Unmarshal(raw, &metric); err != nil {
+			return nil, fmt.Errorf("failed to decode series labels: %w", err)
+		}
+	}
+
+	lset := relabel.Process(labels.FromMap(metric), relabeling...)
+	if lset == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, lset.Map()); err != nil {
+		return nil, err
+	}
+	fields["metric"] = buf.Bytes()
+	var relabeled bytes.Buffer
+	if err := writeJSON(&relabeled, fields); err != nil {
+		return nil, err
+	}
+	return relabeled.Bytes(), nil
+}
+
+// overflow ends the query result on the named limit, truncating or dropping
+// the rest of it.
+func (s *resultStream) overflow(limit string) {
+	if s.exceeded == "" {
+		s.exceeded = limit
+	}
+	if s.limits.policy == v1alpha1.OverflowPolicyDrop {
+		s.dropped = true
+		return
+	}
+	c := s.current
+	if len(c.series) == 0 && s.pending != nil {
+		// The completed chunk is the last one of the truncated result.
+		c = s.pending
+	}
+	c.exceeded = limit
+	c.truncated = true
+}
+
+// rotate completes the current chunk, emitting the previously completed one,
+// or holding it with the drop overflow policy.
+func (s *resultStream) rotate() error {
+	if s.pending != nil && s.limits.policy == v1alpha1.OverflowPolicyDrop {
+		s.held = append(s.held, s.pending)
+	} else if s.pending != nil {
+		if err := s.emit(&s.response, s.pending); err != nil {
+			return err
+		}
+	}
+	s.pending = s.current
+	s.current = &chunk{index: s.pending.index + 1}
+	return nil
+}
+
+// finish emits the chunks left once the query response has been decoded.
+func (s *resultStream) finish() error {
+	if s.dropped {
+		// No part of the result is sent.
+		return nil
+	}
+	for _, c := range s.held {
+		if err := s.emit(&s.response, c); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case s.pending == nil || len(s.current.series) > 0 || s.current.truncated:
+		if s.pending != nil {
+			if err := s.emit(&s.response, s.pending); err != nil {
+				return err
+			}
+		}
+		s.current.last = true
+		return s.emit(&s.response, s.current)
+	default:
+		s.pending.last = true
+		return s.emit(&s.response, s.pending)
+	}
+}
+
+// decodeQueryResponse streams a Prometheus HTTP API query response into resp,
+// calling onSeries for every series of a vector or matrix result instead of
+// holding them in resp. Decoding stops on the first error, including the ones
+// returned by onSeries.
+func decodeQueryResponse(r io.Reader, resp *queryResponse, onSeries func(json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "status":
+			err = dec.Decode(&resp.Status)
+		case "errorType":
+			err = dec.Decode(&resp.ErrorType)
+		case "error":
+			err = dec.Decode(&resp.Error)
+		case "warnings":
+			err = dec.Decode(&resp.Warnings)
+		case "data":
+			resp.Data = &queryData{}
+			err = decodeQueryData(dec, resp.Data, onSeries)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func decodeQueryData(dec *json.Decoder, data *queryData, onSeries func(json.RawMessage) error) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch {
+		case key == "resultType":
+			err = dec.Decode(&data.ResultType)
+		case key == "result" && (data.ResultType == "vector" || data.ResultType == "matrix"):
+			err = decodeSeries(dec, onSeries)
+		default:
+			// Scalar and string results are a single sample, and Prometheus
+			// always sends the result type first.
+			var value json.RawMessage
+			if err = dec.Decode(&value); err == nil && key == "result" {
+				data.Result = value
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+func decodeSeries(dec *json.Decoder, onSeries func(json.RawMessage) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var series json.RawMessage
+		if err := dec.Decode(&series); err != nil {
+			return err
+		}
+		if err := onSeries(series); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if got, ok := tok.(json.Delim); !ok || got != want {
+		return fmt.Errorf("unexpected token %v in query response, expected %v", tok, want)
+	}
+	return nil
+}
+
+// errResponseTooLarge is returned by limitedReader once its limit is exceeded.
+var errResponseTooLarge = errors.New("query response too large")
+
+// limitedReader reads from r until more than n bytes have been read, then
+// fails with errResponseTooLarge.
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, errResponseTooLarge
+	}
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		// Hand out what was read within the limit, the next read fails.
+		return n + int(l.n), nil
+	}
+	return n, err
+}
+
+// payload writes the query response restricted to the given chunk. The series
+// are copied as they were received, without being encoded again.
+func (c *chunk) payload(resp *queryResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(int(c.bytes) + len(c.series) + 128)
+
+	buf.WriteString(`{"status":`)
+	if err := writeJSON(&buf, resp.Status); err != nil {
+		return nil, err
+	}
+	if resp.Data != nil {
+		buf.WriteString(`,"data":{"resultType":`)
+		if err := writeJSON(&buf, resp.Data.ResultType); err != nil {
+			return nil, err
+		}
+		buf.WriteString(`,"result":`)
+		if resp.Data.Result != nil {
+			buf.Write(resp.Data.Result)
+		} else {
+			buf.WriteByte('[')
+			for i, series := range c.series {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				buf.Write(series)
+			}
+			buf.WriteByte(']')
+		}
+		buf.WriteByte('}')
+	}
+	for _, field := range []struct {
+		name  string
+		value interface{}
+		set   bool
+	}{
+		{"errorType", resp.ErrorType, resp.ErrorType != ""},
+		{"error", resp.Error, resp.Error != ""},
+		{"warnings", resp.Warnings, c.last && len(resp.Warnings) > 0},
+	} {
+		if !field.set {
+			continue
+		}
+		buf.WriteString(`,"` + field.name + `":`)
+		if err := writeJSON(&buf, field.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// writeJSON is like json.Marshal but writes to buf, and leaves the HTML
+// characters as they were sent by Prometheus.
+func writeJSON(buf *bytes.Buffer, v interface{}) error {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	// Encode terminates the value with a newline.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
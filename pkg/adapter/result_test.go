@@ -0,0 +1,496 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
+)
+
+const (
+	seriesA = `{"metric":{"job":"a"},"values":[[1,"1"],[2,"2"]]}`
+	seriesB = `{"metric":{"job":"b"},"values":[[1,"1"],[2,"2"]]}`
+	seriesC = `{"metric":{"job":"c"},"values":[[1,"1"],[2,"2"],[3,"3"],[4,"4"],[5,"5"]]}`
+)
+
+func matrixResponse(series ...string) string {
+	return `{"status":"success","data":{"resultType":"matrix","result":[` + strings.Join(series, ",") + `]}}`
+}
+
+func TestStreamResult(t *testing.T) {
+	testCases := map[string]struct {
+		response       string
+		limits         resultLimits
+		seriesPerEvent int64
+		wantChunks     []sentChunk
+		wantExceeded   string
+		wantDropped    bool
+	}{
+		"no limits": {
+			response:   matrixResponse(seriesA, seriesB, seriesC),
+			wantChunks: []sentChunk{{payload: matrixResponse(seriesA, seriesB, seriesC), index: 1, last: true}},
+		},
+		"within limits": {
+			response:   matrixResponse(seriesA, seriesB),
+			limits:     resultLimits{maxSeries: 2, maxSamples: 4, policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{{payload: matrixResponse(seriesA, seriesB), index: 1, last: true}},
+		},
+		"empty": {
+			response:   matrixResponse(),
+			wantChunks: []sentChunk{{payload: matrixResponse(), index: 1, last: true}},
+		},
+		"truncate series": {
+			response: matrixResponse(seriesA, seriesB, seriesC),
+			limits:   resultLimits{maxSeries: 2, policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA, seriesB), index: 1, last: true, truncated: true, exceeded: limitSeries},
+			},
+			wantExceeded: limitSeries,
+		},
+		"truncate samples": {
+			response: matrixResponse(seriesA, seriesC, seriesB),
+			limits:   resultLimits{maxSamples: 6, policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA), index: 1, last: true, truncated: true, exceeded: limitSamples},
+			},
+			wantExceeded: limitSamples,
+		},
+		"truncate bytes": {
+			response: matrixResponse(seriesA, seriesB, seriesC),
+			limits:   resultLimits{maxBytes: int64(len(matrixResponse(seriesA, seriesB))), policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA, seriesB), index: 1, last: true, truncated: true, exceeded: limitBytes},
+			},
+			wantExceeded: limitBytes,
+		},
+		"split": {
+			response: matrixResponse(seriesA, seriesB, seriesC),
+			limits:   resultLimits{maxSamples: 5, policy: v1alpha1.OverflowPolicySplit},
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA, seriesB), index: 1, exceeded: limitSamples},
+				{payload: matrixResponse(seriesC), index: 2, last: true},
+			},
+			wantExceeded: limitSamples,
+		},
+		"split leaves out oversized series": {
+			response: matrixResponse(seriesA, seriesC, seriesB),
+			limits:   resultLimits{maxSamples: 4, policy: v1alpha1.OverflowPolicySplit},
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA, seriesB), index: 1, last: true, truncated: true, exceeded: limitSamples},
+			},
+			wantExceeded: limitSamples,
+		},
+		"series per event": {
+			response:       matrixResponse(seriesA, seriesB, seriesC),
+			seriesPerEvent: 1,
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA), index: 1},
+				{payload: matrixResponse(seriesB), index: 2},
+				{payload: matrixResponse(seriesC), index: 3, last: true},
+			},
+		},
+		"series per event within split limits": {
+			response:       matrixResponse(seriesA, seriesB, seriesC),
+			limits:         resultLimits{maxSamples: 5, policy: v1alpha1.OverflowPolicySplit},
+			seriesPerEvent: 2,
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA, seriesB), index: 1},
+				{payload: matrixResponse(seriesC), index: 2, last: true},
+			},
+		},
+		"warnings are sent with the last chunk": {
+			response:       `{"status":"success","data":{"resultType":"matrix","result":[` + seriesA + `,` + seriesB + `]},"warnings":["partial <result>"]}`,
+			seriesPerEvent: 1,
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA), index: 1},
+				{payload: `{"status":"success","data":{"resultType":"matrix","result":[` + seriesB + `]},"warnings":["partial <result>"]}`, index: 2, last: true},
+			},
+		},
+		"drop": {
+			response:     matrixResponse(seriesA, seriesB, seriesC),
+			limits:       resultLimits{maxSeries: 2, policy: v1alpha1.OverflowPolicyDrop},
+			wantExceeded: limitSeries,
+			wantDropped:  true,
+		},
+		"drop bytes": {
+			response:     matrixResponse(seriesA, seriesB, seriesC),
+			limits:       resultLimits{maxBytes: 64, policy: v1alpha1.OverflowPolicyDrop},
+			wantExceeded: limitBytes,
+			wantDropped:  true,
+		},
+		"drop sends none of the completed chunks": {
+			response:       matrixResponse(seriesA, seriesB, seriesC),
+			limits:         resultLimits{maxSamples: 4, policy: v1alpha1.OverflowPolicyDrop},
+			seriesPerEvent: 1,
+			wantExceeded:   limitSamples,
+			wantDropped:    true,
+		},
+		"drop sends the chunks within limits": {
+			response:       matrixResponse(seriesA, seriesB, seriesC),
+			limits:         resultLimits{maxSeries: 3, policy: v1alpha1.OverflowPolicyDrop},
+			seriesPerEvent: 1,
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA), index: 1},
+				{payload: matrixResponse(seriesB), index: 2},
+				{payload: matrixResponse(seriesC), index: 3, last: true},
+			},
+		},
+		"truncate bounds the whole result": {
+			response:       matrixResponse(seriesA, seriesB, seriesC),
+			limits:         resultLimits{maxSeries: 2, policy: v1alpha1.OverflowPolicyTruncate},
+			seriesPerEvent: 1,
+			wantChunks: []sentChunk{
+				{payload: matrixResponse(seriesA), index: 1},
+				{payload: matrixResponse(seriesB), index: 2, last: true, truncated: true, exceeded: limitSeries},
+			},
+			wantExceeded: limitSeries,
+		},
+		"scalar": {
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"1"]}}`,
+			limits:   resultLimits{maxSeries: 1, policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{
+				{payload: `{"status":"success","data":{"resultType":"scalar","result":[1435781451.781,"1"]}}`, index: 1, last: true},
+			},
+		},
+		"error": {
+			response: `{"status":"error","errorType":"bad_data","error":"parse error"}`,
+			limits:   resultLimits{maxSeries: 1, policy: v1alpha1.OverflowPolicyTruncate},
+			wantChunks: []sentChunk{
+				{payload: `{"status":"error","errorType":"bad_data","error":"parse error"}`, index: 1, last: true},
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var sent []sentChunk
+			got, err := streamResult(strings.NewReader(tc.response), &tc.limits, tc.seriesPerEvent, nil, recordChunks(t, &sent))
+			if err != nil {
+				t.Fatalf("streamResult() = %v", err)
+			}
+			if got.exceeded != tc.wantExceeded {
+				t.Errorf("exceeded = %q, want %q", got.exceeded, tc.wantExceeded)
+			}
+			if got.dropped != tc.wantDropped {
+				t.Errorf("dropped = %v, want %v", got.dropped, tc.wantDropped)
+			}
+			if diff := cmp.Diff(tc.wantChunks, sent, cmp.AllowUnexported(sentChunk{})); diff != "" {
+				t.Errorf("unexpected chunks (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+// sentChunk is a chunk as passed to the emit function of streamResult.
+type sentChunk struct {
+	payload   string
+	index     int
+	last      bool
+	truncated bool
+	exceeded  string
+}
+
+func recordChunks(t *testing.T, sent *[]sentChunk) func(*queryResponse, *chunk) error {
+	return func(resp *queryResponse, c *chunk) error {
+		payload, err := c.payload(resp)
+		if err != nil {
+			t.Fatalf("payload() = %v", err)
+		}
+		*sent = append(*sent, sentChunk{
+			payload:   string(payload),
+			index:     c.index,
+			last:      c.last,
+			truncated: c.truncated,
+			exceeded:  c.exceeded,
+		})
+		return nil
+	}
+}
+
+func TestStreamResultEmitsWhileDecoding(t *testing.T) {
+	// The response breaks off after the third series, the chunks completed
+	// before must already have been emitted.
+	r := io.MultiReader(
+		strings.NewReader(`{"status":"success","data":{"resultType":"matrix","result":[`+seriesA+`,`+seriesB+`,`+seriesC),
+		iotest.ErrReader(errors.New("connection reset")),
+	)
+	var sent []sentChunk
+	if _, err := streamResult(r, &resultLimits{}, 1, nil, recordChunks(t, &sent)); err == nil {
+		t.Error("streamResult() = nil, want error")
+	}
+	want := []sentChunk{
+		{payload: matrixResponse(seriesA), index: 1},
+		{payload: matrixResponse(seriesB), index: 2},
+	}
+	if diff := cmp.Diff(want, sent, cmp.AllowUnexported(sentChunk{})); diff != "" {
+		t.Errorf("unexpected chunks (-want, +got) = %v", diff)
+	}
+}
+
+func TestStreamResultMaxChunkBytes(t *testing.T) {
+	// Without series per event nor limits, the result is still cut into
+	// chunks of bounded size.
+	response := largeMatrixResponse(600, 240)
+	var sizes []int
+	var last bool
+	emit := func(resp *queryResponse, c *chunk) error {
+		sizes = append(sizes, int(c.bytes))
+		last = c.last
+		return nil
+	}
+	if _, err := streamResult(bytes.NewReader(response), &resultLimits{}, 0, nil, emit); err != nil {
+		t.Fatalf("streamResult() = %v", err)
+	}
+	if len(sizes) < 2 || !last {
+		t.Fatalf("chunks = %v, last = %v, want several chunks ending with the last one", sizes, last)
+	}
+	for i, size := range sizes {
+		// A chunk is complete with the series reaching maxChunkBytes.
+		if size >= maxChunkBytes+len(response)/600 {
+			t.Errorf("chunk %d holds %d bytes of series, want less than %d", i+1, size, maxChunkBytes+len(response)/600)
+		}
+	}
+}
+
+func TestStreamResultRelabel(t *testing.T) {
+	relabeling, err := v1alpha1.ParseRelabelConfigs([]v1alpha1.RelabelConfig{
+		{SourceLabels: []string{"job"}, Regex: ptr.String("b"), Action: "drop"},
+		{SourceLabels: []string{"job"}, TargetLabel: "service", Replacement: ptr.String("svc-$1")},
+		{Action: "labeldrop", Regex: ptr.String("pod_template_hash")},
+	})
+	if err != nil {
+		t.Fatal("ParseRelabelConfigs() =", err)
+	}
+	response := matrixResponse(
+		`{"metric":{"job":"a","pod_template_hash":"5d8f"},"values":[[1,"1"]]}`,
+		`{"metric":{"job":"b"},"values":[[1,"1"]]}`,
+		`{"metric":{"job":"c<d>"},"values":[[1,"1"]]}`,
+	)
+	var sent []sentChunk
+	if _, err := streamResult(strings.NewReader(response), &resultLimits{maxSeries: 1, policy: v1alpha1.OverflowPolicySplit},
+		0, relabeling, recordChunks(t, &sent)); err != nil {
+		t.Fatal("streamResult() =", err)
+	}
+	// The dropped series does not count against the limits.
+	want := []sentChunk{
+		{payload: matrixResponse(`{"metric":{"job":"a","service":"svc-a"},"values":[[1,"1"]]}`), index: 1, exceeded: limitSeries},
+		{payload: matrixResponse(`{"metric":{"job":"c<d>","service":"svc-c<d>"},"values":[[1,"1"]]}`), index: 2, last: true},
+	}
+	if diff := cmp.Diff(want, sent, cmp.AllowUnexported(sentChunk{})); diff != "" {
+		t.Errorf("unexpected chunks (-want, +got) = %v", diff)
+	}
+}
+
+func TestStreamResultInvalid(t *testing.T) {
+	emit := func(*queryResponse, *chunk) error { return nil }
+	if _, err := streamResult(strings.NewReader(`<html>Bad Gateway</html>`), &resultLimits{}, 0, nil, emit); err == nil {
+		t.Error("streamResult() = nil, want error")
+	}
+}
+
+func TestChunkCommonLabels(t *testing.T) {
+	testCases := map[string]struct {
+		series []string
+		want   map[string]string
+	}{
+		"no series": {},
+		"one series": {
+			series: []string{`{"metric":{"job":"api","instance":"a"},"value":[1,"1"]}`},
+			want:   map[string]string{"job": "api", "instance": "a"},
+		},
+		"shared labels": {
+			series: []string{
+				`{"metric":{"job":"api","instance":"a","code":"500"},"value":[1,"1"]}`,
+				`{"metric":{"job":"api","instance":"b"},"value":[1,"1"]}`,
+			},
+			want: map[string]string{"job": "api"},
+		},
+		"no shared labels": {
+			series: []string{seriesA, seriesB},
+			want:   map[string]string{},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			c := &chunk{}
+			for _, series := range tc.series {
+				c.add([]byte(series), 1, int64(len(series)))
+			}
+			got, err := c.commonLabels()
+			if err != nil {
+				t.Fatal("commonLabels() =", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected labels (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+func TestChunkTemplateData(t *testing.T) {
+	testCases := map[string]struct {
+		response string
+		want     *datatemplate.Data
+	}{
+		"vector": {
+			response: `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"a"},"value":[1,"0.5"]}]},"warnings":["w"]}`,
+			want: &datatemplate.Data{
+				ResultType: "vector",
+				Series: []datatemplate.Series{{
+					Labels:    map[string]string{"job": "a"},
+					Value:     0.5,
+					Timestamp: time.Unix(1, 0),
+				}},
+				Warnings: []string{"w"},
+			},
+		},
+		"matrix": {
+			response: matrixResponse(seriesA),
+			want: &datatemplate.Data{
+				ResultType: "matrix",
+				Series: []datatemplate.Series{{
+					Labels:    map[string]string{"job": "a"},
+					Value:     2,
+					Timestamp: time.Unix(2, 0),
+					Samples: []datatemplate.Sample{
+						{Value: 1, Timestamp: time.Unix(1, 0)},
+						{Value: 2, Timestamp: time.Unix(2, 0)},
+					},
+				}},
+			},
+		},
+		"scalar": {
+			response: `{"status":"success","data":{"resultType":"scalar","result":[1.5,"42"]}}`,
+			want: &datatemplate.Data{
+				ResultType: "scalar",
+				Series:     []datatemplate.Series{{Value: 42, Timestamp: time.Unix(1, 500000000)}},
+			},
+		},
+		"string": {
+			response: `{"status":"success","data":{"resultType":"string","result":[1,"hello"]}}`,
+			want:     &datatemplate.Data{ResultType: "string", String: "hello"},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var got *datatemplate.Data
+			emit := func(resp *queryResponse, c *chunk) error {
+				var err error
+				got, err = c.templateData(resp)
+				return err
+			}
+			if _, err := streamResult(strings.NewReader(tc.response), &resultLimits{}, 0, nil, emit); err != nil {
+				t.Fatal("streamResult() =", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("unexpected data (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
+
+// largeMatrixResponse generates a range query response of the given number
+// of series, each with the given number of samples.
+func largeMatrixResponse(series, samples int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"status":"success","data":{"resultType":"matrix","result":[`)
+	for i := 0; i < series; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"metric":{"__name__":"http_requests_total","instance":"10.0.%d.%d:8080","job":"api"},"values":[`, i/256, i%256)
+		for j := 0; j < samples; j++ {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `[%d,"%d"]`, 1435781451+15*j, i*j)
+		}
+		b.WriteString(`]}`)
+	}
+	b.WriteString(`]}}`)
+	return []byte(b.String())
+}
+
+// BenchmarkBufferedResult measures reading a whole query_range response into
+// a single event, as the adapter did before results were streamed.
+func BenchmarkBufferedResult(b *testing.B) {
+	response := largeMatrixResponse(2000, 240)
+	b.SetBytes(int64(len(response)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		body, err := ioutil.ReadAll(bytes.NewReader(response))
+		if err != nil {
+			b.Fatal(err)
+		}
+		event := cloudevents.NewEvent()
+		if err := event.SetData(cloudevents.ApplicationJSON, body); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(float64(len(event.Data())), "event-bytes")
+	}
+}
+
+// BenchmarkStreamResult measures streaming the same response in events of
+// bounded size, by default or with the number of series per event.
+func BenchmarkStreamResult(b *testing.B) {
+	response := largeMatrixResponse(2000, 240)
+	for _, seriesPerEvent := range []int64{0, 1, 100} {
+		b.Run(fmt.Sprintf("seriesPerEvent=%d", seriesPerEvent), func(b *testing.B) {
+			b.SetBytes(int64(len(response)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				largest := 0
+				emit := func(resp *queryResponse, c *chunk) error {
+					payload, err := c.payload(resp)
+					if err != nil {
+						return err
+					}
+					event := cloudevents.NewEvent()
+					if err := event.SetData(cloudevents.ApplicationJSON, payload); err != nil {
+						return err
+					}
+					if len(payload) > largest {
+						largest = len(payload)
+					}
+					return nil
+				}
+				if _, err := streamResult(bytes.NewReader(response), &resultLimits{}, seriesPerEvent, nil, emit); err != nil {
+					b.Fatal(err)
+				}
+				b.ReportMetric(float64(largest), "event-bytes")
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron"
+	"go.uber.org/zap"
+)
+
+// catchUpWindow is how long after the source was resumed its adapter catches
+// up from the suspension when it starts, so that an adapter restarted later
+// does not catch up again.
+const catchUpWindow = 10 * time.Minute
+
+// maxTickLookback is how far back the latest tick of the schedule is looked
+// for, which covers yearly schedules.
+const maxTickLookback = 2 * 366 * 24 * time.Hour
+
+// scheduler computes the ticks of the schedule of the source, at which the
+// query is evaluated one at a time.
+type scheduler struct {
+	schedule cron.Schedule
+	// location is the time zone the schedule runs in.
+	location *time.Location
+	// jitter delays the evaluation of every tick.
+	jitter time.Duration
+	// startingDeadline is how late the evaluation of a missed tick may start,
+	// zero if it may start at any time.
+	startingDeadline time.Duration
+}
+
+// newScheduler parses the schedule, in the time zone, UTC if it is empty.
+func newScheduler(schedule, timeZone string, jitter, startingDeadline time.Duration) (*scheduler, error) {
+	sched, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, err
+	}
+	return &scheduler{
+		schedule:         sched,
+		location:         location,
+		jitter:           jitter,
+		startingDeadline: startingDeadline,
+	}, nil
+}
+
+// sourceJitter returns the delay of the evaluations of the source identified
+// by the key, below max. It is derived from the key, so that it is the same at
+// every tick and spreads the sources sharing a schedule.
+func sourceJitter(key string, max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(max))
+}
+
+// next returns the tick to evaluate after the last one, at the time, and the
+// number of ticks missed in between. The ticks whose evaluation should have
+// started by then are missed, except for the latest one if it is within the
+// starting deadline. It returns a zero tick if the schedule has no next tick.
+func (s *scheduler) next(last, now time.Time) (time.Time, int) {
+	tick := s.schedule.Next(last.In(s.location))
+	var late time.Time
+	missed := 0
+	for !tick.IsZero() && !tick.Add(s.jitter).After(now) {
+		if !late.IsZero() {
+			missed++
+		}
+		late = tick
+		tick = s.schedule.Next(tick)
+	}
+	if late.IsZero() {
+		return tick, 0
+	}
+	if s.startingDeadline > 0 && now.Sub(late.Add(s.jitter)) > s.startingDeadline {
+		return tick, missed + 1
+	}
+	return late, missed
+}
+
+// previous returns the latest tick of the schedule at or before the time, or
+// a zero tick if there is none within maxTickLookback.
+func (s *scheduler) previous(t time.Time) time.Time {
+	// The schedule only computes the next ticks, which are looked for after
+	// times further and further back.
+	for d := time.Minute; d <= maxTickLookback; d *= 2 {
+		tick := s.schedule.Next(t.Add(-d).In(s.location))
+		if tick.IsZero() || tick.After(t) {
+			continue
+		}
+		for next := s.schedule.Next(tick); !next.IsZero() && !next.After(t); next = s.schedule.Next(next) {
+			tick = next
+		}
+		return tick
+	}
+	return time.Time{}
+}
+
+// run evaluates the query at the ticks of the schedule until stopCh is
+// closed.
+func (a *prometheusAdapter) run(s *scheduler, stopCh <-chan struct{}) {
+	now := time.Now()
+	tick, missed := s.next(a.catchUpFrom(now), now)
+	if missed > 0 {
+		a.logger.Warnw("Skipped evaluations missed while suspended", zap.Int("missed", missed), zap.Time("next", tick))
+	}
+	a.evaluate(s, tick, stopCh, nil)
+}
+
+// evaluate evaluates the query at the tick, then at the next ticks of the
+// schedule, until stopCh is closed. evaluated, if any, is called with every
+// tick evaluated, once the query response is read, before the next tick.
+func (a *prometheusAdapter) evaluate(s *scheduler, tick time.Time, stopCh <-chan struct{}, evaluated func(time.Time)) {
+	for !tick.IsZero() {
+		timer := time.NewTimer(time.Until(tick.Add(s.jitter)))
+		select {
+		case <-stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		// The events not delivered are not sent again by evaluating the
+		// tick again.
+		if err := a.send(tick); evaluated != nil && (err == nil || errors.Is(err, errNotDelivered)) {
+			evaluated(tick)
+		}
+
+		var missed int
+		if tick, missed = s.next(tick, time.Now()); missed > 0 {
+			a.logger.Warnw("Skipped missed evaluations", zap.Int("missed", missed), zap.Time("next", tick))
+		}
+	}
+	a.logger.Warn("The schedule has no next tick")
+	<-stopCh
+}
+
+// catchUpFrom returns the time after which the ticks of the schedule are
+// evaluated when the adapter starts at the time. The adapter started right
+// after the source was resumed catches up from the suspension, the first
+// range query covering it, otherwise the evaluations start now.
+func (a *prometheusAdapter) catchUpFrom(now time.Time) time.Time {
+	if a.suspendedAt.IsZero() || a.resumedAt.Before(a.suspendedAt) || now.Sub(a.resumedAt) >= catchUpWindow {
+		return now
+	}
+	a.logger.Infow("Catching up from the suspension", zap.Time("suspendedAt", a.suspendedAt))
+	a.lastRun = a.suspendedAt.Add(-a.evaluationDelay)
+	return a.suspendedAt
+}
+
+// evaluateOnce evaluates the query at the tick of the schedule the Job running
+// the adapter was created for by its CronJob, even if it started late, and
+// returns the error of the evaluation so that the Job fails. The range query
+// starts at the tick before it.
+func (a *prometheusAdapter) evaluateOnce(s *scheduler, stopCh <-chan struct{}) error {
+	tick := scheduledTick(a.jobName)
+	if tick.IsZero() {
+		// The Job was not created by the CronJob, but from it, for the
+		// latest tick.
+		tick = s.previous(time.Now())
+	}
+	if tick.IsZero() {
+		return errors.New("the schedule has no tick to evaluate")
+	}
+	if last := s.previous(tick.Add(-time.Nanosecond)); !last.IsZero() {
+		a.lastRun = last.Add(-a.evaluationDelay)
+	}
+	timer := time.NewTimer(time.Until(tick.Add(s.jitter)))
+	defer timer.Stop()
+	select {
+	case <-stopCh:
+		return nil
+	case <-timer.C:
+	}
+	return a.send(tick)
+}
+
+// scheduledTick returns the tick a CronJob created the Job for, which it names
+// after the tick in minutes since the epoch, zero if the name of the Job does
+// not end with it.
+func scheduledTick(jobName string) time.Time {
+	i := strings.LastIndex(jobName, "-")
+	if i < 0 {
+		return time.Time{}
+	}
+	minutes, err := strconv.ParseInt(jobName[i+1:], 10, 64)
+	if err != nil || minutes <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(minutes*60, 0)
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/types"
+	"go.uber.org/zap"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+)
+
+func TestSchedulerNext(t *testing.T) {
+	last := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		schedule         string
+		timeZone         string
+		jitter           time.Duration
+		startingDeadline time.Duration
+		now              time.Time
+		want             time.Time
+		wantMissed       int
+	}{
+		"next tick": {
+			schedule: "* * * * *",
+			now:      last.Add(10 * time.Second),
+			want:     last.Add(time.Minute),
+		},
+		"tick due": {
+			schedule: "* * * * *",
+			now:      last.Add(time.Minute),
+			want:     last.Add(time.Minute),
+		},
+		"tick delayed by the jitter": {
+			schedule: "* * * * *",
+			jitter:   20 * time.Second,
+			now:      last.Add(70 * time.Second),
+			want:     last.Add(time.Minute),
+		},
+		"late tick without a starting deadline": {
+			schedule:   "* * * * *",
+			now:        last.Add(3*time.Minute + 30*time.Second),
+			want:       last.Add(3 * time.Minute),
+			wantMissed: 2,
+		},
+		"late tick within the starting deadline": {
+			schedule:         "* * * * *",
+			startingDeadline: time.Minute,
+			now:              last.Add(3*time.Minute + 30*time.Second),
+			want:             last.Add(3 * time.Minute),
+			wantMissed:       2,
+		},
+		"late tick past the starting deadline": {
+			schedule:         "* * * * *",
+			startingDeadline: 10 * time.Second,
+			now:              last.Add(3*time.Minute + 30*time.Second),
+			want:             last.Add(4 * time.Minute),
+			wantMissed:       3,
+		},
+		"time zone": {
+			schedule: "0 9 * * *",
+			timeZone: "America/New_York",
+			now:      last,
+			// 9am EST.
+			want: time.Date(2022, time.March, 1, 14, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			s, err := newScheduler(tc.schedule, tc.timeZone, tc.jitter, tc.startingDeadline)
+			if err != nil {
+				t.Fatal("newScheduler() =", err)
+			}
+			got, missed := s.next(last, tc.now)
+			if !got.Equal(tc.want) {
+				t.Errorf("next() = %v, want %v", got, tc.want)
+			}
+			if missed != tc.wantMissed {
+				t.Errorf("missed = %d, want %d", missed, tc.wantMissed)
+			}
+		})
+	}
+}
+
+func TestSchedulerPrevious(t *testing.T) {
+	now := time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC)
+
+	testCases := map[string]struct {
+		schedule string
+		t        time.Time
+		want     time.Time
+	}{
+		"every minute": {
+			schedule: "* * * * *",
+			t:        now,
+			want:     time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"at the tick": {
+			schedule: "0 * * * *",
+			t:        time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC),
+			want:     time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC),
+		},
+		"daily": {
+			schedule: "0 13 * * *",
+			t:        now,
+			want:     time.Date(2022, time.February, 28, 13, 0, 0, 0, time.UTC),
+		},
+		"yearly": {
+			schedule: "@yearly",
+			t:        now,
+			want:     time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"no tick": {
+			schedule: "0 0 30 2 *",
+			t:        now,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			s, err := newScheduler(tc.schedule, "", 0, 0)
+			if err != nil {
+				t.Fatal("newScheduler() =", err)
+			}
+			if got := s.previous(tc.t); !got.Equal(tc.want) {
+				t.Errorf("previous() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateOnce(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[]}}`)
+	}))
+	defer ts.Close()
+
+	s, err := newScheduler("* * * * *", "", 0, 0)
+	if err != nil {
+		t.Fatal("newScheduler() =", err)
+	}
+	ce := adaptertest.NewTestClient()
+	a := &prometheusAdapter{
+		ce:        ce,
+		logger:    zap.NewExample().Sugar(),
+		serverURL: ts.URL,
+		promQL:    "up",
+		step:      "15s",
+		eventType: "com.example.up",
+		client:    &http.Client{},
+	}
+	before := s.previous(time.Now())
+	if err := a.evaluateOnce(s, make(chan struct{})); err != nil {
+		t.Fatal("evaluateOnce() =", err)
+	}
+
+	// The range query covers the minute before the latest tick, which may
+	// have changed during the evaluation.
+	wantQuery := func(tick time.Time) string {
+		return fmt.Sprintf("query=up&start=%s&end=%s&step=15s",
+			tick.UTC().Add(-time.Minute).Format(time.RFC3339), tick.UTC().Format(time.RFC3339))
+	}
+	if after := s.previous(time.Now()); query != wantQuery(before) && query != wantQuery(after) {
+		t.Errorf("query = %q, want %q", query, wantQuery(before))
+	}
+	if len(ce.Sent()) != 1 {
+		t.Errorf("sent %d events, want 1", len(ce.Sent()))
+	}
+
+	// The Job fails with the evaluation.
+	ts.Close()
+	if err := a.evaluateOnce(s, make(chan struct{})); err == nil {
+		t.Error("evaluateOnce() = nil, want the error of the evaluation")
+	}
+}
+
+func TestEvaluateOnceScheduledTick(t *testing.T) {
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[]}}`)
+	}))
+	defer ts.Close()
+
+	s, err := newScheduler("*/5 * * * *", "", 0, 0)
+	if err != nil {
+		t.Fatal("newScheduler() =", err)
+	}
+	// The Job started late evaluates the tick it was created for, not the
+	// latest one.
+	tick := s.previous(time.Now()).Add(-time.Hour)
+	a := &prometheusAdapter{
+		ce:        adaptertest.NewTestClient(),
+		logger:    zap.NewExample().Sugar(),
+		serverURL: ts.URL,
+		promQL:    "up",
+		step:      "15s",
+		eventType: "com.example.up",
+		client:    &http.Client{},
+		jobName:   fmt.Sprintf("prometheussource-test-%d", tick.Unix()/60),
+	}
+	if err := a.evaluateOnce(s, make(chan struct{})); err != nil {
+		t.Fatal("evaluateOnce() =", err)
+	}
+
+	want := fmt.Sprintf("query=up&start=%s&end=%s&step=15s",
+		tick.UTC().Add(-5*time.Minute).Format(time.RFC3339), tick.UTC().Format(time.RFC3339))
+	if query != want {
+		t.Errorf("query = %q, want %q", query, want)
+	}
+}
+
+func TestScheduledTick(t *testing.T) {
+	testCases := map[string]struct {
+		jobName string
+		want    time.Time
+	}{
+		"created by the cronjob": {
+			jobName: "prometheussource-test-27435480",
+			want:    time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC),
+		},
+		"created from the cronjob": {
+			jobName: "prometheussource-test-manual",
+		},
+		"no job": {},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := scheduledTick(tc.jobName); !got.Equal(tc.want) {
+				t.Errorf("scheduledTick() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSourceJitter(t *testing.T) {
+	const max = time.Minute
+	a := sourceJitter("3c1c4e2a-5d0f-4b7e-9a53-0c6d2d4f5b1e", max)
+	if a < 0 || a >= max {
+		t.Errorf("sourceJitter() = %v, want in [0, %v)", a, max)
+	}
+	if got := sourceJitter("3c1c4e2a-5d0f-4b7e-9a53-0c6d2d4f5b1e", max); got != a {
+		t.Errorf("sourceJitter() = %v, want the same %v for the same source", got, a)
+	}
+	if got := sourceJitter("8f0b9d31-77a2-4c4e-8d1b-2f3e6a7c9d05", max); got == a {
+		t.Errorf("sourceJitter() = %v for another source, want a different jitter", got)
+	}
+	if got := sourceJitter("3c1c4e2a-5d0f-4b7e-9a53-0c6d2d4f5b1e", 0); got != 0 {
+		t.Errorf("sourceJitter() = %v, want none without a maximum", got)
+	}
+}
+
+func TestSendEvaluatesAtTick(t *testing.T) {
+	tick := time.Date(2022, time.March, 1, 12, 0, 0, 0, time.FixedZone("EST", -5*60*60))
+
+	testCases := map[string]struct {
+		step            string
+		alignToStep     bool
+		evaluationDelay time.Duration
+		lastRun         time.Time
+		wantQuery       string
+		wantTime        time.Time
+		wantExtensions  map[string]time.Time
+	}{
+		"instant query": {
+			wantQuery:      "query=up&time=2022-03-01T17:00:00Z",
+			wantTime:       tick,
+			wantExtensions: map[string]time.Time{evalTimeExtension: tick},
+		},
+		"evaluation delay": {
+			evaluationDelay: 90 * time.Second,
+			wantQuery:       "query=up&time=2022-03-01T16:58:30Z",
+			wantTime:        tick.Add(-90 * time.Second),
+			wantExtensions:  map[string]time.Time{evalTimeExtension: tick.Add(-90 * time.Second)},
+		},
+		"range query": {
+			step:      "15s",
+			lastRun:   tick.Add(-time.Minute + 7*time.Second),
+			wantQuery: "query=up&start=2022-03-01T16:59:07Z&end=2022-03-01T17:00:00Z&step=15s",
+			wantTime:  tick,
+			wantExtensions: map[string]time.Time{
+				evalStartExtension: tick.Add(-time.Minute + 7*time.Second),
+				evalEndExtension:   tick,
+			},
+		},
+		"range query aligned to the step": {
+			step:            "15s",
+			alignToStep:     true,
+			evaluationDelay: 10 * time.Second,
+			lastRun:         tick.Add(-time.Minute - 10*time.Second),
+			// The previous range ended at 16:58:45.
+			wantQuery: "query=up&start=2022-03-01T16:59:00Z&end=2022-03-01T16:59:45Z&step=15s",
+			wantTime:  tick.Add(-10 * time.Second),
+			wantExtensions: map[string]time.Time{
+				evalStartExtension: tick.Add(-time.Minute),
+				evalEndExtension:   tick.Add(-15 * time.Second),
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var query string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.RawQuery
+				fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[]}}`)
+			}))
+			defer ts.Close()
+
+			ce := adaptertest.NewTestClient()
+			a := &prometheusAdapter{
+				ce:              ce,
+				logger:          zap.NewExample().Sugar(),
+				serverURL:       ts.URL,
+				promQL:          "up",
+				step:            tc.step,
+				alignToStep:     tc.alignToStep,
+				stepDuration:    15 * time.Second,
+				evaluationDelay: tc.evaluationDelay,
+				lastRun:         tc.lastRun,
+				eventType:       "com.example.up",
+				client:          &http.Client{},
+			}
+			a.send(tick)
+
+			if query != tc.wantQuery {
+				t.Errorf("query = %q, want %q", query, tc.wantQuery)
+			}
+			event := ce.Sent()[0]
+			if got := event.Time(); !got.Equal(tc.wantTime) {
+				t.Errorf("Time() = %v, want %v", got, tc.wantTime)
+			}
+			for name, want := range tc.wantExtensions {
+				got, err := types.ToTime(event.Extensions()[name])
+				if err != nil || !got.Equal(want) {
+					t.Errorf("extension %s = %v, want %v", name, event.Extensions()[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryRangeWithoutNewStep(t *testing.T) {
+	evalTime := time.Date(2022, time.March, 1, 12, 0, 10, 0, time.UTC)
+	a := &prometheusAdapter{
+		alignToStep:  true,
+		stepDuration: time.Minute,
+		lastRun:      evalTime.Add(-5 * time.Second),
+	}
+	if start, end, ok := a.queryRange(evalTime); ok {
+		t.Errorf("queryRange() = %v, %v, want no new step", start, end)
+	}
+}
+
+func TestAlignToStep(t *testing.T) {
+	testCases := map[string]struct {
+		t    time.Time
+		step time.Duration
+		want time.Time
+	}{
+		"aligned": {
+			t:    time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC),
+			step: 15 * time.Second,
+			want: time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC),
+		},
+		"within a step": {
+			t:    time.Date(2022, time.March, 1, 12, 0, 44, 0, time.UTC),
+			step: 15 * time.Second,
+			want: time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC),
+		},
+		"step from the epoch": {
+			t:    time.Unix(100, 0),
+			step: 7 * time.Second,
+			want: time.Unix(98, 0),
+		},
+		"before the epoch": {
+			t:    time.Unix(-1, 0),
+			step: 7 * time.Second,
+			want: time.Unix(-7, 0),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := alignToStep(tc.t, tc.step); !got.Equal(tc.want) {
+				t.Errorf("alignToStep() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCatchUpFrom(t *testing.T) {
+	now := time.Date(2022, time.March, 1, 12, 0, 30, 0, time.UTC)
+	suspendedAt := now.Add(-time.Hour)
+
+	testCases := map[string]struct {
+		suspendedAt time.Time
+		resumedAt   time.Time
+		want        time.Time
+		wantLastRun time.Time
+	}{
+		"never suspended": {
+			want: now,
+		},
+		"resumed": {
+			suspendedAt: suspendedAt,
+			resumedAt:   now.Add(-time.Minute),
+			want:        suspendedAt,
+			wantLastRun: suspendedAt.Add(-10 * time.Second),
+		},
+		"resumed before the catch up window": {
+			suspendedAt: suspendedAt,
+			resumedAt:   now.Add(-catchUpWindow),
+			want:        now,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			a := &prometheusAdapter{
+				logger:          zap.NewExample().Sugar(),
+				evaluationDelay: 10 * time.Second,
+				suspendedAt:     tc.suspendedAt,
+				resumedAt:       tc.resumedAt,
+			}
+			if got := a.catchUpFrom(now); !got.Equal(tc.want) {
+				t.Errorf("catchUpFrom() = %v, want %v", got, tc.want)
+			}
+			if !a.lastRun.Equal(tc.wantLastRun) {
+				t.Errorf("lastRun = %v, want %v", a.lastRun, tc.wantLastRun)
+			}
+		})
+	}
+}
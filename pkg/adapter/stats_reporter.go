@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adapter
+
+import (
+	"context"
+
+	"go.opencensus.io/stats"
+	"go.opencensus.io/stats/view"
+	"go.opencensus.io/tag"
+	eventingmetrics "knative.dev/eventing/pkg/metrics"
+	"knative.dev/pkg/metrics"
+)
+
+var (
+	// limitExceededCountM is a counter which records the number of query
+	// results exceeding the PrometheusSource limits.
+	limitExceededCountM = stats.Int64(
+		"result_limit_exceeded_count",
+		"Number of query results exceeding the source limits",
+		stats.UnitDimensionless,
+	)
+
+	// outboxDepthM is a gauge which records the number of events in the
+	// outbox.
+	outboxDepthM = stats.Int64(
+		"outbox_depth",
+		"Number of events queued in the outbox",
+		stats.UnitDimensionless,
+	)
+
+	// outboxDroppedCountM is a counter which records the number of events
+	// dropped from the outbox.
+	outboxDroppedCountM = stats.Int64(
+		"outbox_dropped_count",
+		"Number of events dropped from the outbox",
+		stats.UnitDimensionless,
+	)
+
+	// Create the tag keys that will be used to add tags to our measurements.
+	// Tag keys must conform to the restrictions described in
+	// go.opencensus.io/tag/validate.go. Currently those restrictions are:
+	// - length between 1 and 255 inclusive
+	// - characters are printable US-ASCII
+	namespaceKey      = tag.MustNewKey(eventingmetrics.LabelNamespaceName)
+	eventSourceKey    = tag.MustNewKey(eventingmetrics.LabelEventSource)
+	sourceNameKey     = tag.MustNewKey(eventingmetrics.LabelName)
+	limitKey          = tag.MustNewKey("limit")
+	overflowPolicyKey = tag.MustNewKey("overflow_policy")
+	dropReasonKey     = tag.MustNewKey("drop_reason")
+)
+
+// reportArgs defines the arguments for reporting metrics.
+type reportArgs struct {
+	namespace      string
+	eventSource    string
+	name           string
+	limit          string
+	overflowPolicy string
+	dropReason     string
+}
+
+func init() {
+	register()
+}
+
+// statsReporter defines the interface for sending PrometheusSource adapter metrics.
+type statsReporter interface {
+	// reportLimitExceeded captures a query result exceeding the limits.
+	reportLimitExceeded(args *reportArgs) error
+
+	// reportOutboxDepth captures the number of events in the outbox.
+	reportOutboxDepth(args *reportArgs, depth int) error
+
+	// reportOutboxDropped captures an event dropped from the outbox.
+	reportOutboxDropped(args *reportArgs) error
+}
+
+var _ statsReporter = (*reporter)(nil)
+
+// reporter holds cached metric objects to report PrometheusSource adapter metrics.
+type reporter struct {
+	ctx context.Context
+}
+
+// newStatsReporter creates a reporter that collects and reports PrometheusSource
+// adapter metrics.
+func newStatsReporter() (statsReporter, error) {
+	ctx, err := tag.New(
+		context.Background(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &reporter{ctx: ctx}, nil
+}
+
+func (r *reporter) reportLimitExceeded(args *reportArgs) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(namespaceKey, args.namespace),
+		tag.Insert(eventSourceKey, args.eventSource),
+		tag.Insert(sourceNameKey, args.name),
+		tag.Insert(limitKey, args.limit),
+		tag.Insert(overflowPolicyKey, args.overflowPolicy))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, limitExceededCountM.M(1))
+	return nil
+}
+
+func (r *reporter) reportOutboxDepth(args *reportArgs, depth int) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(namespaceKey, args.namespace),
+		tag.Insert(eventSourceKey, args.eventSource),
+		tag.Insert(sourceNameKey, args.name))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, outboxDepthM.M(int64(depth)))
+	return nil
+}
+
+func (r *reporter) reportOutboxDropped(args *reportArgs) error {
+	ctx, err := tag.New(
+		r.ctx,
+		tag.Insert(namespaceKey, args.namespace),
+		tag.Insert(eventSourceKey, args.eventSource),
+		tag.Insert(sourceNameKey, args.name),
+		tag.Insert(dropReasonKey, args.dropReason))
+	if err != nil {
+		return err
+	}
+	metrics.Record(ctx, outboxDroppedCountM.M(1))
+	return nil
+}
+
+func register() {
+	// Create view to see our measurements.
+	if err := view.Register(
+		&view.View{
+			Description: limitExceededCountM.Description(),
+			Measure:     limitExceededCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, eventSourceKey, sourceNameKey, limitKey, overflowPolicyKey},
+		},
+		&view.View{
+			Description: outboxDepthM.Description(),
+			Measure:     outboxDepthM,
+			Aggregation: view.LastValue(),
+			TagKeys:     []tag.Key{namespaceKey, eventSourceKey, sourceNameKey},
+		},
+		&view.View{
+			Description: outboxDroppedCountM.Description(),
+			Measure:     outboxDroppedCountM,
+			Aggregation: view.Count(),
+			TagKeys:     []tag.Key{namespaceKey, eventSourceKey, sourceNameKey, dropReasonKey},
+		},
+	); err != nil {
+		panic(err)
+	}
+}
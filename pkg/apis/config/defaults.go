@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	cm "knative.dev/pkg/configmap"
+)
+
+const (
+	// DefaultsConfigName is the name of the config map holding the defaults
+	// applied to PrometheusSources at admission time.
+	DefaultsConfigName = "config-prometheus-source"
+
+	serverURLKey            = "server-url"
+	scheduleKey             = "schedule"
+	serviceAccountNameKey   = "service-account-name"
+	caCertConfigMapKey      = "ca-cert-config-map"
+	queryTimeoutKey         = "query-timeout"
+	adapterCPURequestKey    = "adapter-cpu-request"
+	adapterMemoryRequestKey = "adapter-memory-request"
+	adapterCPULimitKey      = "adapter-cpu-limit"
+	adapterMemoryLimitKey   = "adapter-memory-limit"
+)
+
+// Defaults holds the values given to the PrometheusSource fields left empty.
+// Empty defaults are not applied.
+type Defaults struct {
+	// ServerURL is the URL of the Prometheus server.
+	ServerURL string
+
+	// Schedule is the crontab-formatted schedule of the queries.
+	Schedule string
+
+	// ServiceAccountName is the service account the receive adapter runs as.
+	ServiceAccountName string
+
+	// CACertConfigMap is the name of the config map holding the CA
+	// certificate of the Prometheus server's signer.
+	CACertConfigMap string
+
+	// QueryTimeout is the evaluation timeout of the queries.
+	QueryTimeout time.Duration
+
+	// AdapterResources are the compute resources of the receive adapter.
+	AdapterResources corev1.ResourceRequirements
+}
+
+// NewDefaultsFromMap creates a Defaults from the supplied map.
+func NewDefaultsFromMap(data map[string]string) (*Defaults, error) {
+	d := &Defaults{}
+
+	var cpuRequest, memoryRequest, cpuLimit, memoryLimit *resource.Quantity
+	if err := cm.Parse(data,
+		cm.AsString(serverURLKey, &d.ServerURL),
+		cm.AsString(scheduleKey, &d.Schedule),
+		cm.AsString(serviceAccountNameKey, &d.ServiceAccountName),
+		cm.AsString(caCertConfigMapKey, &d.CACertConfigMap),
+		cm.AsDuration(queryTimeoutKey, &d.QueryTimeout),
+		cm.AsQuantity(adapterCPURequestKey, &cpuRequest),
+		cm.AsQuantity(adapterMemoryRequestKey, &memoryRequest),
+		cm.AsQuantity(adapterCPULimitKey, &cpuLimit),
+		cm.AsQuantity(adapterMemoryLimitKey, &memoryLimit),
+	); err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	if d.QueryTimeout < 0 {
+		return nil, fmt.Errorf("%s must not be negative, was: %v", queryTimeoutKey, d.QueryTimeout)
+	}
+	d.AdapterResources.Requests = resourceList(cpuRequest, memoryRequest)
+	d.AdapterResources.Limits = resourceList(cpuLimit, memoryLimit)
+	return d, nil
+}
+
+// NewDefaultsFromConfigMap creates a Defaults from the supplied ConfigMap.
+func NewDefaultsFromConfigMap(config *corev1.ConfigMap) (*Defaults, error) {
+	return NewDefaultsFromMap(config.Data)
+}
+
+func resourceList(cpu, memory *resource.Quantity) corev1.ResourceList {
+	if cpu == nil && memory == nil {
+		return nil
+	}
+	rl := corev1.ResourceList{}
+	if cpu != nil {
+		rl[corev1.ResourceCPU] = *cpu
+	}
+	if memory != nil {
+		rl[corev1.ResourceMemory] = *memory
+	}
+	return rl
+}
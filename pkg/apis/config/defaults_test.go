@@ -0,0 +1,107 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewDefaultsFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data    map[string]string
+		want    *Defaults
+		wantErr bool
+	}{
+		"empty": {
+			data: map[string]string{},
+			want: &Defaults{},
+		},
+		"all keys": {
+			data: map[string]string{
+				"server-url":             "http://prometheus.monitoring.svc:9090",
+				"schedule":               "*/5 * * * *",
+				"service-account-name":   "prometheus-source",
+				"ca-cert-config-map":     "prometheus-ca",
+				"query-timeout":          "30s",
+				"adapter-cpu-request":    "25m",
+				"adapter-memory-request": "64Mi",
+				"adapter-cpu-limit":      "500m",
+				"adapter-memory-limit":   "256Mi",
+			},
+			want: &Defaults{
+				ServerURL:          "http://prometheus.monitoring.svc:9090",
+				Schedule:           "*/5 * * * *",
+				ServiceAccountName: "prometheus-source",
+				CACertConfigMap:    "prometheus-ca",
+				QueryTimeout:       30 * time.Second,
+				AdapterResources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("25m"),
+						corev1.ResourceMemory: resource.MustParse("64Mi"),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("500m"),
+						corev1.ResourceMemory: resource.MustParse("256Mi"),
+					},
+				},
+			},
+		},
+		"memory limit only": {
+			data: map[string]string{"adapter-memory-limit": "256Mi"},
+			want: &Defaults{
+				AdapterResources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{
+						corev1.ResourceMemory: resource.MustParse("256Mi"),
+					},
+				},
+			},
+		},
+		"invalid quantity": {
+			data:    map[string]string{"adapter-cpu-request": "a lot"},
+			wantErr: true,
+		},
+		"invalid query timeout": {
+			data:    map[string]string{"query-timeout": "soon"},
+			wantErr: true,
+		},
+		"negative query timeout": {
+			data:    map[string]string{"query-timeout": "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewDefaultsFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: DefaultsConfigName},
+				Data:       tc.data,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewDefaultsFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected defaults (-want, +got): %s", diff)
+			}
+		})
+	}
+}
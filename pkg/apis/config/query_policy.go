@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	cm "knative.dev/pkg/configmap"
+)
+
+const (
+	// QueryPolicyConfigName is the name of the config map holding the
+	// cluster-wide PromQL cost guardrails enforced at admission time.
+	QueryPolicyConfigName = "config-query-policy"
+
+	unscopedSelectorKey             = "unscoped-selector"
+	nameRegexSelectorKey            = "name-regex-selector"
+	maxRangeDurationKey             = "max-range-duration"
+	maxRangeDurationActionKey       = "max-range-duration-action"
+	maxPointsPerEvaluationKey       = "max-points-per-evaluation"
+	maxPointsPerEvaluationActionKey = "max-points-per-evaluation-action"
+	minScheduleIntervalKey          = "min-schedule-interval"
+	minScheduleIntervalActionKey    = "min-schedule-interval-action"
+	namespaceScopingKey             = "namespace-scoping"
+	namespaceLabelKey               = "namespace-label"
+)
+
+// PolicyAction is what the admission webhook does when a query violates a
+// guardrail.
+type PolicyAction string
+
+const (
+	// PolicyActionAllow lets the query through silently.
+	PolicyActionAllow PolicyAction = "allow"
+
+	// PolicyActionWarn lets the query through with an admission warning.
+	PolicyActionWarn PolicyAction = "warn"
+
+	// PolicyActionReject rejects the query.
+	PolicyActionReject PolicyAction = "reject"
+)
+
+// QueryPolicy holds the thresholds used to analyze the cost of PrometheusSource
+// queries at admission time, and what to do when they are exceeded.
+type QueryPolicy struct {
+	// UnscopedSelector is applied to selectors without any label matcher
+	// besides the metric name.
+	UnscopedSelector PolicyAction
+
+	// NameRegexSelector is applied to selectors matching the metric name
+	// with a regular expression.
+	NameRegexSelector PolicyAction
+
+	// MaxRangeDuration is the longest range vector or subquery range allowed.
+	// Zero disables the check.
+	MaxRangeDuration       time.Duration
+	MaxRangeDurationAction PolicyAction
+
+	// MaxPointsPerEvaluation is the largest number of points per series a
+	// range query may return for one schedule interval, that is the schedule
+	// interval divided by the step. Zero disables the check.
+	MaxPointsPerEvaluation       int64
+	MaxPointsPerEvaluationAction PolicyAction
+
+	// MinScheduleInterval is the shortest interval allowed between two runs
+	// of the schedule. Zero disables the check.
+	MinScheduleInterval       time.Duration
+	MinScheduleIntervalAction PolicyAction
+
+	// NamespaceScoping restricts the queries to the series of the namespace
+	// of their PrometheusSource, by setting a NamespaceLabel matcher on every
+	// selector. Queries that cannot be scoped are rejected.
+	NamespaceScoping bool
+
+	// NamespaceLabel is the label holding the namespace of the series.
+	NamespaceLabel string
+}
+
+// NewQueryPolicyFromMap creates a QueryPolicy from the supplied map.
+func NewQueryPolicyFromMap(data map[string]string) (*QueryPolicy, error) {
+	qp := &QueryPolicy{
+		UnscopedSelector:             PolicyActionWarn,
+		NameRegexSelector:            PolicyActionWarn,
+		MaxRangeDurationAction:       PolicyActionWarn,
+		MaxPointsPerEvaluation:       11000,
+		MaxPointsPerEvaluationAction: PolicyActionReject,
+		MinScheduleIntervalAction:    PolicyActionWarn,
+		NamespaceLabel:               "namespace",
+	}
+
+	if err := cm.Parse(data,
+		asPolicyAction(unscopedSelectorKey, &qp.UnscopedSelector),
+		asPolicyAction(nameRegexSelectorKey, &qp.NameRegexSelector),
+		cm.AsDuration(maxRangeDurationKey, &qp.MaxRangeDuration),
+		asPolicyAction(maxRangeDurationActionKey, &qp.MaxRangeDurationAction),
+		cm.AsInt64(maxPointsPerEvaluationKey, &qp.MaxPointsPerEvaluation),
+		asPolicyAction(maxPointsPerEvaluationActionKey, &qp.MaxPointsPerEvaluationAction),
+		cm.AsDuration(minScheduleIntervalKey, &qp.MinScheduleInterval),
+		asPolicyAction(minScheduleIntervalActionKey, &qp.MinScheduleIntervalAction),
+		cm.AsBool(namespaceScopingKey, &qp.NamespaceScoping),
+		cm.AsString(namespaceLabelKey, &qp.NamespaceLabel),
+	); err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	if qp.MaxRangeDuration < 0 {
+		return nil, fmt.Errorf("%s must not be negative, was: %v", maxRangeDurationKey, qp.MaxRangeDuration)
+	}
+	if qp.MaxPointsPerEvaluation < 0 {
+		return nil, fmt.Errorf("%s must not be negative, was: %d", maxPointsPerEvaluationKey, qp.MaxPointsPerEvaluation)
+	}
+	if qp.MinScheduleInterval < 0 {
+		return nil, fmt.Errorf("%s must not be negative, was: %v", minScheduleIntervalKey, qp.MinScheduleInterval)
+	}
+	if !model.LabelName(qp.NamespaceLabel).IsValid() {
+		return nil, fmt.Errorf("%s must be a valid label name, was: %q", namespaceLabelKey, qp.NamespaceLabel)
+	}
+	return qp, nil
+}
+
+// NewQueryPolicyFromConfigMap creates a QueryPolicy from the supplied ConfigMap.
+func NewQueryPolicyFromConfigMap(config *corev1.ConfigMap) (*QueryPolicy, error) {
+	return NewQueryPolicyFromMap(config.Data)
+}
+
+func asPolicyAction(key string, target *PolicyAction) cm.ParseFunc {
+	return func(data map[string]string) error {
+		if raw, ok := data[key]; ok {
+			switch action := PolicyAction(raw); action {
+			case PolicyActionAllow, PolicyActionWarn, PolicyActionReject:
+				*target = action
+			default:
+				return fmt.Errorf("%s must be one of %q, %q or %q, was: %q", key,
+					PolicyActionAllow, PolicyActionWarn, PolicyActionReject, raw)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewQueryPolicyFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data    map[string]string
+		want    *QueryPolicy
+		wantErr bool
+	}{
+		"defaults": {
+			data: map[string]string{},
+			want: &QueryPolicy{
+				UnscopedSelector:             PolicyActionWarn,
+				NameRegexSelector:            PolicyActionWarn,
+				MaxRangeDurationAction:       PolicyActionWarn,
+				MaxPointsPerEvaluation:       11000,
+				MaxPointsPerEvaluationAction: PolicyActionReject,
+				MinScheduleIntervalAction:    PolicyActionWarn,
+				NamespaceLabel:               "namespace",
+			},
+		},
+		"all keys": {
+			data: map[string]string{
+				"unscoped-selector":                "allow",
+				"name-regex-selector":              "reject",
+				"max-range-duration":               "24h",
+				"max-range-duration-action":        "reject",
+				"max-points-per-evaluation":        "500",
+				"max-points-per-evaluation-action": "warn",
+				"min-schedule-interval":            "5m",
+				"min-schedule-interval-action":     "reject",
+				"namespace-scoping":                "true",
+				"namespace-label":                  "kubernetes_namespace",
+			},
+			want: &QueryPolicy{
+				UnscopedSelector:             PolicyActionAllow,
+				NameRegexSelector:            PolicyActionReject,
+				MaxRangeDuration:             24 * time.Hour,
+				MaxRangeDurationAction:       PolicyActionReject,
+				MaxPointsPerEvaluation:       500,
+				MaxPointsPerEvaluationAction: PolicyActionWarn,
+				MinScheduleInterval:          5 * time.Minute,
+				MinScheduleIntervalAction:    PolicyActionReject,
+				NamespaceScoping:             true,
+				NamespaceLabel:               "kubernetes_namespace",
+			},
+		},
+		"unknown action": {
+			data:    map[string]string{"unscoped-selector": "deny"},
+			wantErr: true,
+		},
+		"invalid duration": {
+			data:    map[string]string{"max-range-duration": "a day"},
+			wantErr: true,
+		},
+		"invalid namespace label": {
+			data:    map[string]string{"namespace-label": "kubernetes-namespace"},
+			wantErr: true,
+		},
+		"negative points": {
+			data:    map[string]string{"max-points-per-evaluation": "-1"},
+			wantErr: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewQueryPolicyFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: QueryPolicyConfigName},
+				Data:       tc.data,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewQueryPolicyFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected query policy (-want, +got): %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	cm "knative.dev/pkg/configmap"
+)
+
+const (
+	// ServerPolicyConfigName is the name of the config map holding the
+	// Prometheus servers and credentials PrometheusSources may use.
+	ServerPolicyConfigName = "config-server-policy"
+
+	allowedServersKey  = "allowed-servers"
+	allowPlainHTTPKey  = "allow-plain-http"
+	authTokenDirsKey   = "auth-token-dirs"
+	namespaceKeyPrefix = "."
+
+	// DefaultAuthTokenDir is the directory the service account token of the
+	// receive adapter is mounted in.
+	DefaultAuthTokenDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+// ServerPolicy restricts the Prometheus servers PrometheusSources may query
+// and the files the receive adapter may read a bearer token from. It is
+// enforced at admission time and again by the reconciler.
+type ServerPolicy struct {
+	// AllowedServers are the server URL patterns and CIDRs every namespace
+	// may query. Empty allows any server.
+	AllowedServers []string
+
+	// NamespaceAllowedServers are the server URL patterns and CIDRs the
+	// namespaces may query in addition to AllowedServers.
+	NamespaceAllowedServers map[string][]string
+
+	// AllowPlainHTTP allows every namespace to query servers over plain HTTP.
+	AllowPlainHTTP bool
+
+	// NamespaceAllowPlainHTTP overrides AllowPlainHTTP for the namespaces.
+	NamespaceAllowPlainHTTP map[string]bool
+
+	// AuthTokenDirs are the directories the auth token file must be in.
+	AuthTokenDirs []string
+}
+
+// NewServerPolicyFromMap creates a ServerPolicy from the supplied map. The
+// allowed-servers and allow-plain-http keys may be suffixed with
+// .<namespace> to apply to a single namespace.
+func NewServerPolicyFromMap(data map[string]string) (*ServerPolicy, error) {
+	sp := &ServerPolicy{
+		AuthTokenDirs: []string{DefaultAuthTokenDir},
+	}
+
+	if err := cm.Parse(data,
+		cm.AsBool(allowPlainHTTPKey, &sp.AllowPlainHTTP),
+	); err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+
+	for key, value := range data {
+		switch {
+		case key == allowedServersKey:
+			servers, err := parseServerPatterns(key, value)
+			if err != nil {
+				return nil, err
+			}
+			sp.AllowedServers = servers
+		case strings.HasPrefix(key, allowedServersKey+namespaceKeyPrefix):
+			servers, err := parseServerPatterns(key, value)
+			if err != nil {
+				return nil, err
+			}
+			if sp.NamespaceAllowedServers == nil {
+				sp.NamespaceAllowedServers = map[string][]string{}
+			}
+			sp.NamespaceAllowedServers[strings.TrimPrefix(key, allowedServersKey+namespaceKeyPrefix)] = servers
+		case strings.HasPrefix(key, allowPlainHTTPKey+namespaceKeyPrefix):
+			allow, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %q: %w", key, err)
+			}
+			if sp.NamespaceAllowPlainHTTP == nil {
+				sp.NamespaceAllowPlainHTTP = map[string]bool{}
+			}
+			sp.NamespaceAllowPlainHTTP[strings.TrimPrefix(key, allowPlainHTTPKey+namespaceKeyPrefix)] = allow
+		case key == authTokenDirsKey:
+			dirs := splitList(value)
+			for _, dir := range dirs {
+				if !filepath.IsAbs(dir) {
+					return nil, fmt.Errorf("%s must only hold absolute paths, was: %q", key, dir)
+				}
+			}
+			sp.AuthTokenDirs = dirs
+		}
+	}
+	return sp, nil
+}
+
+// NewServerPolicyFromConfigMap creates a ServerPolicy from the supplied ConfigMap.
+func NewServerPolicyFromConfigMap(config *corev1.ConfigMap) (*ServerPolicy, error) {
+	return NewServerPolicyFromMap(config.Data)
+}
+
+// AllowsServer returns true if the namespace may query the server URL.
+func (sp *ServerPolicy) AllowsServer(namespace string, u *url.URL) bool {
+	if len(sp.AllowedServers) == 0 {
+		return true
+	}
+	for _, patterns := range [][]string{sp.AllowedServers, sp.NamespaceAllowedServers[namespace]} {
+		for _, pattern := range patterns {
+			if matchServer(pattern, u) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowsPlainHTTP returns true if the namespace may query servers over plain HTTP.
+func (sp *ServerPolicy) AllowsPlainHTTP(namespace string) bool {
+	if allow, ok := sp.NamespaceAllowPlainHTTP[namespace]; ok {
+		return allow
+	}
+	return sp.AllowPlainHTTP
+}
+
+// AllowsAuthTokenFile returns true if the receive adapter may read a bearer
+// token from the file.
+func (sp *ServerPolicy) AllowsAuthTokenFile(file string) bool {
+	if !filepath.IsAbs(file) || filepath.Clean(file) != file {
+		return false
+	}
+	for _, dir := range sp.AuthTokenDirs {
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") && rel != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// parseServerPatterns parses a list of server URL patterns and CIDRs.
+func parseServerPatterns(key, value string) ([]string, error) {
+	patterns := splitList(value)
+	for _, pattern := range patterns {
+		if _, _, err := net.ParseCIDR(pattern); err == nil {
+			continue
+		}
+		u, err := url.Parse(pattern)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("%s must hold URL patterns or CIDRs, was: %q", key, pattern)
+		}
+		if _, err := path.Match(u.Hostname(), ""); err != nil {
+			return nil, fmt.Errorf("%s holds an invalid host pattern: %q", key, pattern)
+		}
+	}
+	return patterns, nil
+}
+
+// matchServer returns true if the server URL matches the pattern. A CIDR
+// pattern matches servers given by an IP address within its range, host
+// names are not resolved. A URL pattern matches servers with the same scheme,
+// a host matching its host pattern, the same port if it has one, and a path
+// starting with its path.
+func matchServer(pattern string, u *url.URL) bool {
+	if _, ipNet, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(u.Hostname())
+		return ip != nil && ipNet.Contains(ip)
+	}
+	p, err := url.Parse(pattern)
+	if err != nil {
+		return false
+	}
+	if p.Scheme != "" && p.Scheme != u.Scheme {
+		return false
+	}
+	if ok, _ := path.Match(p.Hostname(), u.Hostname()); !ok {
+		return false
+	}
+	if p.Port() != "" && p.Port() != u.Port() {
+		return false
+	}
+	prefix := strings.TrimSuffix(p.Path, "/")
+	return u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
+
+// splitList splits a comma or newline separated list, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
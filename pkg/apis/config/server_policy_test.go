@@ -0,0 +1,177 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewServerPolicyFromConfigMap(t *testing.T) {
+	testCases := map[string]struct {
+		data    map[string]string
+		want    *ServerPolicy
+		wantErr bool
+	}{
+		"defaults": {
+			data: map[string]string{},
+			want: &ServerPolicy{
+				AuthTokenDirs: []string{DefaultAuthTokenDir},
+			},
+		},
+		"all keys": {
+			data: map[string]string{
+				"allowed-servers":        "https://*.monitoring.svc:9091,\n10.0.0.0/8",
+				"allowed-servers.team-a": "https://prometheus.team-a.svc",
+				"allow-plain-http":       "true",
+				"allow-plain-http.prod":  "false",
+				"auth-token-dirs":        "/var/run/secrets/tokens\n/etc/prometheus-token",
+			},
+			want: &ServerPolicy{
+				AllowedServers:          []string{"https://*.monitoring.svc:9091", "10.0.0.0/8"},
+				NamespaceAllowedServers: map[string][]string{"team-a": {"https://prometheus.team-a.svc"}},
+				AllowPlainHTTP:          true,
+				NamespaceAllowPlainHTTP: map[string]bool{"prod": false},
+				AuthTokenDirs:           []string{"/var/run/secrets/tokens", "/etc/prometheus-token"},
+			},
+		},
+		"invalid server pattern": {
+			data:    map[string]string{"allowed-servers": "prometheus"},
+			wantErr: true,
+		},
+		"invalid host pattern": {
+			data:    map[string]string{"allowed-servers": "https://[a-.svc"},
+			wantErr: true,
+		},
+		"invalid namespace plain HTTP": {
+			data:    map[string]string{"allow-plain-http.team-a": "sometimes"},
+			wantErr: true,
+		},
+		"relative auth token dir": {
+			data:    map[string]string{"auth-token-dirs": "tokens"},
+			wantErr: true,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := NewServerPolicyFromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: ServerPolicyConfigName},
+				Data:       tc.data,
+			})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("NewServerPolicyFromConfigMap() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected server policy (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestServerPolicyAllowsServer(t *testing.T) {
+	sp, err := NewServerPolicyFromMap(map[string]string{
+		"allowed-servers":        "https://*.monitoring.svc:9091, http://thanos.example.com/api/prom, 10.0.0.0/8",
+		"allowed-servers.team-a": "https://prometheus.team-a.svc",
+	})
+	if err != nil {
+		t.Fatal("NewServerPolicyFromMap() =", err)
+	}
+
+	testCases := map[string]struct {
+		namespace string
+		server    string
+		want      bool
+	}{
+		"host pattern":                  {server: "https://prometheus-k8s.monitoring.svc:9091", want: true},
+		"host pattern other port":       {server: "https://prometheus-k8s.monitoring.svc:9090"},
+		"host pattern other scheme":     {server: "http://prometheus-k8s.monitoring.svc:9091"},
+		"host pattern deeper":           {server: "https://a.b.monitoring.svc:9091", want: true},
+		"path prefix":                   {server: "http://thanos.example.com/api/prom/tenant", want: true},
+		"path prefix of another path":   {server: "http://thanos.example.com/api/prometheus"},
+		"no path":                       {server: "http://thanos.example.com"},
+		"CIDR":                          {server: "http://10.1.2.3:9090", want: true},
+		"outside CIDR":                  {server: "http://192.168.1.1:9090"},
+		"CIDR does not resolve names":   {server: "http://localhost:9090"},
+		"namespace server":              {namespace: "team-a", server: "https://prometheus.team-a.svc", want: true},
+		"other namespace server":        {namespace: "team-b", server: "https://prometheus.team-a.svc"},
+		"cluster server in namespace":   {namespace: "team-a", server: "http://10.0.0.1", want: true},
+		"internal endpoint":             {server: "http://kubernetes.default.svc"},
+		"cloud metadata endpoint":       {server: "http://169.254.169.254/latest/meta-data"},
+		"host pattern with user info":   {server: "https://evil.com@prometheus-k8s.monitoring.svc:9091", want: true},
+		"host pattern suffix lookalike": {server: "https://prometheus.monitoring.svc.evil.com:9091"},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			u, err := url.Parse(tc.server)
+			if err != nil {
+				t.Fatal("url.Parse() =", err)
+			}
+			if got := sp.AllowsServer(tc.namespace, u); got != tc.want {
+				t.Errorf("AllowsServer(%q, %q) = %v, want %v", tc.namespace, tc.server, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerPolicyAllowsAuthTokenFile(t *testing.T) {
+	sp, err := NewServerPolicyFromMap(map[string]string{})
+	if err != nil {
+		t.Fatal("NewServerPolicyFromMap() =", err)
+	}
+
+	testCases := map[string]struct {
+		file string
+		want bool
+	}{
+		"service account token": {file: "/var/run/secrets/kubernetes.io/serviceaccount/token", want: true},
+		"relative path":         {file: "token"},
+		"outside approved dirs": {file: "/etc/passwd"},
+		"approved dir itself":   {file: "/var/run/secrets/kubernetes.io/serviceaccount"},
+		"path traversal":        {file: "/var/run/secrets/kubernetes.io/serviceaccount/../../../../../etc/shadow"},
+		"sibling dir prefix":    {file: "/var/run/secrets/kubernetes.io/serviceaccount2/token"},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := sp.AllowsAuthTokenFile(tc.file); got != tc.want {
+				t.Errorf("AllowsAuthTokenFile(%q) = %v, want %v", tc.file, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerPolicyAllowsPlainHTTP(t *testing.T) {
+	sp, err := NewServerPolicyFromMap(map[string]string{
+		"allow-plain-http":        "true",
+		"allow-plain-http.secure": "false",
+	})
+	if err != nil {
+		t.Fatal("NewServerPolicyFromMap() =", err)
+	}
+	if !sp.AllowsPlainHTTP("default") {
+		t.Error(`AllowsPlainHTTP("default") = false, want true`)
+	}
+	if sp.AllowsPlainHTTP("secure") {
+		t.Error(`AllowsPlainHTTP("secure") = true, want false`)
+	}
+}
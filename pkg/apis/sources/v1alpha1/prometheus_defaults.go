@@ -18,6 +18,11 @@ package v1alpha1
 
 import (
 	"context"
+
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
 )
 
 func (s *PrometheusSource) SetDefaults(ctx context.Context) {
@@ -25,5 +30,52 @@ func (s *PrometheusSource) SetDefaults(ctx context.Context) {
 }
 
 func (s *PrometheusSourceSpec) SetDefaults(ctx context.Context) {
-	// Nothing yet.
+	defaults := config.FromContextOrDefaults(ctx).Defaults
+	if s.ServerURL == "" && s.ServerRef == nil {
+		s.ServerURL = defaults.ServerURL
+	}
+	if s.Schedule == "" {
+		s.Schedule = defaults.Schedule
+	}
+	if s.ServiceAccountName == "" {
+		s.ServiceAccountName = defaults.ServiceAccountName
+	}
+	if s.CACertConfigMap == "" {
+		s.CACertConfigMap = defaults.CACertConfigMap
+	}
+	if s.QueryTimeout == "" && defaults.QueryTimeout > 0 {
+		s.QueryTimeout = model.Duration(defaults.QueryTimeout).String()
+	}
+	s.Resources = DefaultResources(s.Resources, &defaults.AdapterResources)
+
+	if s.Limits != nil && s.Limits.OverflowPolicy == "" {
+		s.Limits.OverflowPolicy = OverflowPolicyTruncate
+	}
+}
+
+// DefaultResources adds the default requests and limits of the resources
+// missing from rr.
+func DefaultResources(rr, defaults *corev1.ResourceRequirements) *corev1.ResourceRequirements {
+	if len(defaults.Requests) == 0 && len(defaults.Limits) == 0 {
+		return rr
+	}
+	if rr == nil {
+		rr = &corev1.ResourceRequirements{}
+	}
+	rr.Requests = defaultResourceList(rr.Requests, defaults.Requests)
+	rr.Limits = defaultResourceList(rr.Limits, defaults.Limits)
+	return rr
+}
+
+func defaultResourceList(rl, defaults corev1.ResourceList) corev1.ResourceList {
+	for name, quantity := range defaults {
+		if _, ok := rl[name]; ok {
+			continue
+		}
+		if rl == nil {
+			rl = corev1.ResourceList{}
+		}
+		rl[name] = quantity.DeepCopy()
+	}
+	return rl
 }
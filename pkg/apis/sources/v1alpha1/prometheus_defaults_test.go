@@ -21,8 +21,126 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
 )
 
+func TestPrometheusSourceDefaultsFromConfig(t *testing.T) {
+	defaults, err := config.NewDefaultsFromMap(map[string]string{
+		"server-url":             "http://prometheus.monitoring.svc:9090",
+		"schedule":               "*/5 * * * *",
+		"service-account-name":   "prometheus-source",
+		"ca-cert-config-map":     "prometheus-ca",
+		"query-timeout":          "30s",
+		"adapter-cpu-request":    "25m",
+		"adapter-memory-request": "64Mi",
+		"adapter-memory-limit":   "256Mi",
+	})
+	if err != nil {
+		t.Fatal("NewDefaultsFromMap() =", err)
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{Defaults: defaults})
+
+	testCases := map[string]struct {
+		initial  PrometheusSource
+		expected PrometheusSource
+	}{
+		"empty spec": {
+			initial: PrometheusSource{},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:          "http://prometheus.monitoring.svc:9090",
+					Schedule:           "*/5 * * * *",
+					ServiceAccountName: "prometheus-source",
+					CACertConfigMap:    "prometheus-ca",
+					QueryTimeout:       "30s",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("25m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+		"server reference": {
+			initial: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerRef: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+					Schedule:  "@hourly",
+					Resources: &corev1.ResourceRequirements{},
+				},
+			},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerRef:          &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+					Schedule:           "@hourly",
+					ServiceAccountName: "prometheus-source",
+					CACertConfigMap:    "prometheus-ca",
+					QueryTimeout:       "30s",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("25m"),
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+		"explicit values win": {
+			initial: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:          "https://prometheus.example.com",
+					Schedule:           "@hourly",
+					ServiceAccountName: "custom",
+					CACertConfigMap:    "custom-ca",
+					QueryTimeout:       "2m",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						},
+					},
+				},
+			},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:          "https://prometheus.example.com",
+					Schedule:           "@hourly",
+					ServiceAccountName: "custom",
+					CACertConfigMap:    "custom-ca",
+					QueryTimeout:       "2m",
+					Resources: &corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("25m"),
+							corev1.ResourceMemory: resource.MustParse("128Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tc.initial.SetDefaults(ctx)
+			if diff := cmp.Diff(tc.expected, tc.initial); diff != "" {
+				t.Fatalf("Unexpected defaults (-want, +got): %s", diff)
+			}
+		})
+	}
+}
+
 func TestPrometheusSourceDefaults(t *testing.T) {
 	testCases := map[string]struct {
 		initial  PrometheusSource
@@ -34,6 +152,33 @@ func TestPrometheusSourceDefaults(t *testing.T) {
 				Spec: PrometheusSourceSpec{},
 			},
 		},
+		"limits without overflow policy": {
+			initial: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Limits: &PrometheusSourceLimits{MaxSeries: 100},
+				},
+			},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Limits: &PrometheusSourceLimits{
+						MaxSeries:      100,
+						OverflowPolicy: OverflowPolicyTruncate,
+					},
+				},
+			},
+		},
+		"limits with overflow policy": {
+			initial: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Limits: &PrometheusSourceLimits{OverflowPolicy: OverflowPolicySplit},
+				},
+			},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Limits: &PrometheusSourceLimits{OverflowPolicy: OverflowPolicySplit},
+				},
+			},
+		},
 	}
 	for n, tc := range testCases {
 		t.Run(n, func(t *testing.T) {
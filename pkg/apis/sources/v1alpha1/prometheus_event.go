@@ -0,0 +1,208 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+
+	"github.com/prometheus/common/model"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+)
+
+const (
+	// errorEventTypeSuffix is appended to the event type of a source to name
+	// the type of the events sent in place of its query results.
+	errorEventTypeSuffix = ".error"
+
+	// noDataEventTypeSuffix is appended to the event type of a source to name
+	// the type of the events sent when its query returns no series.
+	noDataEventTypeSuffix = ".nodata"
+
+	// dataReturnedEventTypeSuffix is appended to the event type of a source to
+	// name the type of the events sent when its query returns series again.
+	dataReturnedEventTypeSuffix = ".datareturned"
+
+	// rateLimitedEventTypeSuffix is appended to the event type of a source to
+	// name the events summing up the events suppressed by its rate limit.
+	rateLimitedEventTypeSuffix = ".ratelimited"
+)
+
+// PartitionKeyExtension is the extension attribute holding the values of the
+// partition key labels of a source.
+const PartitionKeyExtension = "partitionkey"
+
+// reservedAttributeNames are the CloudEvent context attributes and the
+// extension attributes set by the receive adapter, or by the dead letter sink
+// delivery, which series labels cannot be promoted to.
+var reservedAttributeNames = sets.NewString(
+	"id", "source", "specversion", "type", "datacontenttype", "dataschema", "subject", "time", "data",
+	"truncated", "limitexceeded", "chunk", "lastchunk", PartitionKeyExtension,
+	"evaltime", "evalstart", "evalend", "sequence", "knativeerrordest", "knativeerrorcode",
+)
+
+// SubjectTemplateData is what the subject template of a source is executed with.
+// +k8s:deepcopy-gen=false
+type SubjectTemplateData struct {
+	// Name is the name of the PrometheusSource.
+	Name string
+	// Namespace is the namespace of the PrometheusSource.
+	Namespace string
+	// Labels are the labels shared by every series of the event.
+	Labels map[string]string
+}
+
+// ParseSubjectTemplate parses the subject template of a source. Labels missing
+// from the series of an event are rendered as empty strings.
+func ParseSubjectTemplate(text string) (*template.Template, error) {
+	return template.New("subject").Option("missingkey=zero").Parse(text)
+}
+
+// GetEventType returns the CloudEvent type of the query results of the source.
+func (s *PrometheusSourceSpec) GetEventType() string {
+	if s.EventType == "" {
+		return PromQLPrometheusSourceEventType
+	}
+	return s.EventType
+}
+
+// GetErrorEventType returns the CloudEvent type of the events sent in place
+// of the query results of the source.
+func (s *PrometheusSourceSpec) GetErrorEventType() string {
+	return s.GetEventType() + errorEventTypeSuffix
+}
+
+// GetNoDataEventType returns the CloudEvent type of the events sent when the
+// query of the source returns no series.
+func (s *PrometheusSourceSpec) GetNoDataEventType() string {
+	return s.GetEventType() + noDataEventTypeSuffix
+}
+
+// GetDataReturnedEventType returns the CloudEvent type of the events sent when
+// the query of the source returns series after a nodata event.
+func (s *PrometheusSourceSpec) GetDataReturnedEventType() string {
+	return s.GetEventType() + dataReturnedEventTypeSuffix
+}
+
+// GetRateLimitedEventType returns the CloudEvent type of the events summing up
+// the events suppressed by the rate limit of the source.
+func (s *PrometheusSourceSpec) GetRateLimitedEventType() string {
+	return s.GetEventType() + rateLimitedEventTypeSuffix
+}
+
+// ValidateEventSource checks that the event source is a URI reference.
+func ValidateEventSource(source string) *apis.FieldError {
+	if _, err := url.Parse(source); err != nil {
+		return apis.ErrInvalidValue(source, apis.CurrentField, err.Error())
+	}
+	return nil
+}
+
+// ValidateSubjectTemplate checks that the subject template parses.
+func ValidateSubjectTemplate(text string) *apis.FieldError {
+	if _, err := ParseSubjectTemplate(text); err != nil {
+		return apis.ErrInvalidValue(text, apis.CurrentField, err.Error())
+	}
+	return nil
+}
+
+// GetName returns the name of the extension attribute the label is promoted to.
+func (e *LabelExtension) GetName() string {
+	if e.Name == "" {
+		return SanitizeExtensionName(e.Label)
+	}
+	return e.Name
+}
+
+// SanitizeExtensionName turns a label name into a CloudEvent attribute name,
+// lowercasing it and removing the characters other than letters and digits.
+func SanitizeExtensionName(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return -1
+	}, label)
+}
+
+// ValidateLabelExtensions checks that the labels are valid Prometheus label
+// names and that they are promoted to distinct extension attributes, neither
+// reserved nor set by the CloudEvent overrides.
+func ValidateLabelExtensions(extensions []LabelExtension, ceOverrides *duckv1.CloudEventOverrides) *apis.FieldError {
+	var errs *apis.FieldError
+	names := make(map[string]int, len(extensions))
+	for i := range extensions {
+		e := &extensions[i]
+		if e.Label == "" {
+			errs = errs.Also(apis.ErrMissingField("label").ViaIndex(i))
+			continue
+		}
+		if !model.LabelName(e.Label).IsValid() {
+			errs = errs.Also(apis.ErrInvalidValue(e.Label, "label", "must be a valid label name").ViaIndex(i))
+			continue
+		}
+		field := "name"
+		if e.Name == "" {
+			field = "label"
+		}
+		name := e.GetName()
+		switch {
+		case name == "":
+			errs = errs.Also(apis.ErrInvalidValue(e.Label, field, "must contain a letter or a digit").ViaIndex(i))
+		case SanitizeExtensionName(name) != name:
+			errs = errs.Also(apis.ErrInvalidValue(name, field, "must consist of lowercase letters and digits").ViaIndex(i))
+		case reservedAttributeNames.Has(name):
+			errs = errs.Also(apis.ErrInvalidValue(name, field,
+				fmt.Sprintf("extension attribute %q is reserved", name)).ViaIndex(i))
+		case ceOverrides != nil && hasKey(ceOverrides.Extensions, name):
+			errs = errs.Also(apis.ErrInvalidValue(name, field,
+				fmt.Sprintf("extension attribute %q is set by ceOverrides", name)).ViaIndex(i))
+		default:
+			if j, ok := names[name]; ok {
+				errs = errs.Also(apis.ErrInvalidValue(name, field,
+					fmt.Sprintf("extension attribute %q is already promoted from labelExtensions[%d]", name, j)).ViaIndex(i))
+			} else {
+				names[name] = i
+			}
+		}
+	}
+	return errs
+}
+
+// ValidatePartitionKeyLabels checks that the labels are valid Prometheus
+// label names.
+func ValidatePartitionKeyLabels(labels []string) *apis.FieldError {
+	var errs *apis.FieldError
+	for i, label := range labels {
+		if !model.LabelName(label).IsValid() {
+			errs = errs.Also(apis.ErrInvalidValue(label, apis.CurrentField, "must be a valid label name").ViaIndex(i))
+		}
+	}
+	return errs
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
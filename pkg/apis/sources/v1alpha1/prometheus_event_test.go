@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+)
+
+func TestEventTypes(t *testing.T) {
+	spec := &PrometheusSourceSpec{}
+	if got, want := spec.GetEventType(), PromQLPrometheusSourceEventType; got != want {
+		t.Errorf("GetEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetErrorEventType(), PromQLErrorPrometheusSourceEventType; got != want {
+		t.Errorf("GetErrorEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetNoDataEventType(), PromQLNoDataPrometheusSourceEventType; got != want {
+		t.Errorf("GetNoDataEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetDataReturnedEventType(), PromQLDataReturnedPrometheusSourceEventType; got != want {
+		t.Errorf("GetDataReturnedEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetRateLimitedEventType(), PromQLRateLimitedPrometheusSourceEventType; got != want {
+		t.Errorf("GetRateLimitedEventType() = %q, want %q", got, want)
+	}
+
+	spec.EventType = "com.example.up"
+	if got, want := spec.GetEventType(), "com.example.up"; got != want {
+		t.Errorf("GetEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetErrorEventType(), "com.example.up.error"; got != want {
+		t.Errorf("GetErrorEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetNoDataEventType(), "com.example.up.nodata"; got != want {
+		t.Errorf("GetNoDataEventType() = %q, want %q", got, want)
+	}
+	if got, want := spec.GetRateLimitedEventType(), "com.example.up.ratelimited"; got != want {
+		t.Errorf("GetRateLimitedEventType() = %q, want %q", got, want)
+	}
+}
+
+func TestLabelExtensionName(t *testing.T) {
+	testCases := map[string]struct {
+		extension LabelExtension
+		want      string
+	}{
+		"lowercase label":    {extension: LabelExtension{Label: "severity"}, want: "severity"},
+		"snake case label":   {extension: LabelExtension{Label: "alert_name"}, want: "alertname"},
+		"mixed case label":   {extension: LabelExtension{Label: "__Kube_Namespace2"}, want: "kubenamespace2"},
+		"explicit name":      {extension: LabelExtension{Label: "namespace", Name: "ns"}, want: "ns"},
+		"no valid character": {extension: LabelExtension{Label: "__"}, want: ""},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := tc.extension.GetName(); got != tc.want {
+				t.Errorf("GetName() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
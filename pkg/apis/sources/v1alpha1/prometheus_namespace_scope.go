@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+// ScopedPromQL returns the query the receive adapter of the source runs: the
+// PromQL query of the source, restricted to the series of the namespace of the
+// source when the cluster query policy enables namespace scoping. It then also
+// fails when a relabel config of the source could set or drop the namespace
+// label of the series.
+func (s *PrometheusSource) ScopedPromQL(ctx context.Context) (string, error) {
+	policy := config.FromContextOrDefaults(ctx).QueryPolicy
+	if !policy.NamespaceScoping {
+		return s.Spec.PromQL, nil
+	}
+	for i := range s.Spec.RelabelConfigs {
+		if err := s.Spec.RelabelConfigs[i].checkNamespaceLabel(policy.NamespaceLabel); err != nil {
+			return "", fmt.Errorf("relabel config %d: %w", i, err)
+		}
+	}
+	return ScopeQuery(s.Spec.PromQL, policy.NamespaceLabel, s.Namespace)
+}
+
+// validateNamespaceScope rejects the queries that cannot be restricted to the
+// namespace of the source, and the relabel configs that could set or drop the
+// namespace label, when the cluster query policy enables namespace scoping.
+// Queries and relabel configs that do not parse are skipped, Validate reports
+// them on its own.
+func (s *PrometheusSource) validateNamespaceScope(ctx context.Context, paths FieldPaths) *apis.FieldError {
+	policy := config.FromContextOrDefaults(ctx).QueryPolicy
+	if !policy.NamespaceScoping {
+		return nil
+	}
+	var errs *apis.FieldError
+	for i := range s.Spec.RelabelConfigs {
+		if _, err := s.Spec.RelabelConfigs[i].Parse(); err != nil {
+			continue
+		}
+		if err := s.Spec.RelabelConfigs[i].checkNamespaceLabel(policy.NamespaceLabel); err != nil {
+			errs = errs.Also((&apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{apis.CurrentField},
+				Details: err.Error(),
+			}).ViaFieldIndex("relabelConfigs", i))
+		}
+	}
+	if _, err := parser.ParseExpr(s.Spec.PromQL); err != nil {
+		return errs
+	}
+	if _, err := ScopeQuery(s.Spec.PromQL, policy.NamespaceLabel, s.Namespace); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(s.Spec.PromQL, paths.PromQL, err.Error()))
+	}
+	return errs
+}
+
+// ScopeQuery rewrites the PromQL query so that every selector only matches
+// the series whose label equals the namespace, replacing the matchers the
+// selectors may already have on the label. It fails for the queries this
+// would silently change, because a selector only matches series of other
+// namespaces, and for the queries that could forge the label of the series
+// they return.
+func ScopeQuery(promQL, label, namespace string) (string, error) {
+	expr, err := parser.ParseExpr(promQL)
+	if err != nil {
+		return "", err
+	}
+	scope, err := labels.NewMatcher(labels.MatchEqual, label, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	var scopeErr error
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		switch n := node.(type) {
+		case *parser.VectorSelector:
+			matchers := make([]*labels.Matcher, 0, len(n.LabelMatchers)+1)
+			for _, m := range n.LabelMatchers {
+				if m.Name != label {
+					matchers = append(matchers, m)
+				} else if !m.Matches(namespace) {
+					scopeErr = fmt.Errorf("selector %s does not match namespace %q", n, namespace)
+					return scopeErr
+				}
+			}
+			n.LabelMatchers = append(matchers, scope)
+		case *parser.Call:
+			// label_replace and label_join take the destination label as
+			// second argument.
+			if n.Func.Name != "label_replace" && n.Func.Name != "label_join" {
+				break
+			}
+			if stringValue(n.Args[1]) == label {
+				scopeErr = fmt.Errorf("%s must not set the %q label", n.Func.Name, label)
+				return scopeErr
+			}
+		case *parser.AggregateExpr:
+			// count_values writes the sample values to the label it takes
+			// as parameter.
+			if n.Op == parser.COUNT_VALUES && stringValue(n.Param) == label {
+				scopeErr = fmt.Errorf("count_values must not set the %q label", label)
+				return scopeErr
+			}
+		}
+		return nil
+	})
+	if scopeErr != nil {
+		return "", scopeErr
+	}
+	return expr.String(), nil
+}
+
+// stringValue returns the value of the string literal, possibly in
+// parentheses, the expression is, or "" when it is not one.
+func stringValue(expr parser.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *parser.ParenExpr:
+			expr = e.Expr
+		case *parser.StringLiteral:
+			return e.Val
+		default:
+			return ""
+		}
+	}
+}
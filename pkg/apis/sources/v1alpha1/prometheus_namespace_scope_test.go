@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+func TestScopeQuery(t *testing.T) {
+	testCases := map[string]struct {
+		promQL  string
+		want    string
+		wantErr string
+	}{
+		"selector": {
+			promQL: `up`,
+			want:   `up{namespace="team-a"}`,
+		},
+		"range and aggregation": {
+			promQL: `sum by (pod) (rate(http_requests_total{code="500"}[5m]))`,
+			want:   `sum by(pod) (rate(http_requests_total{code="500",namespace="team-a"}[5m]))`,
+		},
+		"binary expression and subquery": {
+			promQL: `max_over_time(up[1h:5m]) / on (pod) kube_pod_info`,
+			want:   `max_over_time(up{namespace="team-a"}[1h:5m]) / on(pod) kube_pod_info{namespace="team-a"}`,
+		},
+		"overrides matching namespace matcher": {
+			promQL: `up{namespace=~"team-.*"}`,
+			want:   `up{namespace="team-a"}`,
+		},
+		"name regex selector": {
+			promQL: `{__name__=~"http_.+"}`,
+			want:   `{__name__=~"http_.+",namespace="team-a"}`,
+		},
+		"no selector": {
+			promQL: `vector(1)`,
+			want:   `vector(1)`,
+		},
+		"label_replace of another label": {
+			promQL: `label_replace(up, "host", "$1", "instance", "(.*):.*")`,
+			want:   `label_replace(up{namespace="team-a"}, "host", "$1", "instance", "(.*):.*")`,
+		},
+		"other namespace": {
+			promQL:  `up{namespace="team-b"}`,
+			wantErr: `selector up{namespace="team-b"} does not match namespace "team-a"`,
+		},
+		"excluded namespace": {
+			promQL:  `up{namespace!="team-a"}`,
+			wantErr: `selector up{namespace!="team-a"} does not match namespace "team-a"`,
+		},
+		"label_replace of the namespace label": {
+			promQL:  `label_replace(up, "namespace", "team-b", "", "")`,
+			wantErr: `label_replace must not set the "namespace" label`,
+		},
+		"label_join of the namespace label": {
+			promQL:  `label_join(up, "namespace", "", "pod")`,
+			wantErr: `label_join must not set the "namespace" label`,
+		},
+		"parenthesized label_replace of the namespace label": {
+			promQL:  `label_replace(up, ("namespace"), "team-b", "", "")`,
+			wantErr: `label_replace must not set the "namespace" label`,
+		},
+		"count_values of another label": {
+			promQL: `count_values("version", build_info)`,
+			want:   `count_values("version", build_info{namespace="team-a"})`,
+		},
+		"count_values of the namespace label": {
+			promQL:  `count_values("namespace", up)`,
+			wantErr: `count_values must not set the "namespace" label`,
+		},
+		"parenthesized count_values of the namespace label": {
+			promQL:  `count_values(("namespace"), up)`,
+			wantErr: `count_values must not set the "namespace" label`,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got, err := ScopeQuery(tc.promQL, "namespace", "team-a")
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("ScopeQuery() error = %v, want %s", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("ScopeQuery() =", err)
+			}
+			if got != tc.want {
+				t.Errorf("ScopeQuery() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrometheusSourceNamespaceScope(t *testing.T) {
+	policy, err := config.NewQueryPolicyFromMap(map[string]string{
+		"namespace-scoping": "true",
+		"namespace-label":   "kubernetes_namespace",
+	})
+	if err != nil {
+		t.Fatal("NewQueryPolicyFromMap() =", err)
+	}
+	enforced := config.ToContext(context.Background(), &config.Config{QueryPolicy: policy})
+
+	testCases := map[string]struct {
+		ctx            context.Context
+		promQL         string
+		relabelConfigs []RelabelConfig
+		wantPromQL     string
+		want           *apis.FieldError
+	}{
+		"scoping disabled": {
+			ctx:        context.Background(),
+			promQL:     `up{kubernetes_namespace="team-b"}`,
+			wantPromQL: `up{kubernetes_namespace="team-b"}`,
+		},
+		"scoped": {
+			ctx:        enforced,
+			promQL:     `rate(http_requests_total[5m])`,
+			wantPromQL: `rate(http_requests_total{kubernetes_namespace="team-a"}[5m])`,
+		},
+		"not scopable": {
+			ctx:    enforced,
+			promQL: `up{kubernetes_namespace="team-b"}`,
+			want: apis.ErrInvalidValue(`up{kubernetes_namespace="team-b"}`, "promQL",
+				`selector up{kubernetes_namespace="team-b"} does not match namespace "team-a"`),
+		},
+		"invalid query left to Validate": {
+			ctx:    enforced,
+			promQL: `up{`,
+		},
+		"relabel configs scoping disabled": {
+			ctx:    context.Background(),
+			promQL: `up`,
+			relabelConfigs: []RelabelConfig{
+				{Action: "labeldrop", Regex: ptr.String("kubernetes_.*")},
+			},
+			wantPromQL: `up`,
+		},
+		"relabel configs keeping the namespace label": {
+			ctx:    enforced,
+			promQL: `up`,
+			relabelConfigs: []RelabelConfig{
+				{Action: "labeldrop", Regex: ptr.String("pod_template_hash|instance")},
+				{Action: "labelkeep", Regex: ptr.String("kubernetes_namespace|pod")},
+				{Action: "labelmap", Regex: ptr.String("instance"), Replacement: ptr.String("host")},
+				{SourceLabels: []string{"kubernetes_namespace", "pod"}, Separator: ptr.String("/"), TargetLabel: "workload"},
+				{Action: "hashmod", SourceLabels: []string{"pod"}, Modulus: 4, TargetLabel: "shard"},
+			},
+			wantPromQL: `up{kubernetes_namespace="team-a"}`,
+		},
+		"relabel configs touching the namespace label": {
+			ctx:    enforced,
+			promQL: `up`,
+			relabelConfigs: []RelabelConfig{
+				{SourceLabels: []string{"pod"}, TargetLabel: "kubernetes_namespace"},
+				{SourceLabels: []string{"pod"}, Regex: ptr.String("(.*)-(.*)"), TargetLabel: "kubernetes_$1"},
+				{Action: "hashmod", SourceLabels: []string{"pod"}, Modulus: 4, TargetLabel: "kubernetes_namespace"},
+				{Action: "labelmap", Regex: ptr.String("exported_(.+)")},
+				{Action: "labeldrop", Regex: ptr.String("kubernetes_.*")},
+				{Action: "labelkeep", Regex: ptr.String("pod|instance")},
+				{Action: "labeldrop", SourceLabels: []string{"pod"}},
+			},
+			want: (&apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[0]"},
+				Details: `replace may set the "kubernetes_namespace" label`,
+			}).Also(&apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[1]"},
+				Details: `replace may set the "kubernetes_namespace" label`,
+			}, &apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[2]"},
+				Details: `hashmod may set the "kubernetes_namespace" label`,
+			}, &apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[3]"},
+				Details: `labelmap may set the "kubernetes_namespace" label`,
+			}, &apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[4]"},
+				Details: `labeldrop must not drop the "kubernetes_namespace" label`,
+			}, &apis.FieldError{
+				Message: "relabel config not allowed by namespace scoping",
+				Paths:   []string{"relabelConfigs[5]"},
+				Details: `labelkeep must keep the "kubernetes_namespace" label`,
+			}),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &PrometheusSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a"},
+				Spec:       PrometheusSourceSpec{PromQL: tc.promQL, RelabelConfigs: tc.relabelConfigs},
+			}
+			got := src.validateNamespaceScope(tc.ctx, SpecFieldPaths)
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Errorf("validateNamespaceScope (-want, +got) = %v", diff)
+			}
+			if tc.want != nil {
+				if _, err := src.ScopedPromQL(tc.ctx); err == nil {
+					t.Error("ScopedPromQL() = nil, want an error")
+				}
+				return
+			}
+			if tc.wantPromQL == "" {
+				return
+			}
+			promQL, err := src.ScopedPromQL(tc.ctx)
+			if err != nil {
+				t.Fatal("ScopedPromQL() =", err)
+			}
+			if promQL != tc.wantPromQL {
+				t.Errorf("ScopedPromQL() = %s, want %s", promQL, tc.wantPromQL)
+			}
+		})
+	}
+}
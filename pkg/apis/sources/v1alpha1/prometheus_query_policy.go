@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/robfig/cron"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+// policyFinding is a violation of the cluster query policy together with the
+// action the policy prescribes for it.
+type policyFinding struct {
+	action config.PolicyAction
+	err    *apis.FieldError
+}
+
+// Warnings returns the query policy violations that do not reject the source
+// but should be reported to the user as admission warnings.
+func (s *PrometheusSource) Warnings(ctx context.Context) []string {
+	return s.QueryPolicyWarnings(ctx, SpecFieldPaths)
+}
+
+// QueryPolicyWarnings is like Warnings, naming the fields by the given paths.
+func (s *PrometheusSource) QueryPolicyWarnings(ctx context.Context, paths FieldPaths) []string {
+	var warnings []string
+	for _, f := range s.Spec.queryPolicyFindings(ctx, paths) {
+		if f.action == config.PolicyActionWarn {
+			warnings = append(warnings, f.err.ViaField("spec").Error())
+		}
+	}
+	return warnings
+}
+
+// validateQueryPolicy returns the query policy violations that reject the source.
+func (s *PrometheusSourceSpec) validateQueryPolicy(ctx context.Context, paths FieldPaths) *apis.FieldError {
+	var errs *apis.FieldError
+	for _, f := range s.queryPolicyFindings(ctx, paths) {
+		if f.action == config.PolicyActionReject {
+			errs = errs.Also(f.err)
+		}
+	}
+	return errs
+}
+
+// queryPolicyFindings analyzes the cost of the query and of its schedule
+// against the cluster query policy. Fields that do not parse are skipped,
+// Validate reports them on its own.
+func (s *PrometheusSourceSpec) queryPolicyFindings(ctx context.Context, paths FieldPaths) []policyFinding {
+	policy := config.FromContextOrDefaults(ctx).QueryPolicy
+	var findings []policyFinding
+	report := func(action config.PolicyAction, err *apis.FieldError) {
+		if action != config.PolicyActionAllow {
+			findings = append(findings, policyFinding{action: action, err: err})
+		}
+	}
+
+	if expr, err := parser.ParseExpr(s.PromQL); err == nil {
+		parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+			switch n := node.(type) {
+			case *parser.VectorSelector:
+				switch nameMatcher, others := splitMatchers(n.LabelMatchers); {
+				case nameMatcher != nil && (nameMatcher.Type == labels.MatchRegexp || nameMatcher.Type == labels.MatchNotRegexp):
+					report(policy.NameRegexSelector, apis.ErrGeneric(fmt.Sprintf(
+						"selector %s at position %d matches the metric name with a regular expression",
+						n, n.PosRange.Start), paths.PromQL))
+				case others == 0:
+					report(policy.UnscopedSelector, apis.ErrGeneric(fmt.Sprintf(
+						"selector %s at position %d has no label matchers",
+						n, n.PosRange.Start), paths.PromQL))
+				}
+			case *parser.MatrixSelector:
+				if policy.MaxRangeDuration > 0 && n.Range > policy.MaxRangeDuration {
+					report(policy.MaxRangeDurationAction, apis.ErrGeneric(fmt.Sprintf(
+						"range %v at position %d exceeds the maximum of %v",
+						n.Range, n.PositionRange().Start, policy.MaxRangeDuration), paths.PromQL))
+				}
+			case *parser.SubqueryExpr:
+				if policy.MaxRangeDuration > 0 && n.Range > policy.MaxRangeDuration {
+					report(policy.MaxRangeDurationAction, apis.ErrGeneric(fmt.Sprintf(
+						"subquery range %v at position %d exceeds the maximum of %v",
+						n.Range, n.PositionRange().Start, policy.MaxRangeDuration), paths.PromQL))
+				}
+			}
+			return nil
+		})
+	}
+
+	sched, err := cron.ParseStandard(s.Schedule)
+	if err != nil {
+		return findings
+	}
+	interval := scheduleInterval(sched)
+
+	if policy.MinScheduleInterval > 0 && interval < policy.MinScheduleInterval {
+		report(policy.MinScheduleIntervalAction, apis.ErrGeneric(fmt.Sprintf(
+			"schedule runs every %v, more often than the minimum interval of %v",
+			interval, policy.MinScheduleInterval), paths.Schedule))
+	}
+
+	if s.Step != "" && policy.MaxPointsPerEvaluation > 0 {
+		if step, err := ParseStep(s.Step); err == nil {
+			if points := int64(interval / time.Duration(step)); points > policy.MaxPointsPerEvaluation {
+				report(policy.MaxPointsPerEvaluationAction, apis.ErrGeneric(fmt.Sprintf(
+					"step %s returns %d points per series for a schedule interval of %v, more than the maximum of %d",
+					s.Step, points, interval, policy.MaxPointsPerEvaluation), paths.Step))
+			}
+		}
+	}
+	return findings
+}
+
+// splitMatchers returns the metric name matcher, if any, and the number of
+// other label matchers.
+func splitMatchers(matchers []*labels.Matcher) (*labels.Matcher, int) {
+	var nameMatcher *labels.Matcher
+	others := 0
+	for _, m := range matchers {
+		if m.Name == labels.MetricName {
+			nameMatcher = m
+		} else {
+			others++
+		}
+	}
+	return nameMatcher, others
+}
+
+// scheduleInterval returns the shortest interval between two consecutive runs
+// of the schedule over a week.
+func scheduleInterval(sched cron.Schedule) time.Duration {
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	shortest := time.Duration(0)
+	prev := sched.Next(start)
+	for !prev.IsZero() && prev.Before(end) {
+		next := sched.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); shortest == 0 || d < shortest {
+			shortest = d
+		}
+		prev = next
+	}
+	return shortest
+}
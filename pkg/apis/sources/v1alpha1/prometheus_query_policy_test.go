@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+func TestPrometheusSourceQueryPolicy(t *testing.T) {
+	policy := &config.QueryPolicy{
+		UnscopedSelector:             config.PolicyActionWarn,
+		NameRegexSelector:            config.PolicyActionReject,
+		MaxRangeDuration:             time.Hour,
+		MaxRangeDurationAction:       config.PolicyActionWarn,
+		MaxPointsPerEvaluation:       100,
+		MaxPointsPerEvaluationAction: config.PolicyActionReject,
+		MinScheduleInterval:          5 * time.Minute,
+		MinScheduleIntervalAction:    config.PolicyActionWarn,
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{QueryPolicy: policy})
+
+	testCases := map[string]struct {
+		spec         PrometheusSourceSpec
+		wantErr      *apis.FieldError
+		wantWarnings []string
+	}{
+		"scoped query": {
+			spec: PrometheusSourceSpec{
+				PromQL:   `rate(http_requests_total{job="api"}[5m])`,
+				Schedule: "*/5 * * * *",
+			},
+		},
+		"unscoped selector": {
+			spec: PrometheusSourceSpec{
+				PromQL:   "up",
+				Schedule: "*/5 * * * *",
+			},
+			wantWarnings: []string{"selector up at position 0 has no label matchers: spec.promQL"},
+		},
+		"metric name regex": {
+			spec: PrometheusSourceSpec{
+				PromQL:   `{__name__=~".+"}`,
+				Schedule: "*/5 * * * *",
+			},
+			wantErr: apis.ErrGeneric(`selector {__name__=~".+"} at position 0 matches the metric name with a regular expression`, "promQL"),
+		},
+		"long range and subquery": {
+			spec: PrometheusSourceSpec{
+				PromQL:   `max_over_time(rate(up{job="api"}[2h])[1d:5m])`,
+				Schedule: "0 * * * *",
+			},
+			wantWarnings: []string{
+				"subquery range 24h0m0s at position 14 exceeds the maximum of 1h0m0s: spec.promQL",
+				"range 2h0m0s at position 19 exceeds the maximum of 1h0m0s: spec.promQL",
+			},
+		},
+		"frequent schedule and small step": {
+			spec: PrometheusSourceSpec{
+				PromQL:   `up{job="api"}`,
+				Schedule: "* * * * *",
+				Step:     "100ms",
+			},
+			wantErr: apis.ErrGeneric("step 100ms returns 600 points per series for a schedule interval of 1m0s, more than the maximum of 100", "step"),
+			wantWarnings: []string{
+				"schedule runs every 1m0s, more often than the minimum interval of 5m0s: spec.schedule",
+			},
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &PrometheusSource{Spec: tc.spec}
+			if diff := cmp.Diff(tc.wantErr.Error(), src.Spec.validateQueryPolicy(ctx, SpecFieldPaths).Error()); diff != "" {
+				t.Errorf("validateQueryPolicy (-want, +got) = %v", diff)
+			}
+			if diff := cmp.Diff(tc.wantWarnings, src.Warnings(ctx)); diff != "" {
+				t.Errorf("Warnings (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
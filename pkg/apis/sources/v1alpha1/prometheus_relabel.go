@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/model/relabel"
+	"gopkg.in/yaml.v2"
+	"knative.dev/pkg/apis"
+)
+
+// relabelConfigYAML is a relabel config as written in a Prometheus
+// configuration file.
+type relabelConfigYAML struct {
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	Separator    *string  `yaml:"separator,omitempty"`
+	Regex        *string  `yaml:"regex,omitempty"`
+	Modulus      uint64   `yaml:"modulus,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  *string  `yaml:"replacement,omitempty"`
+	Action       string   `yaml:"action,omitempty"`
+}
+
+// Parse returns the Prometheus relabel config. The config goes through the
+// YAML unmarshaling of Prometheus, so that it gets the same defaults and is
+// checked with the same rules as in a Prometheus configuration file.
+func (c *RelabelConfig) Parse() (*relabel.Config, error) {
+	out, err := yaml.Marshal(&relabelConfigYAML{
+		SourceLabels: c.SourceLabels,
+		Separator:    c.Separator,
+		Regex:        c.Regex,
+		Modulus:      c.Modulus,
+		TargetLabel:  c.TargetLabel,
+		Replacement:  c.Replacement,
+		Action:       c.Action,
+	})
+	if err != nil {
+		return nil, err
+	}
+	cfg := &relabel.Config{}
+	if err := yaml.UnmarshalStrict(out, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ParseRelabelConfigs returns the Prometheus relabel configs.
+func ParseRelabelConfigs(configs []RelabelConfig) ([]*relabel.Config, error) {
+	ret := make([]*relabel.Config, 0, len(configs))
+	for i := range configs {
+		cfg, err := configs[i].Parse()
+		if err != nil {
+			return nil, fmt.Errorf("relabel config %d: %w", i, err)
+		}
+		ret = append(ret, cfg)
+	}
+	return ret, nil
+}
+
+// ValidateRelabelConfigs checks the relabel configs with the rules of
+// Prometheus.
+func ValidateRelabelConfigs(configs []RelabelConfig) *apis.FieldError {
+	var errs *apis.FieldError
+	for i := range configs {
+		if _, err := configs[i].Parse(); err != nil {
+			errs = errs.Also((&apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{apis.CurrentField},
+				Details: err.Error(),
+			}).ViaIndex(i))
+		}
+	}
+	return errs
+}
+
+// checkNamespaceLabel fails when the relabel config could set or drop the
+// label, which namespace scoping reserves for the namespace of the source.
+// The replacement of a labelmap action and the target label of a replace
+// action may reference capture groups, and then may name any label.
+func (c *RelabelConfig) checkNamespaceLabel(label string) error {
+	cfg, err := c.Parse()
+	if err != nil {
+		return err
+	}
+	switch cfg.Action {
+	case relabel.Replace:
+		if cfg.TargetLabel == label || strings.Contains(cfg.TargetLabel, "$") {
+			return fmt.Errorf("replace may set the %q label", label)
+		}
+	case relabel.HashMod:
+		if cfg.TargetLabel == label {
+			return fmt.Errorf("hashmod may set the %q label", label)
+		}
+	case relabel.LabelMap:
+		if cfg.Replacement == label || strings.Contains(cfg.Replacement, "$") {
+			return fmt.Errorf("labelmap may set the %q label", label)
+		}
+	case relabel.LabelDrop:
+		if cfg.Regex.MatchString(label) {
+			return fmt.Errorf("labeldrop must not drop the %q label", label)
+		}
+	case relabel.LabelKeep:
+		if !cfg.Regex.MatchString(label) {
+			return fmt.Errorf("labelkeep must keep the %q label", label)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+// ValidateServerPolicy checks the Prometheus server URL the source resolved to
+// and the auth token file of the source against the cluster server policy.
+// The validation webhook and the reconciler both call it, as the policy may
+// change after admission.
+func (s *PrometheusSource) ValidateServerPolicy(ctx context.Context, serverURL string) *apis.FieldError {
+	return s.Spec.validateServerPolicy(ctx, s.Namespace, serverURL, SpecFieldPaths).ViaField("spec")
+}
+
+// validateServerPolicy checks the server URL and the spec against the server
+// policy of the namespace. Fields that do not parse are skipped, Validate
+// reports them on its own.
+func (s *PrometheusSourceSpec) validateServerPolicy(ctx context.Context, namespace, serverURL string, paths FieldPaths) *apis.FieldError {
+	policy := config.FromContextOrDefaults(ctx).ServerPolicy
+	var errs *apis.FieldError
+
+	if u, err := url.Parse(serverURL); err == nil && u.Host != "" {
+		if u.Scheme == "http" && !policy.AllowsPlainHTTP(namespace) {
+			errs = errs.Also(apis.ErrInvalidValue(serverURL, paths.ServerURL,
+				fmt.Sprintf("plain HTTP is not allowed in namespace %q, use https", namespace)))
+		}
+		if !policy.AllowsServer(namespace, u) {
+			errs = errs.Also(apis.ErrInvalidValue(serverURL, paths.ServerURL,
+				fmt.Sprintf("server is not allowed in namespace %q by the cluster server policy", namespace)))
+		}
+	}
+
+	if s.AuthTokenFile != "" && !policy.AllowsAuthTokenFile(s.AuthTokenFile) {
+		errs = errs.Also(apis.ErrInvalidValue(s.AuthTokenFile, paths.AuthTokenFile,
+			"must be a file in one of the approved directories: "+strings.Join(policy.AuthTokenDirs, ", ")))
+	}
+	return errs
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+func TestPrometheusSourceServerPolicy(t *testing.T) {
+	policy, err := config.NewServerPolicyFromMap(map[string]string{
+		"allowed-servers":         "https://*.monitoring.svc:9091",
+		"allowed-servers.team-a":  "http://prometheus.team-a.svc:9090",
+		"allow-plain-http.team-a": "true",
+	})
+	if err != nil {
+		t.Fatal("NewServerPolicyFromMap() =", err)
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{ServerPolicy: policy})
+
+	testCases := map[string]struct {
+		namespace string
+		spec      PrometheusSourceSpec
+		want      *apis.FieldError
+	}{
+		"allowed server": {
+			namespace: "team-b",
+			spec: PrometheusSourceSpec{
+				ServerURL:     "https://prometheus-k8s.monitoring.svc:9091",
+				AuthTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+			},
+		},
+		"namespace server over plain HTTP": {
+			namespace: "team-a",
+			spec: PrometheusSourceSpec{
+				ServerURL: "http://prometheus.team-a.svc:9090",
+			},
+		},
+		"server not allowed": {
+			namespace: "team-b",
+			spec: PrometheusSourceSpec{
+				ServerURL: "https://kubernetes.default.svc",
+			},
+			want: apis.ErrInvalidValue("https://kubernetes.default.svc", "spec.serverURL",
+				`server is not allowed in namespace "team-b" by the cluster server policy`),
+		},
+		"plain HTTP not allowed": {
+			namespace: "team-b",
+			spec: PrometheusSourceSpec{
+				ServerURL: "http://prometheus.team-a.svc:9090",
+			},
+			want: apis.ErrInvalidValue("http://prometheus.team-a.svc:9090", "spec.serverURL",
+				`plain HTTP is not allowed in namespace "team-b", use https`).Also(
+				apis.ErrInvalidValue("http://prometheus.team-a.svc:9090", "spec.serverURL",
+					`server is not allowed in namespace "team-b" by the cluster server policy`)),
+		},
+		"auth token file outside approved directories": {
+			namespace: "team-b",
+			spec: PrometheusSourceSpec{
+				ServerURL:     "https://prometheus-k8s.monitoring.svc:9091",
+				AuthTokenFile: "/etc/controller-secrets/token",
+			},
+			want: apis.ErrInvalidValue("/etc/controller-secrets/token", "spec.authTokenFile",
+				"must be a file in one of the approved directories: /var/run/secrets/kubernetes.io/serviceaccount"),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			src := &PrometheusSource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tc.namespace},
+				Spec:       tc.spec,
+			}
+			got := src.ValidateServerPolicy(ctx, tc.spec.ServerURL)
+			if diff := cmp.Diff(tc.want.Error(), got.Error()); diff != "" {
+				t.Errorf("ValidateServerPolicy (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
@@ -18,19 +18,195 @@ package v1alpha1
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/datatemplate"
 )
 
+// FieldPaths names the spec fields checked against the cluster policies, so
+// that every API version reports the violations under its own field names.
+type FieldPaths struct {
+	ServerURL     string
+	AuthTokenFile string
+	PromQL        string
+	Schedule      string
+	Step          string
+}
+
+// SpecFieldPaths are the v1alpha1 field paths.
+var SpecFieldPaths = FieldPaths{
+	ServerURL:     "serverURL",
+	AuthTokenFile: "authTokenFile",
+	PromQL:        "promQL",
+	Schedule:      "schedule",
+	Step:          "step",
+}
+
 // Validate Prometheus source object fields
 func (s *PrometheusSource) Validate(ctx context.Context) *apis.FieldError {
-	return s.Spec.Validate(ctx).ViaField("spec")
+	return s.Spec.Validate(ctx).ViaField("spec").Also(
+		s.ValidatePolicies(ctx, SpecFieldPaths),
+		ValidateMuteUntil(s.Annotations).ViaField("metadata"),
+	)
+}
+
+// ValidatePolicies checks the source against the cluster query and server
+// policies, naming the spec fields by the given paths.
+func (s *PrometheusSource) ValidatePolicies(ctx context.Context, paths FieldPaths) *apis.FieldError {
+	return s.Spec.validateQueryPolicy(ctx, paths).Also(
+		s.Spec.validateServerPolicy(ctx, s.Namespace, s.Spec.ServerURL, paths),
+		s.validateNamespaceScope(ctx, paths),
+	).ViaField("spec")
 }
 
 // Validate Prometheus source Spec object fields
 func (s *PrometheusSourceSpec) Validate(ctx context.Context) *apis.FieldError {
 	var errs *apis.FieldError
 
+	// Validate server URL or reference
+	switch {
+	case s.ServerURL == "" && s.ServerRef == nil:
+		errs = errs.Also(apis.ErrMissingOneOf("serverURL", "serverRef"))
+	case s.ServerURL != "" && s.ServerRef != nil:
+		errs = errs.Also(apis.ErrMultipleOneOf("serverURL", "serverRef"))
+	case s.ServerRef != nil:
+		errs = errs.Also(s.ServerRef.Validate(ctx).ViaField("serverRef"))
+	default:
+		errs = errs.Also(ValidateServerURL(s.ServerURL).ViaField("serverURL"))
+	}
+
+	// Validate PromQL
+	if s.PromQL == "" {
+		errs = errs.Also(apis.ErrMissingField("promQL"))
+	} else if fe := ValidatePromQL(s.PromQL); fe != nil {
+		errs = errs.Also(fe.ViaField("promQL"))
+	}
+
+	// Validate schedule
+	if s.Schedule == "" {
+		errs = errs.Also(apis.ErrMissingField("schedule"))
+	} else if _, err := cron.ParseStandard(s.Schedule); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(s.Schedule, "schedule", err.Error()))
+	}
+	errs = errs.Also(ValidateScheduling(s.Schedule, s.TimeZone, s.Jitter, s.StartingDeadline))
+
+	// Validate step
+	if s.Step != "" {
+		if _, err := ParseStep(s.Step); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(s.Step, "step", err.Error()))
+		}
+	}
+
+	// Validate step alignment and evaluation delay
+	if s.AlignToStep && s.Step == "" {
+		errs = errs.Also(apis.ErrInvalidValue(s.AlignToStep, "alignToStep", "only applies to range queries, with a step"))
+	}
+	if s.EvaluationDelay != nil && s.EvaluationDelay.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(s.EvaluationDelay.Duration.String(), "evaluationDelay", "must be a positive duration"))
+	}
+
+	// Validate query timeout
+	if s.QueryTimeout != "" {
+		if d, err := model.ParseDuration(s.QueryTimeout); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(s.QueryTimeout, "queryTimeout", err.Error()))
+		} else if d <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(s.QueryTimeout, "queryTimeout", "must be a positive duration"))
+		}
+	}
+
+	// Validate template
+	if s.Template != nil {
+		errs = errs.Also(s.Template.Validate(ctx).ViaField("template"))
+	}
+
+	// Validate relabel configs
+	errs = errs.Also(ValidateRelabelConfigs(s.RelabelConfigs).ViaField("relabelConfigs"))
+
+	// Validate data format
+	switch s.DataFormat {
+	case "", DataFormatPrometheusJSON:
+	case DataFormatPrometheusText, DataFormatOpenMetricsText, DataFormatCSV, DataFormatProtobuf:
+		if s.Template != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("template", "dataFormat"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(s.DataFormat, "dataFormat",
+			fmt.Sprintf("must be one of %q, %q, %q, %q or %q", DataFormatPrometheusJSON, DataFormatPrometheusText,
+				DataFormatOpenMetricsText, DataFormatCSV, DataFormatProtobuf)))
+	}
+
+	// Validate delivery
+	errs = errs.Also(s.Delivery.Validate(ctx).ViaField("delivery"))
+
+	// Validate outbox
+	if s.Outbox != nil {
+		errs = errs.Also(s.Outbox.Validate(ctx).ViaField("outbox"))
+	}
+
+	// Validate rate limit
+	if s.RateLimit != nil {
+		errs = errs.Also(s.RateLimit.Validate(ctx).ViaField("rateLimit"))
+	}
+
+	// Validate mute windows
+	for i := range s.MuteWindows {
+		errs = errs.Also(s.MuteWindows[i].Validate(ctx).ViaFieldIndex("muteWindows", i))
+	}
+
+	// Validate run mode
+	errs = errs.Also(ValidateRunMode(s, SpecFieldPaths))
+
+	// Validate limits
+	if s.Limits != nil {
+		errs = errs.Also(s.Limits.Validate(ctx).ViaField("limits"))
+	}
+
+	// Validate series per event
+	if s.SeriesPerEvent < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(s.SeriesPerEvent, "seriesPerEvent", "must not be negative"))
+	}
+
+	// Validate empty result policy
+	switch s.OnEmptyResult {
+	case "", EmptyResultPolicySend, EmptyResultPolicySkip, EmptyResultPolicyEmitNoData:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(s.OnEmptyResult, "onEmptyResult",
+			fmt.Sprintf("must be one of %q, %q or %q", EmptyResultPolicySend, EmptyResultPolicySkip, EmptyResultPolicyEmitNoData)))
+	}
+	switch {
+	case s.NoDataThreshold < 0:
+		errs = errs.Also(apis.ErrInvalidValue(s.NoDataThreshold, "noDataThreshold", "must not be negative"))
+	case s.NoDataThreshold > 0 && s.OnEmptyResult != EmptyResultPolicyEmitNoData:
+		errs = errs.Also(apis.ErrInvalidValue(s.NoDataThreshold, "noDataThreshold",
+			fmt.Sprintf("only applies when onEmptyResult is %q", EmptyResultPolicyEmitNoData)))
+	}
+
+	// Validate event attributes
+	if s.EventSource != "" {
+		errs = errs.Also(ValidateEventSource(s.EventSource).ViaField("eventSource"))
+	}
+	if s.Subject != "" {
+		errs = errs.Also(ValidateSubjectTemplate(s.Subject).ViaField("subject"))
+	}
+	if s.CloudEventOverrides != nil {
+		errs = errs.Also(s.CloudEventOverrides.Validate(ctx).ViaField("ceOverrides"))
+	}
+	errs = errs.Also(
+		ValidateLabelExtensions(s.LabelExtensions, s.CloudEventOverrides).ViaField("labelExtensions"),
+		ValidatePartitionKeyLabels(s.PartitionKeyLabels).ViaField("partitionKeyLabels"),
+	)
+
 	// Validate sink
 	if s.Sink == nil {
 		fe := apis.ErrMissingField("sink")
@@ -40,3 +216,293 @@ func (s *PrometheusSourceSpec) Validate(ctx context.Context) *apis.FieldError {
 	}
 	return errs
 }
+
+// Validate PrometheusSourceLimits object fields
+func (l *PrometheusSourceLimits) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if l.MaxSeries < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxSeries, "maxSeries", "must not be negative"))
+	}
+	if l.MaxSamples < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxSamples, "maxSamples", "must not be negative"))
+	}
+	if l.MaxResponseBytes < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxResponseBytes, "maxResponseBytes", "must not be negative"))
+	}
+	switch l.OverflowPolicy {
+	case "", OverflowPolicyTruncate, OverflowPolicySplit, OverflowPolicyDrop:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(l.OverflowPolicy, "overflowPolicy",
+			fmt.Sprintf("must be one of %q, %q or %q", OverflowPolicyTruncate, OverflowPolicySplit, OverflowPolicyDrop)))
+	}
+	return errs
+}
+
+// Validate PrometheusSourceOutbox object fields
+func (o *PrometheusSourceOutbox) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if o.MaxEvents < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(o.MaxEvents, "maxEvents", "must not be negative"))
+	}
+	if o.MaxBytes < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(o.MaxBytes, "maxBytes", "must not be negative"))
+	}
+	if o.MaxAge != nil && o.MaxAge.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(o.MaxAge.Duration.String(), "maxAge", "must be a positive duration"))
+	}
+	return errs
+}
+
+// Validate PrometheusSourceRateLimit object fields
+func (l *PrometheusSourceRateLimit) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if l.Events == 0 {
+		errs = errs.Also(apis.ErrMissingField("events"))
+	} else if l.Events < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.Events, "events", "must be positive"))
+	}
+	if l.Interval != nil && l.Interval.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.Interval.Duration.String(), "interval", "must be a positive duration"))
+	}
+	if l.Burst < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.Burst, "burst", "must not be negative"))
+	}
+	return errs
+}
+
+// Validate PrometheusSourceMuteWindow object fields
+func (w *PrometheusSourceMuteWindow) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	recurring := w.Schedule != "" || w.Duration != nil || w.TimeZone != ""
+	absolute := w.Start != nil || w.End != nil
+	switch {
+	case recurring && absolute:
+		errs = errs.Also(apis.ErrMultipleOneOf("schedule", "start"))
+	case recurring:
+		if w.Schedule == "" {
+			errs = errs.Also(apis.ErrMissingField("schedule"))
+		} else if _, err := cron.ParseStandard(w.Schedule); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(w.Schedule, "schedule", err.Error()))
+		}
+		if w.Duration == nil {
+			errs = errs.Also(apis.ErrMissingField("duration"))
+		} else if w.Duration.Duration <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(w.Duration.Duration.String(), "duration", "must be a positive duration"))
+		}
+		if w.TimeZone != "" {
+			if _, err := time.LoadLocation(w.TimeZone); err != nil {
+				errs = errs.Also(apis.ErrInvalidValue(w.TimeZone, "timeZone", err.Error()))
+			}
+		}
+	case absolute:
+		if w.Start == nil {
+			errs = errs.Also(apis.ErrMissingField("start"))
+		}
+		if w.End == nil {
+			errs = errs.Also(apis.ErrMissingField("end"))
+		}
+		if w.Start != nil && w.End != nil && !w.End.After(w.Start.Time) {
+			errs = errs.Also(apis.ErrInvalidValue(w.End.UTC().Format(time.RFC3339), "end", "must be after start"))
+		}
+	default:
+		errs = errs.Also(apis.ErrMissingOneOf("schedule", "start"))
+	}
+	switch w.Action {
+	case "", MuteActionSuppress, MuteActionSkip:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(w.Action, "action",
+			fmt.Sprintf("must be one of %q or %q", MuteActionSuppress, MuteActionSkip)))
+	}
+	return errs
+}
+
+// ValidateScheduling checks the time zone, jitter and starting deadline of
+// the schedule. The jitter must be shorter than the interval between the ticks
+// of a valid schedule.
+func ValidateScheduling(schedule, timeZone string, jitter, startingDeadline *metav1.Duration) *apis.FieldError {
+	var errs *apis.FieldError
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(timeZone, "timeZone", err.Error()))
+		}
+	}
+	if jitter != nil {
+		if jitter.Duration <= 0 {
+			errs = errs.Also(apis.ErrInvalidValue(jitter.Duration.String(), "jitter", "must be a positive duration"))
+		} else if sched, err := cron.ParseStandard(schedule); err == nil {
+			if interval := scheduleInterval(sched); interval > 0 && jitter.Duration >= interval {
+				errs = errs.Also(apis.ErrInvalidValue(jitter.Duration.String(), "jitter",
+					fmt.Sprintf("must be shorter than the %s interval of the schedule", interval)))
+			}
+		}
+	}
+	if startingDeadline != nil && startingDeadline.Duration <= 0 {
+		errs = errs.Also(apis.ErrInvalidValue(startingDeadline.Duration.String(), "startingDeadline", "must be a positive duration"))
+	}
+	return errs
+}
+
+// ValidateRunMode checks the run mode of the source. The receive adapter of a
+// CronJob does not keep state between evaluations, so it does not support the
+// outbox, the rate limit or the nodata events, and the time zone of its
+// schedule is the one of the CronJob controller. The shared receive adapter
+// has no volume of its own for the outbox, and no token file of the source
+// to read. Only a Deployment has replicas, and the outbox of one of them is
+// not replayed by the others. The spec fields are named by the given paths.
+func ValidateRunMode(s *PrometheusSourceSpec, paths FieldPaths) *apis.FieldError {
+	var errs *apis.FieldError
+	unsupported := func(field string) {
+		errs = errs.Also(apis.ErrGeneric(fmt.Sprintf("not supported with the %q run mode", s.RunMode), field))
+	}
+	switch s.RunMode {
+	case "", RunModeDeployment:
+		if s.Replicas == nil {
+			return nil
+		}
+		if *s.Replicas < 1 {
+			errs = errs.Also(apis.ErrInvalidValue(*s.Replicas, "replicas", "must be at least 1"))
+		} else if *s.Replicas > 1 && s.Outbox != nil {
+			errs = errs.Also(apis.ErrGeneric("not supported with more than one replica", "outbox"))
+		}
+		return errs
+	case RunModeShared:
+		if s.AuthTokenFile != "" {
+			unsupported(paths.AuthTokenFile)
+		}
+		if s.Outbox != nil {
+			unsupported("outbox")
+		}
+		if s.Replicas != nil {
+			unsupported("replicas")
+		}
+		return errs
+	case RunModeCronJob:
+	default:
+		return apis.ErrInvalidValue(s.RunMode, "runMode",
+			fmt.Sprintf("must be one of %q, %q or %q", RunModeDeployment, RunModeCronJob, RunModeShared))
+	}
+	if s.Replicas != nil {
+		unsupported("replicas")
+	}
+	if s.TimeZone != "" {
+		unsupported("timeZone")
+	}
+	if s.Outbox != nil {
+		unsupported("outbox")
+	}
+	if s.RateLimit != nil {
+		unsupported("rateLimit")
+	}
+	if s.OnEmptyResult == EmptyResultPolicyEmitNoData {
+		unsupported("onEmptyResult")
+	}
+	return errs
+}
+
+// ValidateMuteUntil checks that the mute-until annotation, if any, is an
+// RFC 3339 timestamp.
+func ValidateMuteUntil(annotations map[string]string) *apis.FieldError {
+	value, ok := annotations[MuteUntilAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return apis.ErrInvalidValue(value, MuteUntilAnnotation, err.Error()).ViaField("annotations")
+	}
+	return nil
+}
+
+// Validate PrometheusSourceTemplate object fields
+func (t *PrometheusSourceTemplate) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	switch {
+	case t.Text == "" && t.JSON == "":
+		errs = errs.Also(apis.ErrMissingOneOf("text", "json"))
+	case t.Text != "" && t.JSON != "":
+		errs = errs.Also(apis.ErrMultipleOneOf("text", "json"))
+	case t.Text != "":
+		if _, err := datatemplate.Parse(t.Text); err != nil {
+			errs = errs.Also(&apis.FieldError{
+				Message: "invalid template",
+				Paths:   []string{"text"},
+				Details: err.Error(),
+			})
+		}
+	default:
+		if _, err := datatemplate.ParseJSON(t.JSON); err != nil {
+			errs = errs.Also(&apis.FieldError{
+				Message: "invalid template",
+				Paths:   []string{"json"},
+				Details: err.Error(),
+			})
+		}
+	}
+	if t.DataContentType != "" {
+		if _, _, err := mime.ParseMediaType(t.DataContentType); err != nil {
+			errs = errs.Also(apis.ErrInvalidValue(t.DataContentType, "dataContentType", err.Error()))
+		}
+	}
+	return errs
+}
+
+// ParseStep parses a query resolution step the same way the Prometheus HTTP
+// API does: either a float number of seconds or a Prometheus duration string.
+func ParseStep(step string) (model.Duration, error) {
+	if secs, err := strconv.ParseFloat(step, 64); err == nil {
+		if secs <= 0 {
+			return 0, fmt.Errorf("step must be a positive duration")
+		}
+		return model.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(step)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("step must be a positive duration")
+	}
+	return d, nil
+}
+
+// ValidatePromQL parses the query with the upstream PromQL parser and returns
+// one error per parse error, each pointing at the offending position.
+func ValidatePromQL(query string) *apis.FieldError {
+	_, err := parser.ParseExpr(query)
+	if err == nil {
+		return nil
+	}
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) {
+		return &apis.FieldError{
+			Message: "invalid PromQL",
+			Paths:   []string{apis.CurrentField},
+			Details: err.Error(),
+		}
+	}
+	var errs *apis.FieldError
+	for i := range parseErrs {
+		pe := parseErrs[i]
+		errs = errs.Also(&apis.FieldError{
+			Message: fmt.Sprintf("invalid PromQL at position %d: %v", pe.PositionRange.Start, pe.Err),
+			Paths:   []string{apis.CurrentField},
+			Details: pe.Error(),
+		})
+	}
+	return errs
+}
+
+// ValidateServerURL checks that the Prometheus server URL is an absolute
+// http(s) URL.
+func ValidateServerURL(serverURL string) *apis.FieldError {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return apis.ErrInvalidValue(serverURL, apis.CurrentField, err.Error())
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return apis.ErrInvalidValue(serverURL, apis.CurrentField, "must be an absolute URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return apis.ErrInvalidValue(serverURL, apis.CurrentField, "scheme must be http or https")
+	}
+	return nil
+}
@@ -19,21 +19,50 @@ package v1alpha1
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/webhook/resourcesemantics"
 
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
 	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
 )
 
+var validSink = duckv1.Destination{
+	URI: apis.HTTP("sink.example.com"),
+}
+
+func TestPrometheusSourceValidationValid(t *testing.T) {
+	src := &PrometheusSource{
+		Spec: PrometheusSourceSpec{
+			ServerURL: "https://prometheus.example.com:9091",
+			PromQL:    `sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`,
+			Schedule:  "*/5 * * * *",
+			Step:      "30s",
+			Sink:      &validSink,
+		},
+	}
+	if err := src.Validate(context.Background()); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
 func TestPrometheusSourceValidation(t *testing.T) {
+	invalidBackoffPolicy := eventingduckv1.BackoffPolicyType("quadratic")
 	testCases := map[string]struct {
 		cr   resourcesemantics.GenericCRD
 		want *apis.FieldError
 	}{
 		"missing sink": {
 			cr: &PrometheusSource{
-				Spec: PrometheusSourceSpec{},
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+				},
 			},
 			want: func() *apis.FieldError {
 				var errs *apis.FieldError
@@ -42,6 +71,571 @@ func TestPrometheusSourceValidation(t *testing.T) {
 				return errs
 			}(),
 		},
+		"missing required fields": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Sink: &validSink,
+				},
+			},
+			want: apis.ErrMissingOneOf("spec.serverURL", "spec.serverRef").Also(
+				apis.ErrMissingField("spec.promQL", "spec.schedule")),
+		},
+		"server URL and reference": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					ServerRef: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.serverURL", "spec.serverRef"),
+		},
+		"invalid server reference": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerRef: &duckv1.KReference{APIVersion: "v1", Name: "prometheus"},
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrMissingField("spec.serverRef.kind"),
+		},
+		"invalid promQL": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    "rate(http_requests_total[5m] offset)",
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: &apis.FieldError{
+				Message: `invalid PromQL at position 35: unexpected ")" in offset, expected duration`,
+				Paths:   []string{"spec.promQL"},
+				Details: `1:36: parse error: unexpected ")" in offset, expected duration`,
+			},
+		},
+		"invalid schedule": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    "up",
+					Schedule:  "every minute",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("every minute", "spec.schedule", "Expected exactly 5 fields, found 2: every minute"),
+		},
+		"invalid step": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Step:      "5 minutes",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("5 minutes", "spec.step", `not a valid duration string: "5 minutes"`),
+		},
+		"non-positive step": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Step:      "0",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("0", "spec.step", "step must be a positive duration"),
+		},
+		"invalid limits": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Limits: &PrometheusSourceLimits{
+						MaxSeries:      -1,
+						OverflowPolicy: "discard",
+					},
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.limits.maxSeries", "must not be negative").Also(
+				apis.ErrInvalidValue("discard", "spec.limits.overflowPolicy", `must be one of "truncate", "split" or "drop"`)),
+		},
+		"invalid query timeout": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:    "https://prometheus.example.com:9090",
+					PromQL:       `up{job="api"}`,
+					Schedule:     "* * * * *",
+					Sink:         &validSink,
+					QueryTimeout: "0s",
+				},
+			},
+			want: apis.ErrInvalidValue("0s", "spec.queryTimeout", "must be a positive duration"),
+		},
+		"negative series per event": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:      "https://prometheus.example.com:9090",
+					PromQL:         `up{job="api"}`,
+					Schedule:       "* * * * *",
+					Sink:           &validSink,
+					SeriesPerEvent: -1,
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.seriesPerEvent", "must not be negative"),
+		},
+		"relative server URL": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "prometheus:9090",
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("prometheus:9090", "spec.serverURL", "must be an absolute URL"),
+		},
+		"non-http server URL": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "ftp://prometheus.example.com",
+					PromQL:    "up",
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("ftp://prometheus.example.com", "spec.serverURL", "scheme must be http or https"),
+		},
+		"invalid event attributes": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:   "https://prometheus.example.com:9090",
+					PromQL:      `up{job="api"}`,
+					Schedule:    "* * * * *",
+					Sink:        &validSink,
+					EventSource: "%zz",
+					Subject:     "{{.Labels.job",
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team-a": "a"},
+					},
+				},
+			},
+			want: apis.ErrInvalidValue("%zz", "spec.eventSource", `parse "%zz": invalid URL escape "%zz"`).Also(
+				apis.ErrInvalidValue("{{.Labels.job", "spec.subject", "template: subject:1: unclosed action"),
+				apis.ErrInvalidKeyName("team-a", "spec.ceOverrides.extensions", "keys are expected to be alphanumeric")),
+		},
+		"invalid template": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Template: &PrometheusSourceTemplate{
+						Text:            `{{ range .Series }}{{ humanise (value .) }}{{ end }}`,
+						DataContentType: "text/",
+					},
+				},
+			},
+			want: (&apis.FieldError{
+				Message: "invalid template",
+				Paths:   []string{"spec.template.text"},
+				Details: `template: text:1: function "humanise" not defined`,
+			}).Also(apis.ErrInvalidValue("text/", "spec.template.dataContentType", "mime: expected token after slash")),
+		},
+		"template and data format": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:  "https://prometheus.example.com:9090",
+					PromQL:     `up{job="api"}`,
+					Schedule:   "* * * * *",
+					Sink:       &validSink,
+					Template:   &PrometheusSourceTemplate{Text: "{{ .Name }}"},
+					DataFormat: DataFormatCSV,
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.template", "spec.dataFormat"),
+		},
+		"invalid data format": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:  "https://prometheus.example.com:9090",
+					PromQL:     `up{job="api"}`,
+					Schedule:   "* * * * *",
+					Sink:       &validSink,
+					DataFormat: "xml",
+				},
+			},
+			want: apis.ErrInvalidValue("xml", "spec.dataFormat",
+				`must be one of "prometheus-json", "prometheus-text", "openmetrics-text", "csv" or "protobuf"`),
+		},
+		"relabel configs": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RelabelConfigs: []RelabelConfig{
+						{Action: "labeldrop", Regex: ptr.String("pod_template_hash|instance")},
+						{SourceLabels: []string{"job"}, Regex: ptr.String("api|web"), Action: "keep"},
+						{SourceLabels: []string{"namespace", "pod"}, Separator: ptr.String("/"), TargetLabel: "workload"},
+					},
+				},
+			},
+		},
+		"invalid relabel configs": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RelabelConfigs: []RelabelConfig{
+						{SourceLabels: []string{"job"}},
+						{Action: "labeldrop", SourceLabels: []string{"job"}},
+						{Regex: ptr.String("("), TargetLabel: "job"},
+						{Action: "delete"},
+					},
+				},
+			},
+			want: (&apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{"spec.relabelConfigs[0]"},
+				Details: "relabel configuration for replace action requires 'target_label' value",
+			}).Also(&apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{"spec.relabelConfigs[1]"},
+				Details: "labeldrop action requires only 'regex', and no other fields",
+			}, &apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{"spec.relabelConfigs[2]"},
+				Details: "error parsing regexp: missing closing ): `^(?:()$`",
+			}, &apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{"spec.relabelConfigs[3]"},
+				Details: `unknown relabel action "delete"`,
+			}),
+		},
+		"invalid empty result policy": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:       "https://prometheus.example.com:9090",
+					PromQL:          `up{job="api"}`,
+					Schedule:        "* * * * *",
+					Sink:            &validSink,
+					OnEmptyResult:   "ignore",
+					NoDataThreshold: 3,
+				},
+			},
+			want: apis.ErrInvalidValue("ignore", "spec.onEmptyResult", `must be one of "send", "skip" or "emitNoData"`).Also(
+				apis.ErrInvalidValue(3, "spec.noDataThreshold", `only applies when onEmptyResult is "emitNoData"`)),
+		},
+		"negative no data threshold": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:       "https://prometheus.example.com:9090",
+					PromQL:          `up{job="api"}`,
+					Schedule:        "* * * * *",
+					Sink:            &validSink,
+					OnEmptyResult:   EmptyResultPolicyEmitNoData,
+					NoDataThreshold: -1,
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.noDataThreshold", "must not be negative"),
+		},
+		"invalid delivery": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Delivery: &eventingduckv1.DeliverySpec{
+						Retry:         ptr.Int32(-1),
+						BackoffPolicy: &invalidBackoffPolicy,
+						BackoffDelay:  ptr.String("1s"),
+					},
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.delivery.retry").Also(
+				apis.ErrInvalidValue("quadratic", "spec.delivery.backoffPolicy"),
+				apis.ErrInvalidValue("1s", "spec.delivery.backoffDelay")),
+		},
+		"invalid outbox": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Outbox: &PrometheusSourceOutbox{
+						MaxEvents: -1,
+						MaxBytes:  -1,
+						MaxAge:    &metav1.Duration{},
+					},
+				},
+			},
+			want: apis.ErrInvalidValue(-1, "spec.outbox.maxEvents", "must not be negative").Also(
+				apis.ErrInvalidValue(-1, "spec.outbox.maxBytes", "must not be negative"),
+				apis.ErrInvalidValue("0s", "spec.outbox.maxAge", "must be a positive duration")),
+		},
+		"invalid rate limit": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RateLimit: &PrometheusSourceRateLimit{
+						Interval: &metav1.Duration{Duration: -time.Minute},
+						Burst:    -1,
+					},
+				},
+			},
+			want: apis.ErrMissingField("spec.rateLimit.events").Also(
+				apis.ErrInvalidValue("-1m0s", "spec.rateLimit.interval", "must be a positive duration"),
+				apis.ErrInvalidValue(-1, "spec.rateLimit.burst", "must not be negative")),
+		},
+		"negative rate limit": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RateLimit: &PrometheusSourceRateLimit{Events: -10},
+				},
+			},
+			want: apis.ErrInvalidValue(-10, "spec.rateLimit.events", "must be positive"),
+		},
+		"invalid evaluation time": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:       "https://prometheus.example.com:9090",
+					PromQL:          `up{job="api"}`,
+					Schedule:        "* * * * *",
+					AlignToStep:     true,
+					EvaluationDelay: &metav1.Duration{Duration: -time.Minute},
+					Sink:            &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue(true, "spec.alignToStep", "only applies to range queries, with a step").Also(
+				apis.ErrInvalidValue("-1m0s", "spec.evaluationDelay", "must be a positive duration")),
+		},
+		"invalid scheduling": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:        "https://prometheus.example.com:9090",
+					PromQL:           `up{job="api"}`,
+					Schedule:         "*/5 * * * *",
+					TimeZone:         "Mars/Olympus_Mons",
+					Jitter:           &metav1.Duration{Duration: 5 * time.Minute},
+					StartingDeadline: &metav1.Duration{},
+					Sink:             &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("Mars/Olympus_Mons", "spec.timeZone", "unknown time zone Mars/Olympus_Mons").Also(
+				apis.ErrInvalidValue("5m0s", "spec.jitter", "must be shorter than the 5m0s interval of the schedule"),
+				apis.ErrInvalidValue("0s", "spec.startingDeadline", "must be a positive duration")),
+		},
+		"invalid run mode": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RunMode:   "daemonSet",
+				},
+			},
+			want: apis.ErrInvalidValue("daemonSet", "spec.runMode", `must be one of "deployment", "cronJob" or "shared"`),
+		},
+		"outbox in the shared adapter": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Outbox:    &PrometheusSourceOutbox{},
+					RunMode:   RunModeShared,
+				},
+			},
+			want: apis.ErrGeneric(`not supported with the "shared" run mode`, "spec.outbox"),
+		},
+		"token file in the shared adapter": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:     "https://prometheus.example.com:9090",
+					AuthTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+					PromQL:        `up{job="api"}`,
+					Schedule:      "* * * * *",
+					Sink:          &validSink,
+					RunMode:       RunModeShared,
+				},
+			},
+			want: apis.ErrGeneric(`not supported with the "shared" run mode`, "spec.authTokenFile"),
+		},
+		"invalid replicas": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Replicas:  ptr.Int32(0),
+				},
+			},
+			want: apis.ErrInvalidValue(0, "spec.replicas", "must be at least 1"),
+		},
+		"outbox with replicas": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Outbox:    &PrometheusSourceOutbox{},
+					Replicas:  ptr.Int32(2),
+				},
+			},
+			want: apis.ErrGeneric("not supported with more than one replica", "spec.outbox"),
+		},
+		"replicas in the shared adapter": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					RunMode:   RunModeShared,
+					Replicas:  ptr.Int32(2),
+				},
+			},
+			want: apis.ErrGeneric(`not supported with the "shared" run mode`, "spec.replicas"),
+		},
+		"stateful features in a cron job": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL:     "https://prometheus.example.com:9090",
+					PromQL:        `up{job="api"}`,
+					Schedule:      "0 * * * *",
+					TimeZone:      "Europe/Paris",
+					Sink:          &validSink,
+					Outbox:        &PrometheusSourceOutbox{},
+					RateLimit:     &PrometheusSourceRateLimit{Events: 10},
+					OnEmptyResult: EmptyResultPolicyEmitNoData,
+					RunMode:       RunModeCronJob,
+				},
+			},
+			want: apis.ErrGeneric(`not supported with the "cronJob" run mode`,
+				"spec.timeZone", "spec.outbox", "spec.rateLimit", "spec.onEmptyResult"),
+		},
+		"invalid mute windows": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					MuteWindows: []PrometheusSourceMuteWindow{{
+						Schedule: "0 2 * * 6",
+						TimeZone: "Mars/Olympus_Mons",
+						Action:   "drop",
+					}, {
+						Start: &metav1.Time{Time: time.Date(2022, time.March, 1, 11, 0, 0, 0, time.UTC)},
+						End:   &metav1.Time{Time: time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)},
+					}, {
+						Schedule: "0 2 * * 6",
+						Duration: &metav1.Duration{Duration: 2 * time.Hour},
+						End:      &metav1.Time{Time: time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)},
+					}, {}},
+				},
+			},
+			want: apis.ErrMissingField("spec.muteWindows[0].duration").Also(
+				apis.ErrInvalidValue("Mars/Olympus_Mons", "spec.muteWindows[0].timeZone", "unknown time zone Mars/Olympus_Mons"),
+				apis.ErrInvalidValue("drop", "spec.muteWindows[0].action", `must be one of "suppress" or "skip"`),
+				apis.ErrInvalidValue("2022-03-01T10:00:00Z", "spec.muteWindows[1].end", "must be after start"),
+				apis.ErrMultipleOneOf("spec.muteWindows[2].schedule", "spec.muteWindows[2].start"),
+				apis.ErrMissingOneOf("spec.muteWindows[3].schedule", "spec.muteWindows[3].start")),
+		},
+		"invalid mute until": {
+			cr: &PrometheusSource{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{MuteUntilAnnotation: "tomorrow"},
+				},
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+				},
+			},
+			want: apis.ErrInvalidValue("tomorrow", "metadata.annotations."+MuteUntilAnnotation,
+				`parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`),
+		},
+		"template text and JSON": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					Template: &PrometheusSourceTemplate{
+						Text: "{{ .Name }}",
+						JSON: `{"name": "{{ .Name }}"}`,
+					},
+				},
+			},
+			want: apis.ErrMultipleOneOf("spec.template.text", "spec.template.json"),
+		},
+		"invalid label extensions": {
+			cr: &PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServerURL: "https://prometheus.example.com:9090",
+					PromQL:    `up{job="api"}`,
+					Schedule:  "* * * * *",
+					Sink:      &validSink,
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team": "a"},
+					},
+					LabelExtensions: []LabelExtension{
+						{Label: "alert_name"},
+						{Label: "alertname"},
+						{Label: "team"},
+						{Label: "job", Name: "Job"},
+						{Label: "instance", Name: "time"},
+						{Label: "__"},
+						{Label: "0job"},
+						{Name: "job"},
+						{Label: "sequence"},
+						{Label: "knative_error_code"},
+					},
+					PartitionKeyLabels: []string{"namespace", "pod-name"},
+				},
+			},
+			want: apis.ErrInvalidValue("alertname", "spec.labelExtensions[1].label",
+				`extension attribute "alertname" is already promoted from labelExtensions[0]`).Also(
+				apis.ErrInvalidValue("team", "spec.labelExtensions[2].label", `extension attribute "team" is set by ceOverrides`),
+				apis.ErrInvalidValue("Job", "spec.labelExtensions[3].name", "must consist of lowercase letters and digits"),
+				apis.ErrInvalidValue("time", "spec.labelExtensions[4].name", `extension attribute "time" is reserved`),
+				apis.ErrInvalidValue("__", "spec.labelExtensions[5].label", "must contain a letter or a digit"),
+				apis.ErrInvalidValue("0job", "spec.labelExtensions[6].label", "must be a valid label name"),
+				apis.ErrMissingField("spec.labelExtensions[7].label"),
+				apis.ErrInvalidValue("sequence", "spec.labelExtensions[8].label", `extension attribute "sequence" is reserved`),
+				apis.ErrInvalidValue("knativeerrorcode", "spec.labelExtensions[9].label", `extension attribute "knativeerrorcode" is reserved`),
+				apis.ErrInvalidValue("pod-name", "spec.partitionKeyLabels[1]", "must be a valid label name")),
+		},
 	}
 
 	for n, test := range testCases {
@@ -17,7 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/eventing/pkg/apis/duck"
 	"knative.dev/pkg/apis"
 )
@@ -34,6 +38,56 @@ const (
 
 	// PrometheusConditionDeployed has status True when the PrometheusSource has had it's deployment created.
 	PrometheusConditionDeployed apis.ConditionType = "Deployed"
+
+	// PrometheusConditionServerResolved has status True when the PrometheusSource's server reference has been
+	// resolved to the URL of the Prometheus server.
+	PrometheusConditionServerResolved apis.ConditionType = "ServerResolved"
+
+	// PrometheusConditionServerAllowed has status True when the cluster server policy allows the PrometheusSource's
+	// Prometheus server and auth token file.
+	PrometheusConditionServerAllowed apis.ConditionType = "ServerAllowed"
+
+	// PrometheusConditionValidQuery has status True when the PrometheusSource's query complies with the cluster
+	// query policy.
+	PrometheusConditionValidQuery apis.ConditionType = "ValidQuery"
+
+	// PrometheusConditionDeadLetterSinkResolved has status True when the PrometheusSource's dead letter sink has
+	// been resolved to a URI.
+	PrometheusConditionDeadLetterSinkResolved apis.ConditionType = "DeadLetterSinkResolved"
+
+	// PrometheusConditionWithinRateLimit has status True when the receive adapter of the PrometheusSource has not
+	// suppressed events exceeding its rate limit lately.
+	PrometheusConditionWithinRateLimit apis.ConditionType = "WithinRateLimit"
+
+	// PrometheusConditionWithinResultLimits has status True when the receive adapter of the PrometheusSource has
+	// not read a query result exceeding its limits lately.
+	PrometheusConditionWithinResultLimits apis.ConditionType = "WithinResultLimits"
+
+	// PrometheusConditionSuspended has status True when the PrometheusSource is suspended and its receive adapter
+	// scaled down.
+	PrometheusConditionSuspended apis.ConditionType = "Suspended"
+
+	// PrometheusConditionLastRunSucceeded has status True when the last Job of the CronJob running the receive
+	// adapter of a PrometheusSource with the cronJob run mode succeeded.
+	PrometheusConditionLastRunSucceeded apis.ConditionType = "LastRunSucceeded"
+
+	// PrometheusConditionLeaderElected has status True when the replicas of the receive adapter Deployment of a
+	// PrometheusSource hold a Lease electing the one evaluating its query.
+	PrometheusConditionLeaderElected apis.ConditionType = "LeaderElected"
+)
+
+const (
+	// AdapterEventComponent is the component of the Kubernetes events the receive adapters record on their
+	// PrometheusSources.
+	AdapterEventComponent = "prometheussource-adapter"
+
+	// ResultLimitExceededReason is the reason of the Kubernetes events the receive adapter records on the
+	// PrometheusSource when a query result exceeds its limits.
+	ResultLimitExceededReason = "ResultLimitExceeded"
+
+	// RateLimitedReason is the reason of the Kubernetes events the receive adapter records on the PrometheusSource
+	// when it suppresses events exceeding its rate limit.
+	RateLimitedReason = "RateLimited"
 )
 
 var PrometheusCondSet = apis.NewLivingConditionSet(
@@ -66,6 +120,42 @@ func (s *PrometheusSourceStatus) MarkInvalidSchedule(reason, messageFormat strin
 	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionValidSchedule, reason, messageFormat, messageA...)
 }
 
+// MarkServerResolved sets the condition that the server reference of the source has been resolved.
+func (s *PrometheusSourceStatus) MarkServerResolved() {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionServerResolved)
+}
+
+// MarkServerNotResolved sets the condition that the server reference of the source could not be resolved, the
+// receive adapter is not deployed.
+func (s *PrometheusSourceStatus) MarkServerNotResolved(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionServerResolved, reason, messageFormat, messageA...)
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeployed, reason, "The receive adapter is not deployed.")
+}
+
+// MarkServerAllowed sets the condition that the cluster server policy allows the source.
+func (s *PrometheusSourceStatus) MarkServerAllowed() {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionServerAllowed)
+}
+
+// MarkServerNotAllowed sets the condition that the cluster server policy does not allow the source, the
+// receive adapter is not deployed.
+func (s *PrometheusSourceStatus) MarkServerNotAllowed(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionServerAllowed, reason, messageFormat, messageA...)
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeployed, reason, "The receive adapter is not deployed.")
+}
+
+// MarkValidQuery sets the condition that the query of the source complies with the cluster query policy.
+func (s *PrometheusSourceStatus) MarkValidQuery() {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionValidQuery)
+}
+
+// MarkInvalidQuery sets the condition that the query of the source does not comply with the cluster query
+// policy, the receive adapter is not deployed.
+func (s *PrometheusSourceStatus) MarkInvalidQuery(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionValidQuery, reason, messageFormat, messageA...)
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeployed, reason, "The receive adapter is not deployed.")
+}
+
 // MarkSink sets the condition that the source has a sink configured.
 func (s *PrometheusSourceStatus) MarkSink(uri *apis.URL) {
 	s.SinkURI = uri
@@ -81,6 +171,88 @@ func (s *PrometheusSourceStatus) MarkNoSink(reason, messageFormat string, messag
 	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionSinkProvided, reason, messageFormat, messageA...)
 }
 
+// MarkDeadLetterSinkResolved sets the condition that the dead letter sink of the source has been resolved.
+func (s *PrometheusSourceStatus) MarkDeadLetterSinkResolved(uri *apis.URL) {
+	s.DeadLetterSinkURI = uri
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionDeadLetterSinkResolved)
+}
+
+// MarkDeadLetterSinkNotResolved sets the condition that the dead letter sink of the source could not be
+// resolved, the receive adapter is not deployed.
+func (s *PrometheusSourceStatus) MarkDeadLetterSinkNotResolved(reason, messageFormat string, messageA ...interface{}) {
+	s.DeadLetterSinkURI = nil
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeadLetterSinkResolved, reason, messageFormat, messageA...)
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeployed, reason, "The receive adapter is not deployed.")
+}
+
+// MarkNoDeadLetterSink clears the dead letter sink of the source when it has none.
+func (s *PrometheusSourceStatus) MarkNoDeadLetterSink() {
+	s.DeadLetterSinkURI = nil
+	_ = PrometheusCondSet.Manage(s).ClearCondition(PrometheusConditionDeadLetterSinkResolved)
+}
+
+// MarkWithinRateLimit sets the condition that the source has not exceeded its rate limit lately.
+func (s *PrometheusSourceStatus) MarkWithinRateLimit() {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionWithinRateLimit)
+}
+
+// MarkRateLimited sets the condition that the receive adapter of the source suppressed events exceeding its
+// rate limit lately.
+func (s *PrometheusSourceStatus) MarkRateLimited(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionWithinRateLimit, reason, messageFormat, messageA...)
+}
+
+// MarkNoRateLimit clears the rate limit condition of the source when it has no rate limit.
+func (s *PrometheusSourceStatus) MarkNoRateLimit() {
+	_ = PrometheusCondSet.Manage(s).ClearCondition(PrometheusConditionWithinRateLimit)
+}
+
+// MarkWithinResultLimits sets the condition that the query results of the source have not exceeded its limits
+// lately.
+func (s *PrometheusSourceStatus) MarkWithinResultLimits() {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionWithinResultLimits)
+}
+
+// MarkResultLimitExceeded sets the condition that the receive adapter of the source read a query result exceeding
+// its limits lately.
+func (s *PrometheusSourceStatus) MarkResultLimitExceeded(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionWithinResultLimits, reason, messageFormat, messageA...)
+}
+
+// MarkNoResultLimits clears the result limits condition of the source when it has no limits.
+func (s *PrometheusSourceStatus) MarkNoResultLimits() {
+	_ = PrometheusCondSet.Manage(s).ClearCondition(PrometheusConditionWithinResultLimits)
+}
+
+// MarkSuspended sets the condition that the source is suspended, recording when it was suspended.
+func (s *PrometheusSourceStatus) MarkSuspended(now metav1.Time) {
+	if !s.IsSuspended() {
+		s.LastSuspendTime = &now
+	}
+	PrometheusCondSet.Manage(s).MarkTrueWithReason(PrometheusConditionSuspended, "Suspended", "The receive adapter is scaled down.")
+}
+
+// MarkResumed sets the condition that the source is not suspended, recording when it was resumed if it was
+// suspended.
+func (s *PrometheusSourceStatus) MarkResumed(now metav1.Time) {
+	if !s.IsSuspended() {
+		return
+	}
+	s.LastResumeTime = &now
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionSuspended, "Resumed", "The source was resumed.")
+}
+
+// IsSuspended returns true if the source is marked suspended.
+func (s *PrometheusSourceStatus) IsSuspended() bool {
+	c := PrometheusCondSet.Manage(s).GetCondition(PrometheusConditionSuspended)
+	return c != nil && c.IsTrue()
+}
+
+// MarkNotDeployed sets the condition that the receive adapter of the source is not deployed.
+func (s *PrometheusSourceStatus) MarkNotDeployed(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionDeployed, reason, messageFormat, messageA...)
+}
+
 // PropagateDeploymentAvailability uses the availability of the provided Deployment to determine if
 // PrometheusConditionDeployed should be marked as true or false.
 func (s *PrometheusSourceStatus) PropagateDeploymentAvailability(d *appsv1.Deployment) {
@@ -93,6 +265,49 @@ func (s *PrometheusSourceStatus) PropagateDeploymentAvailability(d *appsv1.Deplo
 	}
 }
 
+// PropagateCronJobStatus uses the CronJob running the receive adapter to determine if PrometheusConditionDeployed
+// should be marked as true, and the status of its last Job to mark PrometheusConditionLastRunSucceeded.
+func (s *PrometheusSourceStatus) PropagateCronJobStatus(cj *batchv1.CronJob) {
+	PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionDeployed)
+
+	status := &cj.Status
+	switch {
+	case status.LastScheduleTime == nil:
+		PrometheusCondSet.Manage(s).MarkUnknown(PrometheusConditionLastRunSucceeded, "NotScheduled",
+			"The CronJob '%s' has not run yet.", cj.Name)
+	case status.LastSuccessfulTime != nil && !status.LastSuccessfulTime.Before(status.LastScheduleTime):
+		PrometheusCondSet.Manage(s).MarkTrue(PrometheusConditionLastRunSucceeded)
+	case len(status.Active) > 0:
+		PrometheusCondSet.Manage(s).MarkUnknown(PrometheusConditionLastRunSucceeded, "Running",
+			"The Job of the CronJob '%s' scheduled at %s is running.", cj.Name, status.LastScheduleTime.UTC().Format(time.RFC3339))
+	default:
+		PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionLastRunSucceeded, "JobFailed",
+			"The Job of the CronJob '%s' scheduled at %s did not succeed.", cj.Name, status.LastScheduleTime.UTC().Format(time.RFC3339))
+	}
+}
+
+// MarkNoCronJob clears the last run condition of the source when its receive adapter does not run in a CronJob.
+func (s *PrometheusSourceStatus) MarkNoCronJob() {
+	_ = PrometheusCondSet.Manage(s).ClearCondition(PrometheusConditionLastRunSucceeded)
+}
+
+// MarkLeaderElected sets the condition that a replica of the receive adapter of the source holds its Lease.
+func (s *PrometheusSourceStatus) MarkLeaderElected(holder string) {
+	PrometheusCondSet.Manage(s).MarkTrueWithReason(PrometheusConditionLeaderElected, "Elected",
+		"The replica '%s' evaluates the query.", holder)
+}
+
+// MarkNoLeader sets the condition that no replica of the receive adapter of the source holds its Lease.
+func (s *PrometheusSourceStatus) MarkNoLeader(reason, messageFormat string, messageA ...interface{}) {
+	PrometheusCondSet.Manage(s).MarkFalse(PrometheusConditionLeaderElected, reason, messageFormat, messageA...)
+}
+
+// MarkNoLeaderElection clears the leader election condition of the source when its receive adapter does not run
+// in a Deployment, or is scaled down.
+func (s *PrometheusSourceStatus) MarkNoLeaderElection() {
+	_ = PrometheusCondSet.Manage(s).ClearCondition(PrometheusConditionLeaderElected)
+}
+
 // IsReady returns true if the resource is ready overall.
 func (s *PrometheusSourceStatus) IsReady() bool {
 	return PrometheusCondSet.Manage(s).IsHappy()
@@ -18,11 +18,14 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
@@ -112,6 +115,298 @@ func TestPrometheusGetCondition(t *testing.T) {
 			Type:   PrometheusConditionReady,
 			Status: corev1.ConditionTrue,
 		},
+	}, {
+		name: "mark server not allowed",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkServerNotAllowed("PolicyViolation", "server is not allowed")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:    PrometheusConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "PolicyViolation",
+			Message: "The receive adapter is not deployed.",
+		},
+	}, {
+		name: "mark server not resolved",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkServerNotResolved("NotFound", "services \"prometheus\" not found")
+			return s
+		}(),
+		condQuery: PrometheusConditionServerResolved,
+		want: &apis.Condition{
+			Type:    PrometheusConditionServerResolved,
+			Status:  corev1.ConditionFalse,
+			Reason:  "NotFound",
+			Message: `services "prometheus" not found`,
+		},
+	}, {
+		name: "mark dead letter sink not resolved",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkDeadLetterSinkNotResolved("NotFound", "services \"dls\" not found")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:    PrometheusConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "NotFound",
+			Message: "The receive adapter is not deployed.",
+		},
+	}, {
+		name: "mark dead letter sink resolved",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkDeadLetterSinkResolved(apis.HTTP("dls"))
+			return s
+		}(),
+		condQuery: PrometheusConditionDeadLetterSinkResolved,
+		want: &apis.Condition{
+			Type:   PrometheusConditionDeadLetterSinkResolved,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark no dead letter sink",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkDeadLetterSinkResolved(apis.HTTP("dls"))
+			s.MarkNoDeadLetterSink()
+			return s
+		}(),
+		condQuery: PrometheusConditionDeadLetterSinkResolved,
+	}, {
+		name: "mark no leader keeps ready",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkNoLeader("LeaseExpired", "The Lease has not been renewed since 2022-03-01T10:00:00Z.")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:   PrometheusConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark leader elected",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkNoLeader("LeaseNotFound", "")
+			s.MarkLeaderElected("adapter-7d9f")
+			return s
+		}(),
+		condQuery: PrometheusConditionLeaderElected,
+		want: &apis.Condition{
+			Type:    PrometheusConditionLeaderElected,
+			Status:  corev1.ConditionTrue,
+			Reason:  "Elected",
+			Message: "The replica 'adapter-7d9f' evaluates the query.",
+		},
+	}, {
+		name: "mark no leader election",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkLeaderElected("adapter-7d9f")
+			s.MarkNoLeaderElection()
+			return s
+		}(),
+		condQuery: PrometheusConditionLeaderElected,
+	}, {
+		name: "mark rate limited keeps ready",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkRateLimited("EventsSuppressed", "Suppressed 10 events")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:   PrometheusConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark rate limited",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkWithinRateLimit()
+			s.MarkRateLimited("EventsSuppressed", "Suppressed 10 events")
+			return s
+		}(),
+		condQuery: PrometheusConditionWithinRateLimit,
+		want: &apis.Condition{
+			Type:    PrometheusConditionWithinRateLimit,
+			Status:  corev1.ConditionFalse,
+			Reason:  "EventsSuppressed",
+			Message: "Suppressed 10 events",
+		},
+	}, {
+		name: "mark no rate limit",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkRateLimited("EventsSuppressed", "Suppressed 10 events")
+			s.MarkNoRateLimit()
+			return s
+		}(),
+		condQuery: PrometheusConditionWithinRateLimit,
+	}, {
+		name: "mark result limit exceeded keeps ready",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkResultLimitExceeded("LimitExceeded", "Query result exceeded the maxSeries limit of 10")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:   PrometheusConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark result limit exceeded",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkWithinResultLimits()
+			s.MarkResultLimitExceeded("LimitExceeded", "Query result exceeded the maxSeries limit of 10")
+			return s
+		}(),
+		condQuery: PrometheusConditionWithinResultLimits,
+		want: &apis.Condition{
+			Type:    PrometheusConditionWithinResultLimits,
+			Status:  corev1.ConditionFalse,
+			Reason:  "LimitExceeded",
+			Message: "Query result exceeded the maxSeries limit of 10",
+		},
+	}, {
+		name: "mark no result limits",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkResultLimitExceeded("LimitExceeded", "Query result exceeded the maxSeries limit of 10")
+			s.MarkNoResultLimits()
+			return s
+		}(),
+		condQuery: PrometheusConditionWithinResultLimits,
+	}, {
+		name: "mark not deployed",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.MarkNotDeployed("SharedAdapterNotFound", "The shared adapter was not found.")
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:    PrometheusConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "SharedAdapterNotFound",
+			Message: "The shared adapter was not found.",
+		},
+	}, {
+		name: "propagate cron job",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateCronJobStatus(&batchv1.CronJob{})
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:   PrometheusConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark no cron job",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.PropagateCronJobStatus(&batchv1.CronJob{})
+			s.MarkNoCronJob()
+			return s
+		}(),
+		condQuery: PrometheusConditionLastRunSucceeded,
+	}, {
+		name: "mark suspended keeps ready",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSink(apis.HTTP("example"))
+			s.PropagateDeploymentAvailability(availableDeployment)
+			s.MarkSuspended(metav1.Now())
+			return s
+		}(),
+		condQuery: PrometheusConditionReady,
+		want: &apis.Condition{
+			Type:   PrometheusConditionReady,
+			Status: corev1.ConditionTrue,
+		},
+	}, {
+		name: "mark suspended",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSuspended(metav1.Now())
+			return s
+		}(),
+		condQuery: PrometheusConditionSuspended,
+		want: &apis.Condition{
+			Type:    PrometheusConditionSuspended,
+			Status:  corev1.ConditionTrue,
+			Reason:  "Suspended",
+			Message: "The receive adapter is scaled down.",
+		},
+	}, {
+		name: "mark resumed",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkSuspended(metav1.Now())
+			s.MarkResumed(metav1.Now())
+			return s
+		}(),
+		condQuery: PrometheusConditionSuspended,
+		want: &apis.Condition{
+			Type:    PrometheusConditionSuspended,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Resumed",
+			Message: "The source was resumed.",
+		},
+	}, {
+		name: "mark resumed without suspension",
+		cs: func() *PrometheusSourceStatus {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.MarkResumed(metav1.Now())
+			return s
+		}(),
+		condQuery: PrometheusConditionSuspended,
 	}}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -266,3 +561,92 @@ func TestPrometheusInitializeConditions(t *testing.T) {
 		})
 	}
 }
+
+func TestPrometheusSuspendTimes(t *testing.T) {
+	suspended := metav1.Unix(100, 0)
+	resumed := metav1.Unix(200, 0)
+
+	s := &PrometheusSourceStatus{}
+	s.InitializeConditions()
+	s.MarkSuspended(suspended)
+	// The source stays suspended since the first time it was marked.
+	s.MarkSuspended(metav1.Unix(150, 0))
+	if !s.IsSuspended() {
+		t.Error("IsSuspended() = false, want true")
+	}
+	s.MarkResumed(resumed)
+	s.MarkResumed(metav1.Unix(250, 0))
+	if s.IsSuspended() {
+		t.Error("IsSuspended() = true, want false once resumed")
+	}
+	if !s.LastSuspendTime.Equal(&suspended) {
+		t.Errorf("LastSuspendTime = %v, want %v", s.LastSuspendTime, suspended)
+	}
+	if !s.LastResumeTime.Equal(&resumed) {
+		t.Errorf("LastResumeTime = %v, want %v", s.LastResumeTime, resumed)
+	}
+}
+
+func TestPrometheusPropagateCronJobStatus(t *testing.T) {
+	scheduled := metav1.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)
+	succeeded := metav1.Date(2022, time.March, 1, 10, 0, 5, 0, time.UTC)
+	earlier := metav1.Date(2022, time.March, 1, 9, 0, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		status batchv1.CronJobStatus
+		want   *apis.Condition
+	}{
+		"not scheduled": {
+			want: &apis.Condition{
+				Type:    PrometheusConditionLastRunSucceeded,
+				Status:  corev1.ConditionUnknown,
+				Reason:  "NotScheduled",
+				Message: "The CronJob 'ra' has not run yet.",
+			},
+		},
+		"succeeded": {
+			status: batchv1.CronJobStatus{LastScheduleTime: &scheduled, LastSuccessfulTime: &succeeded},
+			want: &apis.Condition{
+				Type:   PrometheusConditionLastRunSucceeded,
+				Status: corev1.ConditionTrue,
+			},
+		},
+		"running": {
+			status: batchv1.CronJobStatus{
+				LastScheduleTime:   &scheduled,
+				LastSuccessfulTime: &earlier,
+				Active:             []corev1.ObjectReference{{Name: "ra-27434280"}},
+			},
+			want: &apis.Condition{
+				Type:    PrometheusConditionLastRunSucceeded,
+				Status:  corev1.ConditionUnknown,
+				Reason:  "Running",
+				Message: "The Job of the CronJob 'ra' scheduled at 2022-03-01T10:00:00Z is running.",
+			},
+		},
+		"failed": {
+			status: batchv1.CronJobStatus{LastScheduleTime: &scheduled, LastSuccessfulTime: &earlier},
+			want: &apis.Condition{
+				Type:    PrometheusConditionLastRunSucceeded,
+				Status:  corev1.ConditionFalse,
+				Reason:  "JobFailed",
+				Message: "The Job of the CronJob 'ra' scheduled at 2022-03-01T10:00:00Z did not succeed.",
+			},
+		},
+	}
+	for n, test := range tests {
+		t.Run(n, func(t *testing.T) {
+			s := &PrometheusSourceStatus{}
+			s.InitializeConditions()
+			s.PropagateCronJobStatus(&batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "ra"},
+				Status:     test.status,
+			})
+			got := s.GetCondition(PrometheusConditionLastRunSucceeded)
+			ignoreTime := cmpopts.IgnoreFields(apis.Condition{}, "LastTransitionTime", "Severity")
+			if diff := cmp.Diff(test.want, got, ignoreTime); diff != "" {
+				t.Errorf("unexpected condition (-want, +got) = %v", diff)
+			}
+		})
+	}
+}
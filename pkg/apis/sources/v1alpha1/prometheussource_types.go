@@ -17,9 +17,12 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
 	"knative.dev/pkg/apis/duck"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/kmeta"
@@ -45,6 +48,9 @@ var _ resourcesemantics.GenericCRD = (*PrometheusSource)(nil)
 // Check that Prometheus source can be validated and can be defaulted.
 var _ runtime.Object = (*PrometheusSource)(nil)
 
+// Check that Prometheus source can be converted to and from other versions.
+var _ apis.Convertible = (*PrometheusSource)(nil)
+
 // Check that we can create OwnerReferences to a PrometheusSource.
 var _ kmeta.OwnerRefable = (*PrometheusSource)(nil)
 
@@ -57,19 +63,130 @@ var _ = duck.VerifyType(&PrometheusSource{}, &duckv1.Conditions{})
 const (
 	// PromQLPrometheusSourceEventType is the PrometheusSource PromQL CloudEvent type.
 	PromQLPrometheusSourceEventType = "dev.knative.prometheus.promql"
+
+	// PromQLErrorPrometheusSourceEventType is the CloudEvent type sent in place of
+	// a PromQL query result that could not be delivered.
+	PromQLErrorPrometheusSourceEventType = "dev.knative.prometheus.promql.error"
+
+	// PromQLNoDataPrometheusSourceEventType is the CloudEvent type sent when a
+	// PromQL query returns no series.
+	PromQLNoDataPrometheusSourceEventType = "dev.knative.prometheus.promql.nodata"
+
+	// PromQLDataReturnedPrometheusSourceEventType is the CloudEvent type sent
+	// when a PromQL query returns series again after a nodata event.
+	PromQLDataReturnedPrometheusSourceEventType = "dev.knative.prometheus.promql.datareturned"
+
+	// PromQLRateLimitedPrometheusSourceEventType is the CloudEvent type
+	// summing up the events suppressed by the rate limit of a source.
+	PromQLRateLimitedPrometheusSourceEventType = "dev.knative.prometheus.promql.ratelimited"
+)
+
+// MuteUntilAnnotation mutes a PrometheusSource until the RFC 3339 timestamp
+// it is set to, suppressing its events as a mute window would.
+const MuteUntilAnnotation = "prometheus.sources.knative.dev/mute-until"
+
+// OverflowPolicy is what the receive adapter does with a query result that
+// exceeds the PrometheusSource limits.
+type OverflowPolicy string
+
+const (
+	// OverflowPolicyTruncate sends the part of the result that fits within the
+	// limits, marked with the truncated extension attribute.
+	OverflowPolicyTruncate OverflowPolicy = "truncate"
+
+	// OverflowPolicySplit splits the result into as many events as needed for
+	// each of them to fit within the limits.
+	OverflowPolicySplit OverflowPolicy = "split"
+
+	// OverflowPolicyDrop drops the result and sends an error event instead.
+	OverflowPolicyDrop OverflowPolicy = "drop"
+)
+
+// EmptyResultPolicy is what the receive adapter does when a query returns no
+// series.
+type EmptyResultPolicy string
+
+const (
+	// EmptyResultPolicySend sends the empty query result.
+	EmptyResultPolicySend EmptyResultPolicy = "send"
+
+	// EmptyResultPolicySkip sends nothing.
+	EmptyResultPolicySkip EmptyResultPolicy = "skip"
+
+	// EmptyResultPolicyEmitNoData sends a nodata event once the query has
+	// returned no series for a number of consecutive evaluations, and a
+	// datareturned event when it returns series again.
+	EmptyResultPolicyEmitNoData EmptyResultPolicy = "emitNoData"
+)
+
+// RunMode is how the receive adapter of the source runs.
+type RunMode string
+
+const (
+	// RunModeDeployment runs the receive adapter in a Deployment, which
+	// evaluates the query on every tick of the schedule.
+	RunModeDeployment RunMode = "deployment"
+
+	// RunModeCronJob runs the receive adapter in the Jobs of a CronJob, each
+	// of which evaluates the query once and exits.
+	RunModeCronJob RunMode = "cronJob"
+
+	// RunModeShared runs the receive adapter in the shared, multi-tenant,
+	// adapter, which evaluates the queries of all such sources in one pod.
+	RunModeShared RunMode = "shared"
+)
+
+// MuteAction is what the receive adapter does while the source is muted.
+type MuteAction string
+
+const (
+	// MuteActionSuppress evaluates the query without sending the events, so
+	// that the nodata and datareturned transitions are sent correctly once
+	// the source is unmuted.
+	MuteActionSuppress MuteAction = "suppress"
+
+	// MuteActionSkip does not evaluate the query.
+	MuteActionSkip MuteAction = "skip"
+)
+
+// DataFormat is the encoding of the query results in the data of the events.
+type DataFormat string
+
+const (
+	// DataFormatPrometheusJSON is the Prometheus HTTP API query response.
+	DataFormatPrometheusJSON DataFormat = "prometheus-json"
+
+	// DataFormatPrometheusText is the Prometheus text exposition format.
+	DataFormatPrometheusText DataFormat = "prometheus-text"
+
+	// DataFormatOpenMetricsText is the OpenMetrics text format.
+	DataFormatOpenMetricsText DataFormat = "openmetrics-text"
+
+	// DataFormatCSV is one CSV row per sample, with a column per label.
+	DataFormatCSV DataFormat = "csv"
+
+	// DataFormatProtobuf is a Prometheus remote write request, made of
+	// prompb.TimeSeries messages.
+	DataFormatProtobuf DataFormat = "protobuf"
 )
 
 // PrometheusSourceSpec defines the desired state of PrometheusSource
 type PrometheusSourceSpec struct {
 	// ServiceAccountName holds the name of the Kubernetes service account
 	// as which the underlying K8s resources should be run. If unspecified
-	// this will default to the "default" service account for the namespace
-	// in which the PrometheusSource exists.
+	// the receive adapter runs as a service account of its own, created by
+	// the controller and named after the receive adapter.
 	// +optional
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
 	// ServerURL is the URL of the Prometheus server
-	ServerURL string `json:"serverURL"`
+	// +optional
+	ServerURL string `json:"serverURL,omitempty"`
+
+	// ServerRef is a reference to an Addressable or a Service resolving to
+	// the URL of the Prometheus server, in place of ServerURL.
+	// +optional
+	ServerRef *duckv1.KReference `json:"serverRef,omitempty"`
 
 	// PromQL is the Prometheus query for this source
 	PromQL string `json:"promQL"`
@@ -86,15 +203,338 @@ type PrometheusSourceSpec struct {
 	// A crontab-formatted schedule for running the PromQL query
 	Schedule string `json:"schedule"`
 
+	// TimeZone is the IANA time zone of the schedule, UTC by default.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Jitter is the maximum delay of the evaluations of the query after the
+	// ticks of the schedule. The delay of a source is derived from its UID,
+	// so that it is the same at every tick while the sources sharing a
+	// schedule do not query the Prometheus server at once. The query is
+	// still evaluated at the time of the tick.
+	// +optional
+	Jitter *metav1.Duration `json:"jitter,omitempty"`
+
+	// StartingDeadline is how late an evaluation whose tick was missed, while
+	// the previous evaluation was running, may still start. A missed
+	// evaluation later than that is skipped. By default the last missed
+	// evaluation starts as soon as possible.
+	// +optional
+	StartingDeadline *metav1.Duration `json:"startingDeadline,omitempty"`
+
+	// Suspend stops the evaluations of the query, the receive adapter is
+	// scaled down, without deleting the source. Once resumed, the ticks
+	// missed while it was suspended are caught up like those missed while an
+	// evaluation was running, subject to the starting deadline.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// RunMode is how the receive adapter runs, in a Deployment by default.
+	// A cronJob receive adapter only runs to evaluate the query, which suits
+	// sources with a low frequency schedule, but it does not support the
+	// features keeping state between evaluations. A shared receive adapter
+	// runs in the multi-tenant adapter, without a pod of its own.
+	// +optional
+	RunMode RunMode `json:"runMode,omitempty"`
+
+	// Replicas is the number of replicas of the receive adapter Deployment,
+	// 1 by default. The replicas elect a leader through a Lease, which alone
+	// evaluates the query, and a new leader resumes the evaluations after the
+	// last tick its predecessor evaluated.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+
 	// Query resolution step width in duration format or float number of seconds.
 	// Prometheus duration strings are of the form [0-9]+[smhdwy].
 	// +optional
 	Step string `json:"step,omitempty"`
 
+	// AlignToStep aligns the start and end of the range queries to multiples
+	// of the step, so that consecutive queries return every step once.
+	// +optional
+	AlignToStep bool `json:"alignToStep,omitempty"`
+
+	// EvaluationDelay shifts the evaluation time of the query back from the
+	// tick of the schedule, so that the samples ingested late, such as those
+	// sent with remote write, are part of the query results.
+	// +optional
+	EvaluationDelay *metav1.Duration `json:"evaluationDelay,omitempty"`
+
+	// QueryTimeout is the evaluation timeout of the query, in Prometheus
+	// duration format. The receive adapter also gives up on the HTTP request
+	// after this duration.
+	// +optional
+	QueryTimeout string `json:"queryTimeout,omitempty"`
+
 	// Sink is a reference to an object that will resolve to a host
 	// name to use as the sink.
 	// +optional
 	Sink *duckv1.Destination `json:"sink,omitempty"`
+
+	// CloudEventOverrides defines overrides to control the output format and
+	// modifications of the events sent to the sink.
+	// +optional
+	CloudEventOverrides *duckv1.CloudEventOverrides `json:"ceOverrides,omitempty"`
+
+	// Delivery configures the retries of the events the sink does not accept,
+	// and the dead letter sink receiving them once the retries are exhausted.
+	// +optional
+	Delivery *eventingduckv1.DeliverySpec `json:"delivery,omitempty"`
+
+	// Outbox buffers on disk the events that could not be delivered while the
+	// sink is unavailable, and replays them in order once it recovers. The
+	// oldest events are dropped when the outbox is full.
+	// +optional
+	Outbox *PrometheusSourceOutbox `json:"outbox,omitempty"`
+
+	// RateLimit bounds the rate of the events sent to the sink. The events
+	// exceeding it are suppressed and summed up in a ratelimited event.
+	// +optional
+	RateLimit *PrometheusSourceRateLimit `json:"rateLimit,omitempty"`
+
+	// MuteWindows are the planned maintenance windows during which the
+	// source sends no events. The prometheus.sources.knative.dev/mute-until
+	// annotation mutes the source until an RFC 3339 timestamp in addition.
+	// +optional
+	MuteWindows []PrometheusSourceMuteWindow `json:"muteWindows,omitempty"`
+
+	// EventType is the CloudEvent type of the query results, by default
+	// dev.knative.prometheus.promql. The events sent in place of a query
+	// result that could not be delivered have the same type suffixed with
+	// ".error".
+	// +optional
+	EventType string `json:"eventType,omitempty"`
+
+	// EventSource is the CloudEvent source attribute, a URI reference, by
+	// default namespace/name of the PrometheusSource.
+	// +optional
+	EventSource string `json:"eventSource,omitempty"`
+
+	// Subject is a Go text/template of the CloudEvent subject attribute,
+	// executed with the name and namespace of the PrometheusSource as .Name
+	// and .Namespace and with the labels shared by every series of the event
+	// as .Labels. The subject is the name of the PrometheusSource by default.
+	// +optional
+	Subject string `json:"subject,omitempty"`
+
+	// LabelExtensions promotes series labels to CloudEvent extension
+	// attributes, so that Triggers can filter on them. Only the labels shared
+	// by every series of an event are promoted, every label of the series
+	// when SeriesPerEvent is 1.
+	// +optional
+	LabelExtensions []LabelExtension `json:"labelExtensions,omitempty"`
+
+	// PartitionKeyLabels are the series labels whose values, joined with "/",
+	// are sent as the partitionkey extension attribute.
+	// +optional
+	PartitionKeyLabels []string `json:"partitionKeyLabels,omitempty"`
+
+	// Template renders the data of the events from the query results, in
+	// place of the JSON query response.
+	// +optional
+	Template *PrometheusSourceTemplate `json:"template,omitempty"`
+
+	// RelabelConfigs relabel the series of the query results before they are
+	// sent, with the semantics of the metric_relabel_configs of Prometheus.
+	// Series dropped by a keep or drop action are not sent.
+	// +optional
+	RelabelConfigs []RelabelConfig `json:"relabelConfigs,omitempty"`
+
+	// DataFormat is the encoding of the query results in the data of the
+	// events: prometheus-json (the default, the Prometheus HTTP API response),
+	// prometheus-text, openmetrics-text, csv or protobuf (a Prometheus remote
+	// write request).
+	// +optional
+	DataFormat DataFormat `json:"dataFormat,omitempty"`
+
+	// Limits bounds the size of the query results sent as events.
+	// +optional
+	Limits *PrometheusSourceLimits `json:"limits,omitempty"`
+
+	// SeriesPerEvent is the maximum number of series of a query result sent
+	// in one event. 1 sends every series in its own event, zero (the default)
+	// sends as many series as fit in about 1 MiB. Events are sent while the
+	// Prometheus response is being read, so that the memory the receive
+	// adapter holds a result in is bounded by the size of one event.
+	// +optional
+	SeriesPerEvent int64 `json:"seriesPerEvent,omitempty"`
+
+	// OnEmptyResult is what the receive adapter does when a query returns no
+	// series: send (the default), skip or emitNoData.
+	// +optional
+	OnEmptyResult EmptyResultPolicy `json:"onEmptyResult,omitempty"`
+
+	// NoDataThreshold is the number of consecutive evaluations returning no
+	// series after which the nodata event is sent, 1 by default. It is only
+	// used by the emitNoData policy.
+	// +optional
+	NoDataThreshold int64 `json:"noDataThreshold,omitempty"`
+
+	// Resources are the compute resources of the receive adapter.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// LabelExtension promotes a series label to a CloudEvent extension attribute.
+type LabelExtension struct {
+	// Label is the name of the series label.
+	Label string `json:"label"`
+
+	// Name is the name of the extension attribute, by default the name of
+	// the label in lowercase without the characters CloudEvents do not
+	// allow in attribute names.
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// RelabelConfig is a Prometheus relabeling step applied to the series of the
+// query results.
+type RelabelConfig struct {
+	// SourceLabels are the labels whose values, joined with the separator,
+	// are matched against the regex.
+	// +optional
+	SourceLabels []string `json:"sourceLabels,omitempty"`
+
+	// Separator joins the values of the source labels, by default ";".
+	// +optional
+	Separator *string `json:"separator,omitempty"`
+
+	// Regex is the anchored regular expression matched against the joined
+	// values of the source labels, or against the label names for the
+	// labelmap, labeldrop and labelkeep actions, by default "(.*)".
+	// +optional
+	Regex *string `json:"regex,omitempty"`
+
+	// Modulus is the modulus of the hash of the joined values of the source
+	// labels for the hashmod action.
+	// +optional
+	Modulus uint64 `json:"modulus,omitempty"`
+
+	// TargetLabel is the label written by the replace and hashmod actions.
+	// +optional
+	TargetLabel string `json:"targetLabel,omitempty"`
+
+	// Replacement is the value written by the replace action, or the label
+	// name written by the labelmap action, in which the regex capture groups
+	// may be referred to as $1. By default "$1".
+	// +optional
+	Replacement *string `json:"replacement,omitempty"`
+
+	// Action is one of replace (the default), keep, drop, labeldrop,
+	// labelkeep, labelmap and hashmod.
+	// +optional
+	Action string `json:"action,omitempty"`
+}
+
+// PrometheusSourceTemplate renders the data of the events from the query
+// results. Exactly one of Text and JSON is set.
+type PrometheusSourceTemplate struct {
+	// Text is a Go text/template executed with the query result.
+	// +optional
+	Text string `json:"text,omitempty"`
+
+	// JSON is a JSON document whose string values are Go text/templates
+	// executed with the query result.
+	// +optional
+	JSON string `json:"json,omitempty"`
+
+	// DataContentType is the content type of the rendered data, by default
+	// text/plain for a text template and application/json for a JSON
+	// template.
+	// +optional
+	DataContentType string `json:"dataContentType,omitempty"`
+}
+
+// PrometheusSourceOutbox is the on-disk queue of the events the sink could not
+// receive. A zero limit is unbounded, except for MaxBytes.
+type PrometheusSourceOutbox struct {
+	// VolumeClaimName is the name of the PersistentVolumeClaim holding the
+	// outbox, so that it outlives the receive adapter pod. The outbox is an
+	// emptyDir volume of the pod by default.
+	// +optional
+	VolumeClaimName string `json:"volumeClaimName,omitempty"`
+
+	// MaxEvents is the maximum number of events in the outbox.
+	// +optional
+	MaxEvents int64 `json:"maxEvents,omitempty"`
+
+	// MaxBytes is the maximum size in bytes of the events in the outbox,
+	// 64Mi by default.
+	// +optional
+	MaxBytes int64 `json:"maxBytes,omitempty"`
+
+	// MaxAge is how long an event is kept in the outbox before it is dropped.
+	// +optional
+	MaxAge *metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// PrometheusSourceRateLimit bounds the rate of the events sent to the sink,
+// with a token bucket refilled with Events tokens every Interval.
+type PrometheusSourceRateLimit struct {
+	// Events is the number of events allowed per interval.
+	Events int64 `json:"events"`
+
+	// Interval is the period the events are counted over, 1m by default.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Burst is the number of events that may be sent at once, by default
+	// Events.
+	// +optional
+	Burst int64 `json:"burst,omitempty"`
+}
+
+// PrometheusSourceMuteWindow is a window during which the source is muted,
+// either recurring, starting on Schedule and lasting Duration, or absolute,
+// from Start to End.
+type PrometheusSourceMuteWindow struct {
+	// Schedule is a crontab-formatted schedule of the starts of the window.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is how long the window lasts from each start of the schedule.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// TimeZone is the IANA time zone the schedule is in, UTC by default.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Start is the start of an absolute window.
+	// +optional
+	Start *metav1.Time `json:"start,omitempty"`
+
+	// End is the end of an absolute window.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+
+	// Action is what the receive adapter does during the window: suppress
+	// (the default) or skip.
+	// +optional
+	Action MuteAction `json:"action,omitempty"`
+}
+
+// PrometheusSourceLimits bounds the size of the query results sent as events.
+// A zero limit is unbounded.
+type PrometheusSourceLimits struct {
+	// MaxSeries is the maximum number of series in one event.
+	// +optional
+	MaxSeries int64 `json:"maxSeries,omitempty"`
+
+	// MaxSamples is the maximum number of samples in one event.
+	// +optional
+	MaxSamples int64 `json:"maxSamples,omitempty"`
+
+	// MaxResponseBytes is the maximum size in bytes of the query result in
+	// one event. Unless the result is split, the receive adapter stops reading
+	// the Prometheus response once it exceeds this size.
+	// +optional
+	MaxResponseBytes int64 `json:"maxResponseBytes,omitempty"`
+
+	// OverflowPolicy is what to do with a query result exceeding the limits:
+	// truncate (the default), split or drop.
+	// +optional
+	OverflowPolicy OverflowPolicy `json:"overflowPolicy,omitempty"`
 }
 
 // GetGroupVersionKind returns the GroupVersionKind.
@@ -117,6 +557,27 @@ type PrometheusSourceStatus struct {
 	// * SinkURI - the current active sink URI that has been configured for the
 	//   Source.
 	duckv1.SourceStatus `json:",inline"`
+
+	// DeliveryStatus holds the URI the dead letter sink resolved to.
+	eventingduckv1.DeliveryStatus `json:",inline"`
+
+	// LastSuspendTime is when the source was last suspended.
+	// +optional
+	LastSuspendTime *metav1.Time `json:"lastSuspendTime,omitempty"`
+
+	// LastResumeTime is when the source was last resumed.
+	// +optional
+	LastResumeTime *metav1.Time `json:"lastResumeTime,omitempty"`
+
+	// ServerURL is the URL of the Prometheus server the receive adapter
+	// queries, the server reference of the source resolved if it has one.
+	// +optional
+	ServerURL *apis.URL `json:"serverURL,omitempty"`
+
+	// PromQL is the query the receive adapter evaluates, restricted to the
+	// namespace of the source when namespace scoping is enforced.
+	// +optional
+	PromQL string `json:"promQL,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -32,7 +32,7 @@ func TestPrometheusSourceSourceGetStatus(t *testing.T) {
 	status := &duckv1.Status{}
 	config := PrometheusSource{
 		Status: PrometheusSourceStatus{
-			duckv1.SourceStatus{Status: *status},
+			SourceStatus: duckv1.SourceStatus{Status: *status},
 		},
 	}
 
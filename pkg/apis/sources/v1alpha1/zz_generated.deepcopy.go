@@ -22,10 +22,46 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
-	v1 "knative.dev/pkg/apis/duck/v1"
+	apisduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	apis "knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldPaths) DeepCopyInto(out *FieldPaths) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldPaths.
+func (in *FieldPaths) DeepCopy() *FieldPaths {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldPaths)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelExtension) DeepCopyInto(out *LabelExtension) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelExtension.
+func (in *LabelExtension) DeepCopy() *LabelExtension {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelExtension)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusSource) DeepCopyInto(out *PrometheusSource) {
 	*out = *in
@@ -54,6 +90,22 @@ func (in *PrometheusSource) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSourceLimits) DeepCopyInto(out *PrometheusSourceLimits) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSourceLimits.
+func (in *PrometheusSourceLimits) DeepCopy() *PrometheusSourceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSourceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusSourceList) DeepCopyInto(out *PrometheusSourceList) {
 	*out = *in
@@ -87,12 +139,167 @@ func (in *PrometheusSourceList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSourceMuteWindow) DeepCopyInto(out *PrometheusSourceMuteWindow) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSourceMuteWindow.
+func (in *PrometheusSourceMuteWindow) DeepCopy() *PrometheusSourceMuteWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSourceMuteWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSourceOutbox) DeepCopyInto(out *PrometheusSourceOutbox) {
+	*out = *in
+	if in.MaxAge != nil {
+		in, out := &in.MaxAge, &out.MaxAge
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSourceOutbox.
+func (in *PrometheusSourceOutbox) DeepCopy() *PrometheusSourceOutbox {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSourceOutbox)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSourceRateLimit) DeepCopyInto(out *PrometheusSourceRateLimit) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSourceRateLimit.
+func (in *PrometheusSourceRateLimit) DeepCopy() *PrometheusSourceRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSourceRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PrometheusSourceSpec) DeepCopyInto(out *PrometheusSourceSpec) {
 	*out = *in
+	if in.ServerRef != nil {
+		in, out := &in.ServerRef, &out.ServerRef
+		*out = new(duckv1.KReference)
+		**out = **in
+	}
+	if in.Jitter != nil {
+		in, out := &in.Jitter, &out.Jitter
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.StartingDeadline != nil {
+		in, out := &in.StartingDeadline, &out.StartingDeadline
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.EvaluationDelay != nil {
+		in, out := &in.EvaluationDelay, &out.EvaluationDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	if in.Sink != nil {
 		in, out := &in.Sink, &out.Sink
-		*out = new(v1.Destination)
+		*out = new(duckv1.Destination)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloudEventOverrides != nil {
+		in, out := &in.CloudEventOverrides, &out.CloudEventOverrides
+		*out = new(duckv1.CloudEventOverrides)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Delivery != nil {
+		in, out := &in.Delivery, &out.Delivery
+		*out = new(apisduckv1.DeliverySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Outbox != nil {
+		in, out := &in.Outbox, &out.Outbox
+		*out = new(PrometheusSourceOutbox)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(PrometheusSourceRateLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MuteWindows != nil {
+		in, out := &in.MuteWindows, &out.MuteWindows
+		*out = make([]PrometheusSourceMuteWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LabelExtensions != nil {
+		in, out := &in.LabelExtensions, &out.LabelExtensions
+		*out = make([]LabelExtension, len(*in))
+		copy(*out, *in)
+	}
+	if in.PartitionKeyLabels != nil {
+		in, out := &in.PartitionKeyLabels, &out.PartitionKeyLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(PrometheusSourceTemplate)
+		**out = **in
+	}
+	if in.RelabelConfigs != nil {
+		in, out := &in.RelabelConfigs, &out.RelabelConfigs
+		*out = make([]RelabelConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(PrometheusSourceLimits)
+		**out = **in
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(corev1.ResourceRequirements)
 		(*in).DeepCopyInto(*out)
 	}
 	return
@@ -112,6 +319,20 @@ func (in *PrometheusSourceSpec) DeepCopy() *PrometheusSourceSpec {
 func (in *PrometheusSourceStatus) DeepCopyInto(out *PrometheusSourceStatus) {
 	*out = *in
 	in.SourceStatus.DeepCopyInto(&out.SourceStatus)
+	in.DeliveryStatus.DeepCopyInto(&out.DeliveryStatus)
+	if in.LastSuspendTime != nil {
+		in, out := &in.LastSuspendTime, &out.LastSuspendTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastResumeTime != nil {
+		in, out := &in.LastResumeTime, &out.LastResumeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ServerURL != nil {
+		in, out := &in.ServerURL, &out.ServerURL
+		*out = new(apis.URL)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -124,3 +345,55 @@ func (in *PrometheusSourceStatus) DeepCopy() *PrometheusSourceStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSourceTemplate) DeepCopyInto(out *PrometheusSourceTemplate) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSourceTemplate.
+func (in *PrometheusSourceTemplate) DeepCopy() *PrometheusSourceTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSourceTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelabelConfig) DeepCopyInto(out *RelabelConfig) {
+	*out = *in
+	if in.SourceLabels != nil {
+		in, out := &in.SourceLabels, &out.SourceLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Separator != nil {
+		in, out := &in.Separator, &out.Separator
+		*out = new(string)
+		**out = **in
+	}
+	if in.Regex != nil {
+		in, out := &in.Regex, &out.Regex
+		*out = new(string)
+		**out = **in
+	}
+	if in.Replacement != nil {
+		in, out := &in.Replacement, &out.Replacement
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelabelConfig.
+func (in *RelabelConfig) DeepCopy() *RelabelConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RelabelConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// The annotations holding the v1alpha1 values that v1beta1 does not represent
+// exactly, such as a step in float seconds or an invalid duration, so that
+// converting back to v1alpha1 restores them. They are ignored once the v1beta1
+// field they come from changes.
+const (
+	serverURLAnnotation    = "prometheus.sources.knative.dev/v1alpha1-server-url"
+	stepAnnotation         = "prometheus.sources.knative.dev/v1alpha1-step"
+	queryTimeoutAnnotation = "prometheus.sources.knative.dev/v1alpha1-query-timeout"
+)
+
+// ConvertTo implements apis.Convertible.
+// Converts source from v1beta1.PrometheusSource into another version.
+func (source *PrometheusSource) ConvertTo(ctx context.Context, to apis.Convertible) error {
+	switch sink := to.(type) {
+	case *v1alpha1.PrometheusSource:
+		sink.ObjectMeta = *source.ObjectMeta.DeepCopy()
+		annotations := sink.Annotations
+
+		spec := &source.Spec
+		sink.Spec = v1alpha1.PrometheusSourceSpec{
+			ServiceAccountName: spec.ServiceAccountName,
+			ServerURL: restore(annotations, serverURLAnnotation, spec.Server.URL.String(), func(original string) bool {
+				u, err := apis.ParseURL(original)
+				return err == nil && u.String() == spec.Server.URL.String() || err != nil && spec.Server.URL == nil
+			}),
+			ServerRef: spec.Server.Ref.DeepCopy(),
+			PromQL:    spec.Query.PromQL,
+			Schedule:  spec.Schedule,
+			Step: restore(annotations, stepAnnotation, formatStep(spec.Query.Range), func(original string) bool {
+				d, err := v1alpha1.ParseStep(original)
+				return err == nil && spec.Query.Range != nil && spec.Query.Range.Step.Duration == time.Duration(d) ||
+					err != nil && spec.Query.Range == nil
+			}),
+			QueryTimeout: restore(annotations, queryTimeoutAnnotation, formatDuration(spec.Query.Timeout), func(original string) bool {
+				d, err := model.ParseDuration(original)
+				return err == nil && spec.Query.Timeout != nil && spec.Query.Timeout.Duration == time.Duration(d) ||
+					err != nil && spec.Query.Timeout == nil
+			}),
+			CloudEventOverrides: spec.CloudEventOverrides.DeepCopy(),
+			SeriesPerEvent:      spec.SeriesPerEvent,
+			Resources:           spec.Resources.DeepCopy(),
+		}
+		sink.Spec.TimeZone = spec.TimeZone
+		sink.Spec.Jitter = spec.Jitter.DeepCopy()
+		sink.Spec.StartingDeadline = spec.StartingDeadline.DeepCopy()
+		sink.Spec.Suspend = spec.Suspend
+		sink.Spec.RunMode = v1alpha1.RunMode(spec.RunMode)
+		if spec.Replicas != nil {
+			replicas := *spec.Replicas
+			sink.Spec.Replicas = &replicas
+		}
+		sink.Spec.AlignToStep = spec.Query.Range != nil && spec.Query.Range.AlignToStep
+		sink.Spec.EvaluationDelay = spec.Query.EvaluationDelay.DeepCopy()
+		if spec.Server.Auth != nil {
+			sink.Spec.AuthTokenFile = spec.Server.Auth.TokenFile
+		}
+		if spec.Server.TLS != nil {
+			sink.Spec.CACertConfigMap = spec.Server.TLS.CACertConfigMap
+		}
+		if spec.Sink != (duckv1.Destination{}) {
+			sink.Spec.Sink = spec.Sink.DeepCopy()
+		}
+		if spec.Event != nil {
+			sink.Spec.EventType = spec.Event.Type
+			sink.Spec.EventSource = spec.Event.Source
+			sink.Spec.Subject = spec.Event.Subject
+			sink.Spec.LabelExtensions = convertLabelExtensionsTo(spec.Event.LabelExtensions)
+			if spec.Event.PartitionKeyLabels != nil {
+				sink.Spec.PartitionKeyLabels = append([]string{}, spec.Event.PartitionKeyLabels...)
+			}
+		}
+		if spec.Template != nil {
+			sink.Spec.Template = (*v1alpha1.PrometheusSourceTemplate)(spec.Template.DeepCopy())
+		}
+		sink.Spec.Delivery = spec.Delivery.DeepCopy()
+		if spec.Outbox != nil {
+			sink.Spec.Outbox = (*v1alpha1.PrometheusSourceOutbox)(spec.Outbox.DeepCopy())
+		}
+		if spec.RateLimit != nil {
+			sink.Spec.RateLimit = (*v1alpha1.PrometheusSourceRateLimit)(spec.RateLimit.DeepCopy())
+		}
+		sink.Spec.MuteWindows = convertMuteWindowsTo(spec.MuteWindows)
+		sink.Spec.RelabelConfigs = convertRelabelConfigsTo(spec.RelabelConfigs)
+		sink.Spec.DataFormat = v1alpha1.DataFormat(spec.DataFormat)
+		sink.Spec.OnEmptyResult = v1alpha1.EmptyResultPolicy(spec.OnEmptyResult)
+		sink.Spec.NoDataThreshold = spec.NoDataThreshold
+		if spec.Limits != nil {
+			sink.Spec.Limits = &v1alpha1.PrometheusSourceLimits{
+				MaxSeries:        spec.Limits.MaxSeries,
+				MaxSamples:       spec.Limits.MaxSamples,
+				MaxResponseBytes: spec.Limits.MaxResponseBytes,
+				OverflowPolicy:   v1alpha1.OverflowPolicy(spec.Limits.OverflowPolicy),
+			}
+		}
+		if len(annotations) == 0 {
+			sink.Annotations = nil
+		}
+
+		sink.Status.SourceStatus = *source.Status.SourceStatus.DeepCopy()
+		sink.Status.DeliveryStatus = *source.Status.DeliveryStatus.DeepCopy()
+		sink.Status.LastSuspendTime = source.Status.LastSuspendTime.DeepCopy()
+		sink.Status.LastResumeTime = source.Status.LastResumeTime.DeepCopy()
+		sink.Status.ServerURL = source.Status.ServerURL.DeepCopy()
+		sink.Status.PromQL = source.Status.PromQL
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", sink)
+	}
+}
+
+// ConvertFrom implements apis.Convertible.
+// Converts obj from another version into v1beta1.PrometheusSource.
+func (sink *PrometheusSource) ConvertFrom(ctx context.Context, from apis.Convertible) error {
+	switch source := from.(type) {
+	case *v1alpha1.PrometheusSource:
+		sink.ObjectMeta = *source.ObjectMeta.DeepCopy()
+		if sink.Annotations == nil {
+			sink.Annotations = map[string]string{}
+		}
+		annotations := sink.Annotations
+
+		spec := &source.Spec
+		sink.Spec = PrometheusSourceSpec{
+			SourceSpec: duckv1.SourceSpec{
+				CloudEventOverrides: spec.CloudEventOverrides.DeepCopy(),
+			},
+			ServiceAccountName: spec.ServiceAccountName,
+			Server: PrometheusServer{
+				Ref: spec.ServerRef.DeepCopy(),
+			},
+			Query: PrometheusQuery{
+				PromQL: spec.PromQL,
+			},
+			Schedule:       spec.Schedule,
+			SeriesPerEvent: spec.SeriesPerEvent,
+			Resources:      spec.Resources.DeepCopy(),
+		}
+		sink.Spec.TimeZone = spec.TimeZone
+		sink.Spec.Jitter = spec.Jitter.DeepCopy()
+		sink.Spec.StartingDeadline = spec.StartingDeadline.DeepCopy()
+		sink.Spec.Suspend = spec.Suspend
+		sink.Spec.RunMode = RunMode(spec.RunMode)
+		if spec.Replicas != nil {
+			replicas := *spec.Replicas
+			sink.Spec.Replicas = &replicas
+		}
+		if u, err := apis.ParseURL(spec.ServerURL); err == nil {
+			sink.Spec.Server.URL = u
+		}
+		keep(annotations, serverURLAnnotation, spec.ServerURL, sink.Spec.Server.URL.String())
+		if spec.Step != "" {
+			if d, err := v1alpha1.ParseStep(spec.Step); err == nil {
+				sink.Spec.Query.Range = &PrometheusQueryRange{Step: metav1.Duration{Duration: time.Duration(d)}}
+			}
+		}
+		keep(annotations, stepAnnotation, spec.Step, formatStep(sink.Spec.Query.Range))
+		if sink.Spec.Query.Range != nil {
+			sink.Spec.Query.Range.AlignToStep = spec.AlignToStep
+		}
+		sink.Spec.Query.EvaluationDelay = spec.EvaluationDelay.DeepCopy()
+		if spec.QueryTimeout != "" {
+			if d, err := model.ParseDuration(spec.QueryTimeout); err == nil {
+				sink.Spec.Query.Timeout = &metav1.Duration{Duration: time.Duration(d)}
+			}
+		}
+		keep(annotations, queryTimeoutAnnotation, spec.QueryTimeout, formatDuration(sink.Spec.Query.Timeout))
+		if spec.AuthTokenFile != "" {
+			sink.Spec.Server.Auth = &PrometheusServerAuth{TokenFile: spec.AuthTokenFile}
+		}
+		if spec.CACertConfigMap != "" {
+			sink.Spec.Server.TLS = &PrometheusServerTLS{CACertConfigMap: spec.CACertConfigMap}
+		}
+		if spec.Sink != nil {
+			sink.Spec.Sink = *spec.Sink.DeepCopy()
+		}
+		if spec.EventType != "" || spec.EventSource != "" || spec.Subject != "" ||
+			spec.LabelExtensions != nil || spec.PartitionKeyLabels != nil {
+			sink.Spec.Event = &PrometheusSourceEvent{
+				Type:            spec.EventType,
+				Source:          spec.EventSource,
+				Subject:         spec.Subject,
+				LabelExtensions: convertLabelExtensionsFrom(spec.LabelExtensions),
+			}
+			if spec.PartitionKeyLabels != nil {
+				sink.Spec.Event.PartitionKeyLabels = append([]string{}, spec.PartitionKeyLabels...)
+			}
+		}
+		if spec.Template != nil {
+			sink.Spec.Template = (*PrometheusSourceTemplate)(spec.Template.DeepCopy())
+		}
+		sink.Spec.Delivery = spec.Delivery.DeepCopy()
+		if spec.Outbox != nil {
+			sink.Spec.Outbox = (*PrometheusSourceOutbox)(spec.Outbox.DeepCopy())
+		}
+		if spec.RateLimit != nil {
+			sink.Spec.RateLimit = (*PrometheusSourceRateLimit)(spec.RateLimit.DeepCopy())
+		}
+		sink.Spec.MuteWindows = convertMuteWindowsFrom(spec.MuteWindows)
+		sink.Spec.RelabelConfigs = convertRelabelConfigsFrom(spec.RelabelConfigs)
+		sink.Spec.DataFormat = DataFormat(spec.DataFormat)
+		sink.Spec.OnEmptyResult = EmptyResultPolicy(spec.OnEmptyResult)
+		sink.Spec.NoDataThreshold = spec.NoDataThreshold
+		if spec.Limits != nil {
+			sink.Spec.Limits = &PrometheusSourceLimits{
+				MaxSeries:        spec.Limits.MaxSeries,
+				MaxSamples:       spec.Limits.MaxSamples,
+				MaxResponseBytes: spec.Limits.MaxResponseBytes,
+				OverflowPolicy:   OverflowPolicy(spec.Limits.OverflowPolicy),
+			}
+		}
+		if len(annotations) == 0 {
+			sink.Annotations = nil
+		}
+
+		sink.Status.SourceStatus = *source.Status.SourceStatus.DeepCopy()
+		sink.Status.DeliveryStatus = *source.Status.DeliveryStatus.DeepCopy()
+		sink.Status.LastSuspendTime = source.Status.LastSuspendTime.DeepCopy()
+		sink.Status.LastResumeTime = source.Status.LastResumeTime.DeepCopy()
+		sink.Status.ServerURL = source.Status.ServerURL.DeepCopy()
+		sink.Status.PromQL = source.Status.PromQL
+		return nil
+	default:
+		return fmt.Errorf("unknown version, got: %T", source)
+	}
+}
+
+// convertLabelExtensionsTo converts label extensions to v1alpha1.
+func convertLabelExtensionsTo(extensions []LabelExtension) []v1alpha1.LabelExtension {
+	if extensions == nil {
+		return nil
+	}
+	ret := make([]v1alpha1.LabelExtension, len(extensions))
+	for i, e := range extensions {
+		ret[i] = v1alpha1.LabelExtension{Label: e.Label, Name: e.Name}
+	}
+	return ret
+}
+
+// convertLabelExtensionsFrom converts label extensions from v1alpha1.
+func convertLabelExtensionsFrom(extensions []v1alpha1.LabelExtension) []LabelExtension {
+	if extensions == nil {
+		return nil
+	}
+	ret := make([]LabelExtension, len(extensions))
+	for i, e := range extensions {
+		ret[i] = LabelExtension{Label: e.Label, Name: e.Name}
+	}
+	return ret
+}
+
+// convertRelabelConfigsTo converts relabel configs to v1alpha1.
+func convertRelabelConfigsTo(configs []RelabelConfig) []v1alpha1.RelabelConfig {
+	if configs == nil {
+		return nil
+	}
+	ret := make([]v1alpha1.RelabelConfig, len(configs))
+	for i := range configs {
+		ret[i] = v1alpha1.RelabelConfig(*configs[i].DeepCopy())
+	}
+	return ret
+}
+
+// convertRelabelConfigsFrom converts relabel configs from v1alpha1.
+func convertRelabelConfigsFrom(configs []v1alpha1.RelabelConfig) []RelabelConfig {
+	if configs == nil {
+		return nil
+	}
+	ret := make([]RelabelConfig, len(configs))
+	for i := range configs {
+		ret[i] = RelabelConfig(*configs[i].DeepCopy())
+	}
+	return ret
+}
+
+// convertMuteWindowsTo converts mute windows to v1alpha1.
+func convertMuteWindowsTo(windows []PrometheusSourceMuteWindow) []v1alpha1.PrometheusSourceMuteWindow {
+	if windows == nil {
+		return nil
+	}
+	ret := make([]v1alpha1.PrometheusSourceMuteWindow, len(windows))
+	for i := range windows {
+		w := windows[i].DeepCopy()
+		ret[i] = v1alpha1.PrometheusSourceMuteWindow{
+			Schedule: w.Schedule,
+			Duration: w.Duration,
+			TimeZone: w.TimeZone,
+			Start:    w.Start,
+			End:      w.End,
+			Action:   v1alpha1.MuteAction(w.Action),
+		}
+	}
+	return ret
+}
+
+// convertMuteWindowsFrom converts mute windows from v1alpha1.
+func convertMuteWindowsFrom(windows []v1alpha1.PrometheusSourceMuteWindow) []PrometheusSourceMuteWindow {
+	if windows == nil {
+		return nil
+	}
+	ret := make([]PrometheusSourceMuteWindow, len(windows))
+	for i := range windows {
+		w := windows[i].DeepCopy()
+		ret[i] = PrometheusSourceMuteWindow{
+			Schedule: w.Schedule,
+			Duration: w.Duration,
+			TimeZone: w.TimeZone,
+			Start:    w.Start,
+			End:      w.End,
+			Action:   MuteAction(w.Action),
+		}
+	}
+	return ret
+}
+
+// keep records the original v1alpha1 value in the annotation when it differs
+// from the converted v1beta1 value, formatted back.
+func keep(annotations map[string]string, key, original, converted string) {
+	if original != converted {
+		annotations[key] = original
+	} else {
+		delete(annotations, key)
+	}
+}
+
+// restore returns the original v1alpha1 value recorded in the annotation if
+// it still converts to the v1beta1 value, and the converted value otherwise.
+// The annotation is removed either way.
+func restore(annotations map[string]string, key, converted string, same func(original string) bool) string {
+	original, ok := annotations[key]
+	delete(annotations, key)
+	if ok && same(original) {
+		return original
+	}
+	return converted
+}
+
+// formatStep formats the step of the range in the Prometheus duration format.
+func formatStep(r *PrometheusQueryRange) string {
+	if r == nil {
+		return ""
+	}
+	return formatDuration(&r.Step)
+}
+
+// formatDuration formats the duration in the Prometheus duration format.
+func formatDuration(d *metav1.Duration) string {
+	if d == nil {
+		return ""
+	}
+	return model.Duration(d.Duration).String()
+}
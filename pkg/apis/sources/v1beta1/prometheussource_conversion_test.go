@@ -0,0 +1,383 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+type testConvertible struct{ apis.Convertible }
+
+func TestPrometheusSourceConversionBadType(t *testing.T) {
+	good, bad := &PrometheusSource{}, &testConvertible{}
+
+	if err := good.ConvertTo(context.Background(), bad); err == nil {
+		t.Errorf("ConvertTo() = %#v, wanted error", bad)
+	}
+	if err := good.ConvertFrom(context.Background(), bad); err == nil {
+		t.Errorf("ConvertFrom() = %#v, wanted error", good)
+	}
+}
+
+func TestPrometheusSourceConversionRoundTripV1alpha1(t *testing.T) {
+	status := v1alpha1.PrometheusSourceStatus{
+		SourceStatus: duckv1.SourceStatus{
+			Status: duckv1.Status{
+				ObservedGeneration: 1,
+				Conditions: duckv1.Conditions{{
+					Type:   apis.ConditionReady,
+					Status: corev1.ConditionTrue,
+				}},
+			},
+			SinkURI: apis.HTTP("sink.example.com"),
+		},
+	}
+
+	testCases := map[string]*v1alpha1.PrometheusSource{
+		"empty": {},
+		"full": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "prometheus",
+				Namespace:   "team-a",
+				Annotations: map[string]string{"team": "a"},
+			},
+			Spec: v1alpha1.PrometheusSourceSpec{
+				ServiceAccountName: "prometheus-source",
+				ServerURL:          "https://prometheus.monitoring.svc:9091/prometheus",
+				PromQL:             `sum(up{job="api"})`,
+				AuthTokenFile:      "/var/run/secrets/kubernetes.io/serviceaccount/token",
+				CACertConfigMap:    "prometheus-ca",
+				Schedule:           "*/5 * * * *",
+				Step:               "30s",
+				QueryTimeout:       "1m",
+				Sink: &duckv1.Destination{
+					Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "display"},
+				},
+				CloudEventOverrides: &duckv1.CloudEventOverrides{
+					Extensions: map[string]string{"team": "a"},
+				},
+				EventType:   "com.example.api.up",
+				EventSource: "https://prometheus.example.com",
+				Subject:     "{{.Labels.job}}",
+				LabelExtensions: []v1alpha1.LabelExtension{
+					{Label: "alert_name"},
+					{Label: "namespace", Name: "ns"},
+				},
+				PartitionKeyLabels: []string{"namespace", "pod"},
+				Template: &v1alpha1.PrometheusSourceTemplate{
+					Text:            `{{ range .Series }}{{ label "job" . }}: {{ value . }}{{ end }}`,
+					DataContentType: "text/markdown",
+				},
+				Limits: &v1alpha1.PrometheusSourceLimits{
+					MaxSeries:        100,
+					MaxSamples:       1000,
+					MaxResponseBytes: 1 << 20,
+					OverflowPolicy:   v1alpha1.OverflowPolicySplit,
+				},
+				SeriesPerEvent:  10,
+				DataFormat:      v1alpha1.DataFormatPrometheusJSON,
+				OnEmptyResult:   v1alpha1.EmptyResultPolicyEmitNoData,
+				NoDataThreshold: 3,
+				RelabelConfigs: []v1alpha1.RelabelConfig{
+					{Action: "labeldrop", Regex: ptr.String("pod_template_hash|instance")},
+					{
+						SourceLabels: []string{"namespace", "pod"},
+						Separator:    ptr.String("/"),
+						TargetLabel:  "workload",
+						Replacement:  ptr.String(""),
+					},
+				},
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+				},
+			},
+			Status: status,
+		},
+		"server reference": {
+			Spec: v1alpha1.PrometheusSourceSpec{
+				ServerRef: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus", Namespace: "monitoring"},
+				PromQL:    "up",
+				Schedule:  "@hourly",
+			},
+		},
+		"step in float seconds": {
+			Spec: v1alpha1.PrometheusSourceSpec{
+				ServerURL: "https://prometheus.example.com",
+				Step:      "15.5",
+			},
+		},
+		"unnormalized durations": {
+			Spec: v1alpha1.PrometheusSourceSpec{
+				ServerURL:    "https://prometheus.example.com",
+				Step:         "90s",
+				QueryTimeout: "120s",
+			},
+		},
+		"invalid values": {
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"team": "a"},
+			},
+			Spec: v1alpha1.PrometheusSourceSpec{
+				ServerURL:    "http://prometheus.example.com:port",
+				Step:         "often",
+				QueryTimeout: "-1m",
+			},
+		},
+	}
+
+	for n, want := range testCases {
+		t.Run(n, func(t *testing.T) {
+			hub := &PrometheusSource{}
+			if err := hub.ConvertFrom(context.Background(), want); err != nil {
+				t.Fatal("ConvertFrom() =", err)
+			}
+			got := &v1alpha1.PrometheusSource{}
+			if err := hub.ConvertTo(context.Background(), got); err != nil {
+				t.Fatal("ConvertTo() =", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error("Round trip (-want, +got) =", diff)
+			}
+		})
+	}
+}
+
+func TestPrometheusSourceConversionRoundTripV1beta1(t *testing.T) {
+	linearBackoff := eventingduckv1.BackoffPolicyLinear
+	testCases := map[string]*PrometheusSource{
+		"empty": {},
+		"full": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "prometheus",
+				Namespace: "team-a",
+			},
+			Spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{
+					Sink: duckv1.Destination{URI: apis.HTTP("sink.example.com")},
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team": "a"},
+					},
+				},
+				ServiceAccountName: "prometheus-source",
+				Server: PrometheusServer{
+					URL:  &apis.URL{Scheme: "https", Host: "prometheus.monitoring.svc:9091"},
+					Auth: &PrometheusServerAuth{TokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token"},
+					TLS:  &PrometheusServerTLS{CACertConfigMap: "prometheus-ca"},
+				},
+				Query: PrometheusQuery{
+					PromQL:  "up",
+					Range:   &PrometheusQueryRange{Step: metav1.Duration{Duration: 90 * time.Second}},
+					Timeout: &metav1.Duration{Duration: 2 * time.Minute},
+				},
+				Schedule: "*/5 * * * *",
+				Event: &PrometheusSourceEvent{
+					Type:    "com.example.up",
+					Subject: "{{.Name}}/{{.Labels.instance}}",
+				},
+			},
+		},
+		"data format": {
+			Spec: PrometheusSourceSpec{DataFormat: DataFormatOpenMetricsText},
+		},
+		"relabel configs": {
+			Spec: PrometheusSourceSpec{
+				RelabelConfigs: []RelabelConfig{
+					{SourceLabels: []string{"job"}, Regex: ptr.String("node"), Action: "drop"},
+					{SourceLabels: []string{"instance"}, Modulus: 4, TargetLabel: "shard", Action: "hashmod"},
+				},
+			},
+		},
+		"delivery": {
+			Spec: PrometheusSourceSpec{
+				Delivery: &eventingduckv1.DeliverySpec{
+					DeadLetterSink: &duckv1.Destination{
+						Ref: &duckv1.KReference{APIVersion: "serving.knative.dev/v1", Kind: "Service", Name: "dls"},
+					},
+					Retry:         ptr.Int32(3),
+					BackoffPolicy: &linearBackoff,
+					BackoffDelay:  ptr.String("PT0.5S"),
+				},
+			},
+			Status: PrometheusSourceStatus{
+				DeliveryStatus: eventingduckv1.DeliveryStatus{
+					DeadLetterSinkURI: apis.HTTP("dls.default.svc.cluster.local"),
+				},
+			},
+		},
+		"outbox": {
+			Spec: PrometheusSourceSpec{
+				Outbox: &PrometheusSourceOutbox{
+					VolumeClaimName: "prometheus-outbox",
+					MaxEvents:       1000,
+					MaxBytes:        1 << 20,
+					MaxAge:          &metav1.Duration{Duration: time.Hour},
+				},
+			},
+		},
+		"rate limit": {
+			Spec: PrometheusSourceSpec{
+				RateLimit: &PrometheusSourceRateLimit{
+					Events:   100,
+					Interval: &metav1.Duration{Duration: time.Minute},
+					Burst:    20,
+				},
+			},
+		},
+		"evaluation time": {
+			Spec: PrometheusSourceSpec{
+				Query: PrometheusQuery{
+					PromQL: "up",
+					Range: &PrometheusQueryRange{
+						Step:        metav1.Duration{Duration: 15 * time.Second},
+						AlignToStep: true,
+					},
+					EvaluationDelay: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+		},
+		"scheduling": {
+			Spec: PrometheusSourceSpec{
+				Schedule:         "0 9 * * *",
+				TimeZone:         "America/New_York",
+				Jitter:           &metav1.Duration{Duration: 30 * time.Second},
+				StartingDeadline: &metav1.Duration{Duration: time.Minute},
+			},
+		},
+		"run mode": {
+			Spec: PrometheusSourceSpec{RunMode: RunModeCronJob},
+		},
+		"replicas": {
+			Spec: PrometheusSourceSpec{Replicas: ptr.Int32(3)},
+		},
+		"shared adapter status": {
+			Spec: PrometheusSourceSpec{RunMode: RunModeShared},
+			Status: PrometheusSourceStatus{
+				ServerURL: apis.HTTP("prometheus.monitoring.svc:9090"),
+				PromQL:    `up{namespace="team-a"}`,
+			},
+		},
+		"suspend": {
+			Spec: PrometheusSourceSpec{Suspend: true},
+			Status: PrometheusSourceStatus{
+				LastSuspendTime: &metav1.Time{Time: time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)},
+				LastResumeTime:  &metav1.Time{Time: time.Date(2022, time.March, 1, 11, 0, 0, 0, time.UTC)},
+			},
+		},
+		"mute windows": {
+			Spec: PrometheusSourceSpec{
+				MuteWindows: []PrometheusSourceMuteWindow{{
+					Schedule: "0 2 * * 6",
+					Duration: &metav1.Duration{Duration: 2 * time.Hour},
+					TimeZone: "Europe/Paris",
+				}, {
+					Start:  &metav1.Time{Time: time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)},
+					End:    &metav1.Time{Time: time.Date(2022, time.March, 1, 11, 0, 0, 0, time.UTC)},
+					Action: MuteActionSkip,
+				}},
+			},
+		},
+		"empty result policy": {
+			Spec: PrometheusSourceSpec{OnEmptyResult: EmptyResultPolicySkip},
+		},
+		"json template": {
+			Spec: PrometheusSourceSpec{
+				Template: &PrometheusSourceTemplate{JSON: `{"summary": "{{ len .Series }} series"}`},
+			},
+		},
+		"label extensions": {
+			Spec: PrometheusSourceSpec{
+				Event: &PrometheusSourceEvent{
+					LabelExtensions:    []LabelExtension{{Label: "severity"}},
+					PartitionKeyLabels: []string{"namespace"},
+				},
+				Limits: &PrometheusSourceLimits{
+					MaxSeries:      100,
+					OverflowPolicy: OverflowPolicyDrop,
+				},
+				SeriesPerEvent: 10,
+			},
+		},
+		"server reference": {
+			Spec: PrometheusSourceSpec{
+				Server: PrometheusServer{
+					Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+				},
+			},
+		},
+	}
+
+	for n, want := range testCases {
+		t.Run(n, func(t *testing.T) {
+			v1a := &v1alpha1.PrometheusSource{}
+			if err := want.ConvertTo(context.Background(), v1a); err != nil {
+				t.Fatal("ConvertTo() =", err)
+			}
+			got := &PrometheusSource{}
+			if err := got.ConvertFrom(context.Background(), v1a); err != nil {
+				t.Fatal("ConvertFrom() =", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Error("Round trip (-want, +got) =", diff)
+			}
+		})
+	}
+}
+
+func TestPrometheusSourceConversionKeepsOriginalValues(t *testing.T) {
+	v1a := &v1alpha1.PrometheusSource{
+		Spec: v1alpha1.PrometheusSourceSpec{
+			ServerURL: "https://prometheus.example.com",
+			Step:      "60",
+		},
+	}
+	hub := &PrometheusSource{}
+	if err := hub.ConvertFrom(context.Background(), v1a); err != nil {
+		t.Fatal("ConvertFrom() =", err)
+	}
+	if got, want := hub.Spec.Query.Range.Step.Duration, time.Minute; got != want {
+		t.Errorf("Step = %v, want %v", got, want)
+	}
+	if got, want := hub.Annotations[stepAnnotation], "60"; got != want {
+		t.Errorf("Annotations[%s] = %q, want %q", stepAnnotation, got, want)
+	}
+
+	// The original value is dropped once the v1beta1 field changes.
+	hub.Spec.Query.Range.Step.Duration = 2 * time.Minute
+	got := &v1alpha1.PrometheusSource{}
+	if err := hub.ConvertTo(context.Background(), got); err != nil {
+		t.Fatal("ConvertTo() =", err)
+	}
+	if got, want := got.Spec.Step, "2m"; got != want {
+		t.Errorf("Step = %q, want %q", got, want)
+	}
+	if got.Annotations != nil {
+		t.Errorf("Annotations = %v, want none", got.Annotations)
+	}
+}
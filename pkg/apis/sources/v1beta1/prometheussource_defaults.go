@@ -0,0 +1,57 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+func (s *PrometheusSource) SetDefaults(ctx context.Context) {
+	s.Spec.SetDefaults(ctx)
+}
+
+func (s *PrometheusSourceSpec) SetDefaults(ctx context.Context) {
+	defaults := config.FromContextOrDefaults(ctx).Defaults
+	if s.Server.URL == nil && s.Server.Ref == nil && defaults.ServerURL != "" {
+		if u, err := apis.ParseURL(defaults.ServerURL); err == nil {
+			s.Server.URL = u
+		}
+	}
+	if s.Server.TLS == nil && defaults.CACertConfigMap != "" {
+		s.Server.TLS = &PrometheusServerTLS{CACertConfigMap: defaults.CACertConfigMap}
+	}
+	if s.Query.Timeout == nil && defaults.QueryTimeout > 0 {
+		s.Query.Timeout = &metav1.Duration{Duration: defaults.QueryTimeout}
+	}
+	if s.Schedule == "" {
+		s.Schedule = defaults.Schedule
+	}
+	if s.ServiceAccountName == "" {
+		s.ServiceAccountName = defaults.ServiceAccountName
+	}
+	s.Resources = v1alpha1.DefaultResources(s.Resources, &defaults.AdapterResources)
+
+	if s.Limits != nil && s.Limits.OverflowPolicy == "" {
+		s.Limits.OverflowPolicy = OverflowPolicyTruncate
+	}
+}
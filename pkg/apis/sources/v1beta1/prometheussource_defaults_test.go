@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+func TestPrometheusSourceDefaults(t *testing.T) {
+	defaults, err := config.NewDefaultsFromMap(map[string]string{
+		"server-url":           "http://prometheus.monitoring.svc:9090",
+		"schedule":             "*/5 * * * *",
+		"service-account-name": "prometheus-source",
+		"ca-cert-config-map":   "prometheus-ca",
+		"query-timeout":        "30s",
+		"adapter-memory-limit": "256Mi",
+	})
+	if err != nil {
+		t.Fatal("NewDefaultsFromMap() =", err)
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{Defaults: defaults})
+
+	testCases := map[string]struct {
+		ctx      context.Context
+		initial  PrometheusSource
+		expected PrometheusSource
+	}{
+		"no defaults": {
+			ctx:     context.Background(),
+			initial: PrometheusSource{},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{},
+			},
+		},
+		"empty spec": {
+			ctx:     ctx,
+			initial: PrometheusSource{},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServiceAccountName: "prometheus-source",
+					Server: PrometheusServer{
+						URL: &apis.URL{Scheme: "http", Host: "prometheus.monitoring.svc:9090"},
+						TLS: &PrometheusServerTLS{CACertConfigMap: "prometheus-ca"},
+					},
+					Query: PrometheusQuery{
+						Timeout: &metav1.Duration{Duration: 30 * time.Second},
+					},
+					Schedule: "*/5 * * * *",
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+		"server reference and limits": {
+			ctx: ctx,
+			initial: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					Server: PrometheusServer{
+						Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+					},
+					Schedule: "@hourly",
+					Limits:   &PrometheusSourceLimits{MaxSeries: 100},
+				},
+			},
+			expected: PrometheusSource{
+				Spec: PrometheusSourceSpec{
+					ServiceAccountName: "prometheus-source",
+					Server: PrometheusServer{
+						Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+						TLS: &PrometheusServerTLS{CACertConfigMap: "prometheus-ca"},
+					},
+					Query: PrometheusQuery{
+						Timeout: &metav1.Duration{Duration: 30 * time.Second},
+					},
+					Schedule: "@hourly",
+					Limits: &PrometheusSourceLimits{
+						MaxSeries:      100,
+						OverflowPolicy: OverflowPolicyTruncate,
+					},
+					Resources: &corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("256Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			tc.initial.SetDefaults(tc.ctx)
+			if diff := cmp.Diff(tc.expected, tc.initial); diff != "" {
+				t.Fatalf("Unexpected defaults (-want, +got): %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"knative.dev/pkg/apis"
+)
+
+const (
+	// PrometheusConditionReady has status True when the PrometheusSource is ready to send events.
+	PrometheusConditionReady = apis.ConditionReady
+
+	// PrometheusConditionSinkProvided has status True when the PrometheusSource has been configured with a sink target.
+	PrometheusConditionSinkProvided apis.ConditionType = "SinkProvided"
+
+	// PrometheusConditionDeployed has status True when the PrometheusSource has had it's deployment created.
+	PrometheusConditionDeployed apis.ConditionType = "Deployed"
+)
+
+// PrometheusCondSet is the same condition set as in v1alpha1, the version the
+// PrometheusSources are reconciled as.
+var PrometheusCondSet = apis.NewLivingConditionSet(
+	PrometheusConditionSinkProvided,
+	PrometheusConditionDeployed,
+)
+
+// GetConditionSet retrieves the condition set for this resource. Implements the KRShaped interface.
+func (*PrometheusSource) GetConditionSet() apis.ConditionSet {
+	return PrometheusCondSet
+}
+
+// GetCondition returns the condition currently associated with the given type, or nil.
+func (s *PrometheusSourceStatus) GetCondition(t apis.ConditionType) *apis.Condition {
+	return PrometheusCondSet.Manage(s).GetCondition(t)
+}
+
+// IsReady returns true if the resource is ready overall.
+func (s *PrometheusSourceStatus) IsReady() bool {
+	return PrometheusCondSet.Manage(s).IsHappy()
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// specFieldPaths are the v1beta1 paths of the fields checked against the
+// cluster policies.
+var specFieldPaths = v1alpha1.FieldPaths{
+	ServerURL:     "server.url",
+	AuthTokenFile: "server.auth.tokenFile",
+	PromQL:        "query.promQL",
+	Schedule:      "schedule",
+	Step:          "query.range.step",
+}
+
+// Validate Prometheus source object fields
+func (s *PrometheusSource) Validate(ctx context.Context) *apis.FieldError {
+	errs := s.Spec.Validate(ctx).ViaField("spec").Also(
+		v1alpha1.ValidateMuteUntil(s.Annotations).ViaField("metadata"))
+
+	// The cluster policies and the run mode are checked by v1alpha1.
+	v1a := &v1alpha1.PrometheusSource{}
+	if err := s.ConvertTo(ctx, v1a); err != nil {
+		return errs.Also(apis.ErrGeneric(err.Error()))
+	}
+	return errs.Also(
+		v1a.ValidatePolicies(ctx, specFieldPaths),
+		v1alpha1.ValidateRunMode(&v1a.Spec, specFieldPaths).ViaField("spec"),
+	)
+}
+
+// Warnings returns the query policy violations that do not reject the source
+// but should be reported to the user as admission warnings.
+func (s *PrometheusSource) Warnings(ctx context.Context) []string {
+	v1a := &v1alpha1.PrometheusSource{}
+	if err := s.ConvertTo(ctx, v1a); err != nil {
+		return nil
+	}
+	return v1a.QueryPolicyWarnings(ctx, specFieldPaths)
+}
+
+// Validate Prometheus source Spec object fields
+func (s *PrometheusSourceSpec) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+
+	// Validate server
+	errs = errs.Also(s.Server.Validate(ctx).ViaField("server"))
+
+	// Validate query
+	errs = errs.Also(s.Query.Validate(ctx).ViaField("query"))
+
+	// Validate schedule
+	if s.Schedule == "" {
+		errs = errs.Also(apis.ErrMissingField("schedule"))
+	} else if _, err := cron.ParseStandard(s.Schedule); err != nil {
+		errs = errs.Also(apis.ErrInvalidValue(s.Schedule, "schedule", err.Error()))
+	}
+	errs = errs.Also(v1alpha1.ValidateScheduling(s.Schedule, s.TimeZone, s.Jitter, s.StartingDeadline))
+
+	// Validate event attributes
+	if s.Event != nil {
+		errs = errs.Also(s.Event.Validate(ctx).ViaField("event"))
+		// The promoted labels must not collide with the CloudEvent overrides.
+		errs = errs.Also(v1alpha1.ValidateLabelExtensions(convertLabelExtensionsTo(s.Event.LabelExtensions),
+			s.CloudEventOverrides).ViaField("event", "labelExtensions"))
+	}
+
+	// Validate template
+	if s.Template != nil {
+		errs = errs.Also((*v1alpha1.PrometheusSourceTemplate)(s.Template).Validate(ctx).ViaField("template"))
+	}
+
+	// Validate relabel configs
+	errs = errs.Also(v1alpha1.ValidateRelabelConfigs(convertRelabelConfigsTo(s.RelabelConfigs)).ViaField("relabelConfigs"))
+
+	// Validate data format
+	switch s.DataFormat {
+	case "", DataFormatPrometheusJSON:
+	case DataFormatPrometheusText, DataFormatOpenMetricsText, DataFormatCSV, DataFormatProtobuf:
+		if s.Template != nil {
+			errs = errs.Also(apis.ErrMultipleOneOf("template", "dataFormat"))
+		}
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(s.DataFormat, "dataFormat",
+			fmt.Sprintf("must be one of %q, %q, %q, %q or %q", DataFormatPrometheusJSON, DataFormatPrometheusText,
+				DataFormatOpenMetricsText, DataFormatCSV, DataFormatProtobuf)))
+	}
+
+	// Validate delivery
+	errs = errs.Also(s.Delivery.Validate(ctx).ViaField("delivery"))
+
+	// Validate outbox
+	if s.Outbox != nil {
+		errs = errs.Also((*v1alpha1.PrometheusSourceOutbox)(s.Outbox).Validate(ctx).ViaField("outbox"))
+	}
+
+	// Validate rate limit
+	if s.RateLimit != nil {
+		errs = errs.Also((*v1alpha1.PrometheusSourceRateLimit)(s.RateLimit).Validate(ctx).ViaField("rateLimit"))
+	}
+
+	// Validate mute windows
+	for i, w := range convertMuteWindowsTo(s.MuteWindows) {
+		errs = errs.Also(w.Validate(ctx).ViaFieldIndex("muteWindows", i))
+	}
+
+	// Validate limits
+	if s.Limits != nil {
+		errs = errs.Also(s.Limits.Validate(ctx).ViaField("limits"))
+	}
+
+	// Validate series per event
+	if s.SeriesPerEvent < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(s.SeriesPerEvent, "seriesPerEvent", "must not be negative"))
+	}
+
+	// Validate empty result policy
+	switch s.OnEmptyResult {
+	case "", EmptyResultPolicySend, EmptyResultPolicySkip, EmptyResultPolicyEmitNoData:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(s.OnEmptyResult, "onEmptyResult",
+			fmt.Sprintf("must be one of %q, %q or %q", EmptyResultPolicySend, EmptyResultPolicySkip, EmptyResultPolicyEmitNoData)))
+	}
+	switch {
+	case s.NoDataThreshold < 0:
+		errs = errs.Also(apis.ErrInvalidValue(s.NoDataThreshold, "noDataThreshold", "must not be negative"))
+	case s.NoDataThreshold > 0 && s.OnEmptyResult != EmptyResultPolicyEmitNoData:
+		errs = errs.Also(apis.ErrInvalidValue(s.NoDataThreshold, "noDataThreshold",
+			fmt.Sprintf("only applies when onEmptyResult is %q", EmptyResultPolicyEmitNoData)))
+	}
+
+	// Validate sink
+	if s.Sink == (duckv1.Destination{}) {
+		errs = errs.Also(apis.ErrMissingField("sink"))
+	} else if fe := s.Sink.Validate(ctx); fe != nil {
+		errs = errs.Also(fe.ViaField("sink"))
+	}
+
+	// Validate CloudEvent overrides
+	if s.CloudEventOverrides != nil {
+		errs = errs.Also(s.CloudEventOverrides.Validate(ctx).ViaField("ceOverrides"))
+	}
+	return errs
+}
+
+// Validate PrometheusServer object fields
+func (s *PrometheusServer) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	switch {
+	case s.URL == nil && s.Ref == nil:
+		errs = errs.Also(apis.ErrMissingOneOf("url", "ref"))
+	case s.URL != nil && s.Ref != nil:
+		errs = errs.Also(apis.ErrMultipleOneOf("url", "ref"))
+	case s.Ref != nil:
+		errs = errs.Also(s.Ref.Validate(ctx).ViaField("ref"))
+	default:
+		errs = errs.Also(v1alpha1.ValidateServerURL(s.URL.String()).ViaField("url"))
+	}
+	if s.Auth != nil && s.Auth.TokenFile == "" {
+		errs = errs.Also(apis.ErrMissingField("auth.tokenFile"))
+	}
+	if s.TLS != nil && s.TLS.CACertConfigMap == "" {
+		errs = errs.Also(apis.ErrMissingField("tls.caCertConfigMap"))
+	}
+	return errs
+}
+
+// Validate PrometheusQuery object fields
+func (q *PrometheusQuery) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if q.PromQL == "" {
+		errs = errs.Also(apis.ErrMissingField("promQL"))
+	} else if fe := v1alpha1.ValidatePromQL(q.PromQL); fe != nil {
+		errs = errs.Also(fe.ViaField("promQL"))
+	}
+	if q.Range != nil {
+		errs = errs.Also(validateDuration(q.Range.Step, "range.step"))
+	}
+	if q.Timeout != nil {
+		errs = errs.Also(validateDuration(*q.Timeout, "timeout"))
+	}
+	if q.EvaluationDelay != nil {
+		errs = errs.Also(validateDuration(*q.EvaluationDelay, "evaluationDelay"))
+	}
+	return errs
+}
+
+// Validate PrometheusSourceEvent object fields
+func (e *PrometheusSourceEvent) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if e.Source != "" {
+		errs = errs.Also(v1alpha1.ValidateEventSource(e.Source).ViaField("source"))
+	}
+	if e.Subject != "" {
+		errs = errs.Also(v1alpha1.ValidateSubjectTemplate(e.Subject).ViaField("subject"))
+	}
+	errs = errs.Also(v1alpha1.ValidatePartitionKeyLabels(e.PartitionKeyLabels).ViaField("partitionKeyLabels"))
+	return errs
+}
+
+// Validate PrometheusSourceLimits object fields
+func (l *PrometheusSourceLimits) Validate(ctx context.Context) *apis.FieldError {
+	var errs *apis.FieldError
+	if l.MaxSeries < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxSeries, "maxSeries", "must not be negative"))
+	}
+	if l.MaxSamples < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxSamples, "maxSamples", "must not be negative"))
+	}
+	if l.MaxResponseBytes < 0 {
+		errs = errs.Also(apis.ErrInvalidValue(l.MaxResponseBytes, "maxResponseBytes", "must not be negative"))
+	}
+	switch l.OverflowPolicy {
+	case "", OverflowPolicyTruncate, OverflowPolicySplit, OverflowPolicyDrop:
+	default:
+		errs = errs.Also(apis.ErrInvalidValue(l.OverflowPolicy, "overflowPolicy",
+			fmt.Sprintf("must be one of %q, %q or %q", OverflowPolicyTruncate, OverflowPolicySplit, OverflowPolicyDrop)))
+	}
+	return errs
+}
+
+// validateDuration checks that the duration is positive and, as Prometheus
+// has a millisecond resolution, a whole number of milliseconds.
+func validateDuration(d metav1.Duration, field string) *apis.FieldError {
+	switch {
+	case d.Duration <= 0:
+		return apis.ErrInvalidValue(d.Duration.String(), field, "must be a positive duration")
+	case d.Duration%time.Millisecond != 0:
+		return apis.ErrInvalidValue(d.Duration.String(), field, "must be a whole number of milliseconds")
+	}
+	return nil
+}
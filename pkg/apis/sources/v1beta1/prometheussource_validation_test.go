@@ -0,0 +1,325 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/ptr"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+)
+
+var (
+	validSink = duckv1.Destination{
+		URI: apis.HTTP("sink.example.com"),
+	}
+	validServer = PrometheusServer{
+		URL: &apis.URL{Scheme: "https", Host: "prometheus.example.com:9091"},
+	}
+)
+
+func TestPrometheusSourceValidationValid(t *testing.T) {
+	src := &PrometheusSource{
+		Spec: PrometheusSourceSpec{
+			SourceSpec: duckv1.SourceSpec{Sink: validSink},
+			Server:     validServer,
+			Query: PrometheusQuery{
+				PromQL: `sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`,
+				Range:  &PrometheusQueryRange{Step: metav1.Duration{Duration: 30 * time.Second}},
+			},
+			Schedule: "*/5 * * * *",
+		},
+	}
+	if err := src.Validate(context.Background()); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestPrometheusSourceValidation(t *testing.T) {
+	testCases := map[string]struct {
+		spec PrometheusSourceSpec
+		want *apis.FieldError
+	}{
+		"missing required fields": {
+			spec: PrometheusSourceSpec{},
+			want: apis.ErrMissingOneOf("spec.server.url", "spec.server.ref").Also(
+				apis.ErrMissingField("spec.query.promQL", "spec.schedule", "spec.sink")),
+		},
+		"server URL and reference": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server: PrometheusServer{
+					URL: validServer.URL,
+					Ref: &duckv1.KReference{APIVersion: "v1", Kind: "Service", Name: "prometheus"},
+				},
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+			},
+			want: apis.ErrMultipleOneOf("spec.server.url", "spec.server.ref"),
+		},
+		"non-http server URL": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server: PrometheusServer{
+					URL: &apis.URL{Scheme: "ftp", Host: "prometheus.example.com"},
+				},
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+			},
+			want: apis.ErrInvalidValue("ftp://prometheus.example.com", "spec.server.url", "scheme must be http or https"),
+		},
+		"empty auth and tls": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server: PrometheusServer{
+					URL:  validServer.URL,
+					Auth: &PrometheusServerAuth{},
+					TLS:  &PrometheusServerTLS{},
+				},
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+			},
+			want: apis.ErrMissingField("spec.server.auth.tokenFile", "spec.server.tls.caCertConfigMap"),
+		},
+		"invalid promQL": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query:      PrometheusQuery{PromQL: "rate(http_requests_total[5m] offset)"},
+				Schedule:   "* * * * *",
+			},
+			want: &apis.FieldError{
+				Message: `invalid PromQL at position 35: unexpected ")" in offset, expected duration`,
+				Paths:   []string{"spec.query.promQL"},
+				Details: `1:36: parse error: unexpected ")" in offset, expected duration`,
+			},
+		},
+		"invalid durations": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query: PrometheusQuery{
+					PromQL:          "up",
+					Range:           &PrometheusQueryRange{Step: metav1.Duration{Duration: -time.Minute}},
+					Timeout:         &metav1.Duration{Duration: 1500 * time.Microsecond},
+					EvaluationDelay: &metav1.Duration{},
+				},
+				Schedule: "* * * * *",
+			},
+			want: apis.ErrInvalidValue("-1m0s", "spec.query.range.step", "must be a positive duration").Also(
+				apis.ErrInvalidValue("1.5ms", "spec.query.timeout", "must be a whole number of milliseconds"),
+				apis.ErrInvalidValue("0s", "spec.query.evaluationDelay", "must be a positive duration")),
+		},
+		"stateful features in a cron job": {
+			spec: PrometheusSourceSpec{
+				SourceSpec:    duckv1.SourceSpec{Sink: validSink},
+				Server:        validServer,
+				Query:         PrometheusQuery{PromQL: "up"},
+				Schedule:      "0 * * * *",
+				RateLimit:     &PrometheusSourceRateLimit{Events: 10},
+				OnEmptyResult: EmptyResultPolicyEmitNoData,
+				RunMode:       RunModeCronJob,
+			},
+			want: apis.ErrGeneric(`not supported with the "cronJob" run mode`, "spec.rateLimit", "spec.onEmptyResult"),
+		},
+		"token file in the shared adapter": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server: PrometheusServer{
+					URL:  validServer.URL,
+					Auth: &PrometheusServerAuth{TokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token"},
+				},
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+				RunMode:  RunModeShared,
+			},
+			want: apis.ErrGeneric(`not supported with the "shared" run mode`, "spec.server.auth.tokenFile"),
+		},
+		"invalid replicas": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query:      PrometheusQuery{PromQL: "up"},
+				Schedule:   "* * * * *",
+				Replicas:   ptr.Int32(0),
+			},
+			want: apis.ErrInvalidValue(0, "spec.replicas", "must be at least 1"),
+		},
+		"invalid schedule and limits": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query:      PrometheusQuery{PromQL: "up"},
+				Schedule:   "every minute",
+				Limits:     &PrometheusSourceLimits{MaxSeries: -1},
+			},
+			want: apis.ErrInvalidValue("every minute", "spec.schedule", "Expected exactly 5 fields, found 2: every minute").Also(
+				apis.ErrInvalidValue(-1, "spec.limits.maxSeries", "must not be negative")),
+		},
+		"invalid event attributes": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{
+					Sink: validSink,
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team-a": "a"},
+					},
+				},
+				Server:   validServer,
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+				Event: &PrometheusSourceEvent{
+					Source:  "%zz",
+					Subject: "{{.Labels.job",
+				},
+			},
+			want: apis.ErrInvalidValue("%zz", "spec.event.source", `parse "%zz": invalid URL escape "%zz"`).Also(
+				apis.ErrInvalidValue("{{.Labels.job", "spec.event.subject", "template: subject:1: unclosed action"),
+				apis.ErrInvalidKeyName("team-a", "spec.ceOverrides.extensions", "keys are expected to be alphanumeric")),
+		},
+		"invalid data format": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query:      PrometheusQuery{PromQL: "up"},
+				Schedule:   "* * * * *",
+				DataFormat: "xml",
+			},
+			want: apis.ErrInvalidValue("xml", "spec.dataFormat",
+				`must be one of "prometheus-json", "prometheus-text", "openmetrics-text", "csv" or "protobuf"`),
+		},
+		"invalid relabel config": {
+			spec: PrometheusSourceSpec{
+				SourceSpec:     duckv1.SourceSpec{Sink: validSink},
+				Server:         validServer,
+				Query:          PrometheusQuery{PromQL: "up"},
+				Schedule:       "* * * * *",
+				RelabelConfigs: []RelabelConfig{{Action: "hashmod", TargetLabel: "shard"}},
+			},
+			want: &apis.FieldError{
+				Message: "invalid relabel config",
+				Paths:   []string{"spec.relabelConfigs[0]"},
+				Details: "relabel configuration for hashmod requires non-zero modulus",
+			},
+		},
+		"no data threshold without emitNoData": {
+			spec: PrometheusSourceSpec{
+				SourceSpec:      duckv1.SourceSpec{Sink: validSink},
+				Server:          validServer,
+				Query:           PrometheusQuery{PromQL: "up"},
+				Schedule:        "* * * * *",
+				OnEmptyResult:   EmptyResultPolicySkip,
+				NoDataThreshold: 5,
+			},
+			want: apis.ErrInvalidValue(5, "spec.noDataThreshold", `only applies when onEmptyResult is "emitNoData"`),
+		},
+		"invalid template": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{Sink: validSink},
+				Server:     validServer,
+				Query:      PrometheusQuery{PromQL: "up"},
+				Schedule:   "* * * * *",
+				Template:   &PrometheusSourceTemplate{JSON: `{"summary": "{{ .Name }"}`},
+			},
+			want: &apis.FieldError{
+				Message: "invalid template",
+				Paths:   []string{"spec.template.json"},
+				Details: `template: $.summary:1: unexpected "}" in operand`,
+			},
+		},
+		"invalid label extensions": {
+			spec: PrometheusSourceSpec{
+				SourceSpec: duckv1.SourceSpec{
+					Sink: validSink,
+					CloudEventOverrides: &duckv1.CloudEventOverrides{
+						Extensions: map[string]string{"team": "a"},
+					},
+				},
+				Server:   validServer,
+				Query:    PrometheusQuery{PromQL: "up"},
+				Schedule: "* * * * *",
+				Event: &PrometheusSourceEvent{
+					LabelExtensions:    []LabelExtension{{Label: "team"}, {Label: "job", Name: "Job"}},
+					PartitionKeyLabels: []string{"pod-name"},
+				},
+			},
+			want: apis.ErrInvalidValue("pod-name", "spec.event.partitionKeyLabels[0]", "must be a valid label name").Also(
+				apis.ErrInvalidValue("team", "spec.event.labelExtensions[0].label", `extension attribute "team" is set by ceOverrides`),
+				apis.ErrInvalidValue("Job", "spec.event.labelExtensions[1].name", "must consist of lowercase letters and digits")),
+		},
+	}
+
+	for n, test := range testCases {
+		t.Run(n, func(t *testing.T) {
+			got := (&PrometheusSource{Spec: test.spec}).Validate(context.Background())
+			if diff := cmp.Diff(test.want.Error(), got.Error()); diff != "" {
+				t.Errorf("%s: validate (-want, +got) = %v", n, diff)
+			}
+		})
+	}
+}
+
+func TestPrometheusSourcePolicies(t *testing.T) {
+	serverPolicy, err := config.NewServerPolicyFromMap(map[string]string{
+		"allowed-servers": "https://*.monitoring.svc",
+	})
+	if err != nil {
+		t.Fatal("NewServerPolicyFromMap() =", err)
+	}
+	queryPolicy := &config.QueryPolicy{
+		UnscopedSelector:             config.PolicyActionWarn,
+		MaxPointsPerEvaluation:       100,
+		MaxPointsPerEvaluationAction: config.PolicyActionReject,
+	}
+	ctx := config.ToContext(context.Background(), &config.Config{
+		ServerPolicy: serverPolicy,
+		QueryPolicy:  queryPolicy,
+	})
+
+	src := &PrometheusSource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a"},
+		Spec: PrometheusSourceSpec{
+			SourceSpec: duckv1.SourceSpec{Sink: validSink},
+			Server:     validServer,
+			Query: PrometheusQuery{
+				PromQL: "up",
+				Range:  &PrometheusQueryRange{Step: metav1.Duration{Duration: time.Second}},
+			},
+			Schedule: "*/5 * * * *",
+		},
+	}
+
+	wantErr := apis.ErrInvalidValue("https://prometheus.example.com:9091", "spec.server.url",
+		`server is not allowed in namespace "team-a" by the cluster server policy`).Also(&apis.FieldError{
+		Message: "step 1s returns 300 points per series for a schedule interval of 5m0s, more than the maximum of 100",
+		Paths:   []string{"spec.query.range.step"},
+	})
+	if diff := cmp.Diff(wantErr.Error(), src.Validate(ctx).Error()); diff != "" {
+		t.Errorf("Validate (-want, +got) = %v", diff)
+	}
+
+	wantWarnings := []string{"selector up at position 0 has no label matchers: spec.query.promQL"}
+	if diff := cmp.Diff(wantWarnings, src.Warnings(ctx)); diff != "" {
+		t.Errorf("Warnings (-want, +got) = %v", diff)
+	}
+}
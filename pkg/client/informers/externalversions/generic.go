@@ -24,6 +24,7 @@ import (
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
 	cache "k8s.io/client-go/tools/cache"
 	v1alpha1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	v1beta1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1beta1"
 )
 
 // GenericInformer is type of SharedIndexInformer which will locate and delegate to other
@@ -56,6 +57,10 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	case v1alpha1.SchemeGroupVersion.WithResource("prometheussources"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Sources().V1alpha1().PrometheusSources().Informer()}, nil
 
+		// Group=sources.knative.dev, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("prometheussources"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Sources().V1beta1().PrometheusSources().Informer()}, nil
+
 	}
 
 	return nil, fmt.Errorf("no informer found for %v", resource)
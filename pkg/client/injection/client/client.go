@@ -34,8 +34,10 @@ import (
 	dynamic "k8s.io/client-go/dynamic"
 	rest "k8s.io/client-go/rest"
 	v1alpha1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	v1beta1 "knative.dev/eventing-prometheus/pkg/apis/sources/v1beta1"
 	versioned "knative.dev/eventing-prometheus/pkg/client/clientset/versioned"
 	typedsourcesv1alpha1 "knative.dev/eventing-prometheus/pkg/client/clientset/versioned/typed/sources/v1alpha1"
+	typedsourcesv1beta1 "knative.dev/eventing-prometheus/pkg/client/clientset/versioned/typed/sources/v1beta1"
 	injection "knative.dev/pkg/injection"
 	dynamicclient "knative.dev/pkg/injection/clients/dynamicclient"
 	logging "knative.dev/pkg/logging"
@@ -241,3 +243,149 @@ func (w *wrapSourcesV1alpha1PrometheusSourceImpl) UpdateStatus(ctx context.Conte
 func (w *wrapSourcesV1alpha1PrometheusSourceImpl) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
 	return nil, errors.New("NYI: Watch")
 }
+
+// SourcesV1beta1 retrieves the SourcesV1beta1Client
+func (w *wrapClient) SourcesV1beta1() typedsourcesv1beta1.SourcesV1beta1Interface {
+	return &wrapSourcesV1beta1{
+		dyn: w.dyn,
+	}
+}
+
+type wrapSourcesV1beta1 struct {
+	dyn dynamic.Interface
+}
+
+func (w *wrapSourcesV1beta1) RESTClient() rest.Interface {
+	panic("RESTClient called on dynamic client!")
+}
+
+func (w *wrapSourcesV1beta1) PrometheusSources(namespace string) typedsourcesv1beta1.PrometheusSourceInterface {
+	return &wrapSourcesV1beta1PrometheusSourceImpl{
+		dyn: w.dyn.Resource(schema.GroupVersionResource{
+			Group:    "sources.knative.dev",
+			Version:  "v1beta1",
+			Resource: "prometheussources",
+		}),
+
+		namespace: namespace,
+	}
+}
+
+type wrapSourcesV1beta1PrometheusSourceImpl struct {
+	dyn dynamic.NamespaceableResourceInterface
+
+	namespace string
+}
+
+var _ typedsourcesv1beta1.PrometheusSourceInterface = (*wrapSourcesV1beta1PrometheusSourceImpl)(nil)
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Create(ctx context.Context, in *v1beta1.PrometheusSource, opts v1.CreateOptions) (*v1beta1.PrometheusSource, error) {
+	in.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "sources.knative.dev",
+		Version: "v1beta1",
+		Kind:    "PrometheusSource",
+	})
+	uo := &unstructured.Unstructured{}
+	if err := convert(in, uo); err != nil {
+		return nil, err
+	}
+	uo, err := w.dyn.Namespace(w.namespace).Create(ctx, uo, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSource{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
+	return w.dyn.Namespace(w.namespace).Delete(ctx, name, opts)
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error {
+	return w.dyn.Namespace(w.namespace).DeleteCollection(ctx, opts, listOpts)
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Get(ctx context.Context, name string, opts v1.GetOptions) (*v1beta1.PrometheusSource, error) {
+	uo, err := w.dyn.Namespace(w.namespace).Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSource{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) List(ctx context.Context, opts v1.ListOptions) (*v1beta1.PrometheusSourceList, error) {
+	uo, err := w.dyn.Namespace(w.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSourceList{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1beta1.PrometheusSource, err error) {
+	uo, err := w.dyn.Namespace(w.namespace).Patch(ctx, name, pt, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSource{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Update(ctx context.Context, in *v1beta1.PrometheusSource, opts v1.UpdateOptions) (*v1beta1.PrometheusSource, error) {
+	in.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "sources.knative.dev",
+		Version: "v1beta1",
+		Kind:    "PrometheusSource",
+	})
+	uo := &unstructured.Unstructured{}
+	if err := convert(in, uo); err != nil {
+		return nil, err
+	}
+	uo, err := w.dyn.Namespace(w.namespace).Update(ctx, uo, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSource{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) UpdateStatus(ctx context.Context, in *v1beta1.PrometheusSource, opts v1.UpdateOptions) (*v1beta1.PrometheusSource, error) {
+	in.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "sources.knative.dev",
+		Version: "v1beta1",
+		Kind:    "PrometheusSource",
+	})
+	uo := &unstructured.Unstructured{}
+	if err := convert(in, uo); err != nil {
+		return nil, err
+	}
+	uo, err := w.dyn.Namespace(w.namespace).UpdateStatus(ctx, uo, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1beta1.PrometheusSource{}
+	if err := convert(uo, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *wrapSourcesV1beta1PrometheusSourceImpl) Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error) {
+	return nil, errors.New("NYI: Watch")
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package datatemplate renders the data of the events sent by a
+// PrometheusSource from its query results, with Go text/templates.
+package datatemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// TextContentType is the default content type of the data rendered by a
+	// text template.
+	TextContentType = "text/plain"
+
+	// JSONContentType is the default content type of the data rendered by a
+	// JSON template.
+	JSONContentType = "application/json"
+)
+
+// Data is what the templates are executed with.
+type Data struct {
+	// Name is the name of the PrometheusSource.
+	Name string
+	// Namespace is the namespace of the PrometheusSource.
+	Namespace string
+	// Time is the evaluation time of the query.
+	Time time.Time
+	// ResultType is the type of the query result: vector, matrix, scalar or
+	// string.
+	ResultType string
+	// Series are the series of the query result. A scalar result is a single
+	// series without labels.
+	Series []Series
+	// String is the value of a string result.
+	String string
+	// Warnings are the warnings of the query response.
+	Warnings []string
+}
+
+// Series is a series of a query result.
+type Series struct {
+	// Labels are the labels of the series.
+	Labels map[string]string
+	// Value is the value of the last sample of the series.
+	Value float64
+	// Timestamp is the timestamp of the last sample of the series.
+	Timestamp time.Time
+	// Samples are the samples of a range query result series.
+	Samples []Sample
+}
+
+// Sample is a sample of a range query result series.
+type Sample struct {
+	Value     float64
+	Timestamp time.Time
+}
+
+// Template renders the data of an event.
+type Template struct {
+	text *template.Template
+	// json is the decoded JSON template, with the string values parsed as
+	// templates.
+	json interface{}
+}
+
+// Parse parses a text template.
+func Parse(text string) (*Template, error) {
+	t, err := newTemplate("text").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{text: t}, nil
+}
+
+// ParseJSON parses a JSON template: a JSON document whose string values are
+// text templates, rendered as strings.
+func ParseJSON(doc string) (*Template, error) {
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid JSON: more than one value")
+	}
+	parsed, err := parseJSONValue(v, "$")
+	if err != nil {
+		return nil, err
+	}
+	return &Template{json: parsed}, nil
+}
+
+func parseJSONValue(v interface{}, path string) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return newTemplate(path).Parse(v)
+	case map[string]interface{}:
+		for key, value := range v {
+			parsed, err := parseJSONValue(value, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = parsed
+		}
+	case []interface{}:
+		for i, value := range v {
+			parsed, err := parseJSONValue(value, path+"["+strconv.Itoa(i)+"]")
+			if err != nil {
+				return nil, err
+			}
+			v[i] = parsed
+		}
+	}
+	return v, nil
+}
+
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=zero").Funcs(FuncMap())
+}
+
+// ContentType returns the default content type of the rendered data.
+func (t *Template) ContentType() string {
+	if t.text != nil {
+		return TextContentType
+	}
+	return JSONContentType
+}
+
+// Execute renders the data of an event.
+func (t *Template) Execute(data *Data) ([]byte, error) {
+	if t.text != nil {
+		var buf bytes.Buffer
+		if err := t.text.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	v, err := executeJSONValue(t.json, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func executeJSONValue(v interface{}, data *Data) (interface{}, error) {
+	switch v := v.(type) {
+	case *template.Template:
+		var buf strings.Builder
+		if err := v.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			executed, err := executeJSONValue(value, data)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = executed
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, value := range v {
+			executed, err := executeJSONValue(value, data)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = executed
+		}
+		return ret, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datatemplate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var testData = &Data{
+	Name:       "cpu",
+	Namespace:  "monitoring",
+	Time:       time.Date(2022, time.March, 1, 12, 0, 0, 0, time.UTC),
+	ResultType: "vector",
+	Series: []Series{{
+		Labels: map[string]string{"node": "worker-1", "instance": "10.0.0.1:9100"},
+		Value:  0.9312,
+	}, {
+		Labels: map[string]string{"node": "worker-2", "instance": "10.0.0.2:9100"},
+		Value:  0.42,
+	}},
+}
+
+func TestExecute(t *testing.T) {
+	testCases := map[string]struct {
+		text     string
+		json     string
+		want     string
+		wantType string
+	}{
+		"text": {
+			text:     `{{ range .Series }}CPU on node {{ label "node" . }} is {{ humanizePercentage (value .) }}` + "\n" + `{{ end }}`,
+			want:     "CPU on node worker-1 is 93.12%\nCPU on node worker-2 is 42%\n",
+			wantType: TextContentType,
+		},
+		"text with source": {
+			text:     `{{ .Namespace }}/{{ .Name }} at {{ .Time.Unix }}: {{ len .Series }} series`,
+			want:     "monitoring/cpu at 1646136000: 2 series",
+			wantType: TextContentType,
+		},
+		"json": {
+			json: `{"summary": "{{ len .Series }} nodes", "count": 2, "nodes": [
+				"{{ range $i, $s := .Series }}{{ if $i }},{{ end }}{{ reReplaceAll \":[0-9]+\" \"\" (label \"instance\" $s) }}{{ end }}"
+			], "critical": true, "runbook": null}`,
+			want:     `{"count":2,"critical":true,"nodes":["10.0.0.1,10.0.0.2"],"runbook":null,"summary":"2 nodes"}`,
+			wantType: JSONContentType,
+		},
+		"json string": {
+			json:     `"{{ .ResultType }}"`,
+			want:     `"vector"`,
+			wantType: JSONContentType,
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			var tmpl *Template
+			var err error
+			if tc.json != "" {
+				tmpl, err = ParseJSON(tc.json)
+			} else {
+				tmpl, err = Parse(tc.text)
+			}
+			if err != nil {
+				t.Fatal("Parse() =", err)
+			}
+			got, err := tmpl.Execute(testData)
+			if err != nil {
+				t.Fatal("Execute() =", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("unexpected data (-want, +got) = %v", diff)
+			}
+			if got := tmpl.ContentType(); got != tc.wantType {
+				t.Errorf("ContentType() = %q, want %q", got, tc.wantType)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse(`{{ humanize }`); err == nil {
+		t.Error("Parse() = nil, want error")
+	}
+	if _, err := Parse(`{{ humanise 1 }}`); err == nil {
+		t.Error("Parse() = nil, want error for an unknown function")
+	}
+	if _, err := ParseJSON(`{"summary": "{{ .Name }"}`); err == nil {
+		t.Error("ParseJSON() = nil, want error")
+	}
+	if _, err := ParseJSON(`{"summary": `); err == nil {
+		t.Error("ParseJSON() = nil, want error for invalid JSON")
+	}
+	if _, err := ParseJSON(`{} {}`); err == nil {
+		t.Error("ParseJSON() = nil, want error for more than one value")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	testCases := []struct {
+		fn    func(interface{}) (string, error)
+		input interface{}
+		want  string
+	}{
+		{humanize, 0, "0"},
+		{humanize, 1234567.0, "1.235M"},
+		{humanize, "12345", "12.35k"},
+		{humanize, 0.0012, "1.2m"},
+		{humanize, -2500, "-2.5k"},
+		{humanize, math.Inf(1), "+Inf"},
+		{humanizeBytes, 512, "512B"},
+		{humanizeBytes, 1536, "1.5KiB"},
+		{humanizeBytes, 3 * 1024 * 1024 * 1024.0, "3GiB"},
+		{humanizeDuration, 0, "0s"},
+		{humanizeDuration, 1.5, "1.5s"},
+		{humanizeDuration, 90061, "1d 1h 1m 1s"},
+		{humanizeDuration, 3725, "1h 2m 5s"},
+		{humanizeDuration, -65, "-1m 5s"},
+		{humanizeDuration, 0.25, "250ms"},
+		{humanizePercentage, 0.9312, "93.12%"},
+		{humanizePercentage, "1", "100%"},
+	}
+	for _, tc := range testCases {
+		got, err := tc.fn(tc.input)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", tc.input, err)
+		} else if got != tc.want {
+			t.Errorf("%v: got %q, want %q", tc.input, got, tc.want)
+		}
+	}
+
+	if _, err := humanize(struct{}{}); err == nil {
+		t.Error("humanize(struct{}{}) = nil, want error")
+	}
+	if got, err := reReplaceAll(`(.*):[0-9]+`, "$1", "10.0.0.1:9100"); err != nil || got != "10.0.0.1" {
+		t.Errorf(`reReplaceAll() = %q, %v, want "10.0.0.1"`, got, err)
+	}
+	if _, err := reReplaceAll(`(`, "", ""); err == nil {
+		t.Error("reReplaceAll() = nil, want error for an invalid regular expression")
+	}
+}
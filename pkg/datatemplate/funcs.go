@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datatemplate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// FuncMap returns the functions available to the templates, named and
+// behaving as those of the Prometheus alerting and console templates.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"humanize":           humanize,
+		"humanizeBytes":      humanizeBytes,
+		"humanizeDuration":   humanizeDuration,
+		"humanizePercentage": humanizePercentage,
+		"label":              label,
+		"value":              value,
+		"reReplaceAll":       reReplaceAll,
+	}
+}
+
+// humanize formats a number with an SI prefix.
+func humanize(i interface{}) (string, error) {
+	v, err := toFloat64(i)
+	if err != nil {
+		return "", err
+	}
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	prefix := ""
+	if math.Abs(v) >= 1 {
+		for _, p := range []string{"k", "M", "G", "T", "P", "E", "Z", "Y"} {
+			if math.Abs(v) < 1000 {
+				break
+			}
+			prefix = p
+			v /= 1000
+		}
+		return fmt.Sprintf("%.4g%s", v, prefix), nil
+	}
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(v) >= 1 {
+			break
+		}
+		prefix = p
+		v *= 1000
+	}
+	return fmt.Sprintf("%.4g%s", v, prefix), nil
+}
+
+// humanizeBytes formats a number of bytes with a binary prefix.
+func humanizeBytes(i interface{}) (string, error) {
+	v, err := toFloat64(i)
+	if err != nil {
+		return "", err
+	}
+	prefix := ""
+	if !math.IsNaN(v) && !math.IsInf(v, 0) {
+		for _, p := range []string{"Ki", "Mi", "Gi", "Ti", "Pi", "Ei", "Zi", "Yi"} {
+			if math.Abs(v) < 1024 {
+				break
+			}
+			prefix = p
+			v /= 1024
+		}
+	}
+	return fmt.Sprintf("%.4g%sB", v, prefix), nil
+}
+
+// humanizeDuration formats a number of seconds as a duration.
+func humanizeDuration(i interface{}) (string, error) {
+	v, err := toFloat64(i)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Sprintf("%.4g", v), nil
+	}
+	if v == 0 {
+		return fmt.Sprintf("%.4gs", v), nil
+	}
+	if math.Abs(v) >= 1 {
+		sign := ""
+		if v < 0 {
+			sign = "-"
+			v = -v
+		}
+		duration := int64(v)
+		seconds := duration % 60
+		minutes := (duration / 60) % 60
+		hours := (duration / 60 / 60) % 24
+		days := duration / 60 / 60 / 24
+		// Seconds are whole from minutes up.
+		switch {
+		case days != 0:
+			return fmt.Sprintf("%s%dd %dh %dm %ds", sign, days, hours, minutes, seconds), nil
+		case hours != 0:
+			return fmt.Sprintf("%s%dh %dm %ds", sign, hours, minutes, seconds), nil
+		case minutes != 0:
+			return fmt.Sprintf("%s%dm %ds", sign, minutes, seconds), nil
+		}
+		return fmt.Sprintf("%s%.4gs", sign, v), nil
+	}
+	prefix := ""
+	for _, p := range []string{"m", "u", "n", "p", "f", "a", "z", "y"} {
+		if math.Abs(v) >= 1 {
+			break
+		}
+		prefix = p
+		v *= 1000
+	}
+	return fmt.Sprintf("%.4g%ss", v, prefix), nil
+}
+
+// humanizePercentage formats a ratio as a percentage.
+func humanizePercentage(i interface{}) (string, error) {
+	v, err := toFloat64(i)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%.4g%%", v*100), nil
+}
+
+// label returns the value of the label of the series.
+func label(name string, s Series) string {
+	return s.Labels[name]
+}
+
+// value returns the value of the last sample of the series.
+func value(s Series) float64 {
+	return s.Value
+}
+
+// reReplaceAll replaces the matches of the regular expression in text with
+// the replacement, which may refer to the submatches as $1.
+func reReplaceAll(pattern, replacement, text string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(text, replacement), nil
+}
+
+func toFloat64(i interface{}) (float64, error) {
+	switch v := i.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case fmt.Stringer:
+		return strconv.ParseFloat(v.String(), 64)
+	}
+	return 0, fmt.Errorf("cannot convert %v of type %T to a number", i, i)
+}
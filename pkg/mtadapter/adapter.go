@@ -0,0 +1,307 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mtadapter
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
+
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/eventing/pkg/metrics/source"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	prometheusadapter "knative.dev/eventing-prometheus/pkg/adapter"
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
+)
+
+const (
+	// caCertKey is the key of the CA certificate in the CA certificate
+	// ConfigMap of a source.
+	caCertKey = "service-ca.crt"
+)
+
+type envConfig struct {
+	adapter.EnvConfig
+
+	// MaxConcurrentQueries bounds the queries running concurrently against
+	// each Prometheus server, they are not bounded if it is zero.
+	MaxConcurrentQueries int `envconfig:"PROMETHEUS_MAX_CONCURRENT_QUERIES_PER_SERVER" default:"10"`
+}
+
+// runner runs the receive adapter of a source, configured by env.
+type runner struct {
+	env    map[string]string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// muteUntil is the mute-until annotation of the source, which changes
+	// without restarting the receive adapter.
+	mu        sync.Mutex
+	muteUntil string
+}
+
+// setMuteUntil sets the mute-until annotation of the source.
+func (r *runner) setMuteUntil(src *v1alpha1.PrometheusSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.muteUntil = src.Annotations[v1alpha1.MuteUntilAnnotation]
+}
+
+// readMuteUntil returns the time the mute-until annotation of the source mutes
+// it until, zero if it has none.
+func (r *runner) readMuteUntil() (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.muteUntil == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, r.muteUntil)
+}
+
+// stop stops the receive adapter and waits for its evaluation in progress,
+// if any.
+func (r *runner) stop() {
+	r.cancel()
+	<-r.done
+}
+
+// mtAdapter runs the receive adapters of the sources with the shared run
+// mode, which its controller adds, updates and removes as the sources change.
+type mtAdapter struct {
+	ctx        context.Context
+	logger     *zap.SugaredLogger
+	kubeClient kubernetes.Interface
+	recorder   record.EventRecorder
+	reporter   source.StatsReporter
+	servers    *serverLimits
+
+	// newCEClient and newSourceAdapter create the CloudEvents client and the
+	// receive adapter of a source.
+	newCEClient      func(target string, ceOverrides *duckv1.CloudEventOverrides) (cloudevents.Client, error)
+	newSourceAdapter func(ctx context.Context, vars map[string]string, ceClient cloudevents.Client, opts prometheusadapter.SourceOptions) (adapter.Adapter, error)
+
+	mu      sync.Mutex
+	runners map[types.NamespacedName]*runner
+}
+
+func NewEnvConfig() adapter.EnvConfigAccessor {
+	return &envConfig{}
+}
+
+// NewAdapter creates the shared adapter running the receive adapters of the
+// sources with the shared run mode. The CloudEvents client is not used, each
+// source has its own.
+func NewAdapter(ctx context.Context, processed adapter.EnvConfigAccessor, _ cloudevents.Client) adapter.Adapter {
+	logger := logging.FromContext(ctx)
+	env := processed.(*envConfig)
+
+	reporter, err := source.NewStatsReporter()
+	if err != nil {
+		logger.Errorw("Error building statsreporter", zap.Error(err))
+	}
+
+	return &mtAdapter{
+		ctx:        ctx,
+		logger:     logger,
+		kubeClient: kubeclient.Get(ctx),
+		recorder:   prometheusadapter.NewEventRecorder(ctx, ""),
+		reporter:   reporter,
+		servers:    newServerLimits(env.MaxConcurrentQueries),
+		newCEClient: func(target string, ceOverrides *duckv1.CloudEventOverrides) (cloudevents.Client, error) {
+			return adapter.NewCloudEventsClient(target, ceOverrides, reporter)
+		},
+		newSourceAdapter: prometheusadapter.NewSourceAdapter,
+		runners:          make(map[types.NamespacedName]*runner),
+	}
+}
+
+// Start blocks until the context is done, then stops the receive adapters.
+func (a *mtAdapter) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	a.mu.Lock()
+	runners := a.runners
+	a.runners = make(map[types.NamespacedName]*runner)
+	a.mu.Unlock()
+	for _, r := range runners {
+		r.stop()
+	}
+	return nil
+}
+
+// Update runs the receive adapter of the source if the shared adapter runs
+// it, and restarts it when its configuration changed, or stops it otherwise.
+func (a *mtAdapter) Update(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	key := types.NamespacedName{Namespace: src.Namespace, Name: src.Name}
+	if !runsInSharedAdapter(src) {
+		a.Remove(key)
+		return nil
+	}
+
+	env := makeEnv(src)
+	a.mu.Lock()
+	old := a.runners[key]
+	if old != nil && reflect.DeepEqual(old.env, env) {
+		a.mu.Unlock()
+		old.setMuteUntil(src)
+		return nil
+	}
+	delete(a.runners, key)
+	a.mu.Unlock()
+	if old != nil {
+		logging.FromContext(ctx).Infow("Restarting the receive adapter", zap.Any("source", key))
+		old.stop()
+	}
+
+	r, err := a.start(key, src, env)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.runners[key] = r
+	a.mu.Unlock()
+	return nil
+}
+
+// Remove stops the receive adapter of the source, if it is running.
+func (a *mtAdapter) Remove(key types.NamespacedName) {
+	a.mu.Lock()
+	r := a.runners[key]
+	delete(a.runners, key)
+	a.mu.Unlock()
+	if r != nil {
+		a.logger.Infow("Stopping the receive adapter", zap.Any("source", key))
+		r.stop()
+	}
+}
+
+// start starts the receive adapter of the source. The receive adapter
+// queries the Prometheus server with the CA certificate of the source, and
+// sends the events to its sink with its own client. The sources with a token
+// file, admitted before the shared run mode rejected them, are not run: the
+// token file would be the one of the shared adapter.
+func (a *mtAdapter) start(key types.NamespacedName, src *v1alpha1.PrometheusSource, env map[string]string) (*runner, error) {
+	if src.Spec.AuthTokenFile != "" {
+		return nil, controller.NewPermanentError(fmt.Errorf("the shared adapter does not run %s, which has a token file", key))
+	}
+	ceClient, err := a.newCEClient(src.Status.SinkURI.String(), src.Spec.CloudEventOverrides)
+	if err != nil {
+		return nil, fmt.Errorf("error building the cloud event client of %s: %w", key, err)
+	}
+	r := &runner{env: env, done: make(chan struct{})}
+	r.setMuteUntil(src)
+	opts := prometheusadapter.SourceOptions{
+		Transport: a.servers.transport(src.Status.ServerURL.String()),
+		Recorder:  a.recorder,
+		MuteUntil: r.readMuteUntil,
+	}
+	if src.Spec.CACertConfigMap != "" {
+		opts.CACert = a.caCert(src.Namespace, src.Spec.CACertConfigMap)
+	}
+
+	logger := a.logger.With(zap.Any("source", key))
+	ctx, cancel := context.WithCancel(logging.WithLogger(a.ctx, logger))
+	ra, err := a.newSourceAdapter(ctx, env, ceClient, opts)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("error configuring the receive adapter of %s: %w", key, err)
+	}
+
+	r.cancel = cancel
+	go func() {
+		defer close(r.done)
+		logger.Info("Starting the receive adapter")
+		if err := ra.Start(ctx); err != nil {
+			logger.Errorw("The receive adapter failed", zap.Error(err))
+		}
+	}()
+	return r, nil
+}
+
+// caCert returns the function reading the CA certificate from the ConfigMap
+// in the namespace, in place of the one mounted in the pods of the receive
+// adapter.
+func (a *mtAdapter) caCert(namespace, name string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		cm, err := a.kubeClient.CoreV1().ConfigMaps(namespace).Get(a.ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		caCert, ok := cm.Data[caCertKey]
+		if !ok {
+			return nil, fmt.Errorf("the ConfigMap %s/%s has no %s key", namespace, name, caCertKey)
+		}
+		return []byte(caCert), nil
+	}
+}
+
+// runsInSharedAdapter returns whether the shared adapter runs the receive
+// adapter of the source: the source has the shared run mode, is not
+// suspended, and the controller marked it deployed since it last changed.
+func runsInSharedAdapter(src *v1alpha1.PrometheusSource) bool {
+	return src.Spec.RunMode == v1alpha1.RunModeShared &&
+		!src.Spec.Suspend &&
+		src.DeletionTimestamp == nil &&
+		src.Status.ObservedGeneration == src.Generation &&
+		src.Status.GetCondition(v1alpha1.PrometheusConditionDeployed).IsTrue() &&
+		src.Status.SinkURI != nil &&
+		src.Status.ServerURL != nil
+}
+
+// makeEnv returns the environment of the receive adapter of the source, the
+// one of the receive adapter Deployment of a source with the deployment run
+// mode, from the status the controller published.
+func makeEnv(src *v1alpha1.PrometheusSource) map[string]string {
+	eventSource := src.Spec.EventSource
+	if len(src.Status.CloudEventAttributes) > 0 {
+		eventSource = src.Status.CloudEventAttributes[0].Source
+	}
+	args := &resources.ReceiveAdapterArgs{
+		EventSource: eventSource,
+		Source:      src,
+		SinkURI:     src.Status.SinkURI.String(),
+		ServerURL:   src.Status.ServerURL.String(),
+		PromQL:      src.Status.PromQL,
+	}
+	if src.Status.DeadLetterSinkURI != nil {
+		args.DeadLetterSinkURI = src.Status.DeadLetterSinkURI.String()
+	}
+
+	env := map[string]string{
+		"K_SINK": args.SinkURI,
+	}
+	for _, v := range resources.MakeReceiveAdapterEnv(args) {
+		env[v.Name] = v.Value
+	}
+	// The namespace of the pods of the receive adapter.
+	env["NAMESPACE"] = src.Namespace
+	return env
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mtadapter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/eventing/pkg/adapter/v2"
+	adaptertest "knative.dev/eventing/pkg/adapter/v2/test"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+
+	prometheusadapter "knative.dev/eventing-prometheus/pkg/adapter"
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// fakeAdapter is a receive adapter running until its context is done.
+type fakeAdapter struct {
+	vars    map[string]string
+	opts    prometheusadapter.SourceOptions
+	stopped chan struct{}
+}
+
+func (f *fakeAdapter) Start(ctx context.Context) error {
+	<-ctx.Done()
+	close(f.stopped)
+	return nil
+}
+
+// newTestAdapter returns a shared adapter recording the receive adapters it
+// starts.
+func newTestAdapter() (*mtAdapter, func() []*fakeAdapter) {
+	var (
+		mu      sync.Mutex
+		started []*fakeAdapter
+	)
+	logger := zap.NewExample().Sugar()
+	a := &mtAdapter{
+		ctx:     logging.WithLogger(context.Background(), logger),
+		logger:  logger,
+		servers: newServerLimits(2),
+		newCEClient: func(string, *duckv1.CloudEventOverrides) (cloudevents.Client, error) {
+			return adaptertest.NewTestClient(), nil
+		},
+		newSourceAdapter: func(_ context.Context, vars map[string]string, _ cloudevents.Client, opts prometheusadapter.SourceOptions) (adapter.Adapter, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			f := &fakeAdapter{vars: vars, opts: opts, stopped: make(chan struct{})}
+			started = append(started, f)
+			return f, nil
+		},
+		runners: make(map[types.NamespacedName]*runner),
+	}
+	return a, func() []*fakeAdapter {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*fakeAdapter(nil), started...)
+	}
+}
+
+func newSharedSource(opts ...func(*v1alpha1.PrometheusSource)) *v1alpha1.PrometheusSource {
+	src := &v1alpha1.PrometheusSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "test-ns",
+			Name:       "test-name",
+			UID:        "test-uid",
+			Generation: 2,
+		},
+		Spec: v1alpha1.PrometheusSourceSpec{
+			ServerURL: "http://prometheus.example.com/api",
+			PromQL:    "up",
+			Schedule:  "* * * * *",
+			RunMode:   v1alpha1.RunModeShared,
+		},
+	}
+	src.Status.InitializeConditions()
+	src.Status.ObservedGeneration = 2
+	src.Status.MarkSink(apis.HTTP("sink.example.com"))
+	src.Status.PropagateDeploymentAvailability(&appsv1.Deployment{
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentAvailable,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	})
+	src.Status.ServerURL, _ = apis.ParseURL("http://prometheus.example.com/api")
+	src.Status.PromQL = `up{namespace="test-ns"}`
+	for _, opt := range opts {
+		opt(src)
+	}
+	return src
+}
+
+func TestRunsInSharedAdapter(t *testing.T) {
+	testCases := map[string]struct {
+		source *v1alpha1.PrometheusSource
+		want   bool
+	}{
+		"deployed": {
+			source: newSharedSource(),
+			want:   true,
+		},
+		"deployment run mode": {
+			source: newSharedSource(func(s *v1alpha1.PrometheusSource) {
+				s.Spec.RunMode = v1alpha1.RunModeDeployment
+			}),
+		},
+		"suspended": {
+			source: newSharedSource(func(s *v1alpha1.PrometheusSource) {
+				s.Spec.Suspend = true
+			}),
+		},
+		"not observed": {
+			source: newSharedSource(func(s *v1alpha1.PrometheusSource) {
+				s.Generation = 3
+			}),
+		},
+		"not deployed": {
+			source: newSharedSource(func(s *v1alpha1.PrometheusSource) {
+				s.Status.MarkNotDeployed("SharedAdapterNotFound", "")
+			}),
+		},
+		"being deleted": {
+			source: newSharedSource(func(s *v1alpha1.PrometheusSource) {
+				now := metav1.Now()
+				s.DeletionTimestamp = &now
+			}),
+		},
+	}
+
+	for n, tc := range testCases {
+		t.Run(n, func(t *testing.T) {
+			if got := runsInSharedAdapter(tc.source); got != tc.want {
+				t.Errorf("runsInSharedAdapter() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMakeEnv(t *testing.T) {
+	src := newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Status.CloudEventAttributes = []duckv1.CloudEventAttributes{{
+			Type:   v1alpha1.PromQLPrometheusSourceEventType,
+			Source: "http://prometheus.example.com/api",
+		}}
+	})
+	env := makeEnv(src)
+
+	want := map[string]string{
+		"K_SINK":                "http://sink.example.com",
+		"NAMESPACE":             "test-ns",
+		"NAME":                  "test-name",
+		"EVENT_SOURCE":          "http://prometheus.example.com/api",
+		"PROMETHEUS_SERVER_URL": "http://prometheus.example.com/api",
+		"PROMETHEUS_PROM_QL":    `up{namespace="test-ns"}`,
+		"PROMETHEUS_SOURCE_UID": "test-uid",
+	}
+	got := make(map[string]string, len(want))
+	for k := range want {
+		got[k] = env[k]
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Error("makeEnv() (-want, +got) =", diff)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	a, started := newTestAdapter()
+	key := types.NamespacedName{Namespace: "test-ns", Name: "test-name"}
+
+	if err := a.Update(ctx, newSharedSource()); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if got := len(started()); got != 1 {
+		t.Fatalf("started %d receive adapters, want 1", got)
+	}
+	first := started()[0]
+	if first.opts.Transport == nil {
+		t.Error("the queries of the source are not bounded per server")
+	}
+	if first.opts.CACert != nil {
+		t.Error("the source has no CA certificate")
+	}
+
+	// An update not changing the receive adapter does not restart it.
+	if err := a.Update(ctx, newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Labels = map[string]string{"team": "a"}
+	})); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if got := len(started()); got != 1 {
+		t.Fatalf("started %d receive adapters, want 1", got)
+	}
+
+	// Neither does muting it, which the receive adapter reads on every
+	// evaluation.
+	if err := a.Update(ctx, newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Annotations = map[string]string{v1alpha1.MuteUntilAnnotation: "2022-03-01T18:00:00Z"}
+	})); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	if got := len(started()); got != 1 {
+		t.Fatalf("started %d receive adapters, want 1", got)
+	}
+	if got, err := first.opts.MuteUntil(); err != nil || !got.Equal(time.Date(2022, time.March, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("MuteUntil() = %s, %v, want 2022-03-01T18:00:00Z", got, err)
+	}
+
+	// A new schedule is hot-reloaded.
+	if err := a.Update(ctx, newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Spec.Schedule = "*/5 * * * *"
+		s.Spec.CACertConfigMap = "serving-ca"
+	})); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	<-first.stopped
+	if got := len(started()); got != 2 {
+		t.Fatalf("started %d receive adapters, want 2", got)
+	}
+	second := started()[1]
+	if got, want := second.vars["PROMETHEUS_SCHEDULE"], "*/5 * * * *"; got != want {
+		t.Errorf("PROMETHEUS_SCHEDULE = %q, want %q", got, want)
+	}
+	if second.opts.CACert == nil {
+		t.Error("the CA certificate of the source is not isolated")
+	}
+
+	// Suspending the source stops its receive adapter.
+	if err := a.Update(ctx, newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Spec.Suspend = true
+	})); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	<-second.stopped
+	if _, ok := a.runners[key]; ok {
+		t.Error("the receive adapter of the suspended source is still running")
+	}
+
+	// Neither is a source with a token file, admitted before the shared run
+	// mode rejected them.
+	if err := a.Update(ctx, newSharedSource(func(s *v1alpha1.PrometheusSource) {
+		s.Spec.AuthTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	})); !controller.IsPermanentError(err) {
+		t.Errorf("Update() = %v, want a permanent error", err)
+	}
+	if got := len(started()); got != 2 {
+		t.Fatalf("started %d receive adapters, want 2", got)
+	}
+
+	if err := a.Update(ctx, newSharedSource()); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	third := started()[2]
+	a.Remove(key)
+	<-third.stopped
+
+	if err := a.Update(ctx, newSharedSource()); err != nil {
+		t.Fatal("Update() =", err)
+	}
+	fourth := started()[3]
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := a.Start(ctx); err != nil {
+		t.Fatal("Start() =", err)
+	}
+	<-fourth.stopped
+}
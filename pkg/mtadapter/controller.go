@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mtadapter
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/eventing/pkg/adapter/v2"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
+	pkgreconciler "knative.dev/pkg/reconciler"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	prometheusinformer "knative.dev/eventing-prometheus/pkg/client/injection/informers/sources/v1alpha1/prometheussource"
+	promreconciler "knative.dev/eventing-prometheus/pkg/client/injection/reconciler/sources/v1alpha1/prometheussource"
+)
+
+const (
+	// controllerAgentName is the string used by the shared adapter to
+	// identify itself when creating events.
+	controllerAgentName = "prometheus-source-mt-adapter"
+)
+
+// Reconciler updates the receive adapters the shared adapter runs as the
+// sources change. It does not update the sources, their status is the one of
+// the controller.
+type Reconciler struct {
+	adapter *mtAdapter
+}
+
+// Check that our Reconciler implements ReconcileKind and ObserveDeletion.
+var _ promreconciler.Interface = (*Reconciler)(nil)
+var _ pkgreconciler.OnDeletionInterface = (*Reconciler)(nil)
+
+// NewController initializes the controller of the shared adapter, which
+// watches every PrometheusSource. The sources switching to another run mode
+// are watched as well, for their receive adapter to be stopped.
+func NewController(ctx context.Context, a adapter.Adapter) *controller.Impl {
+	mt, ok := a.(*mtAdapter)
+	if !ok {
+		logging.FromContext(ctx).Fatalf("Unexpected adapter %T", a)
+	}
+
+	r := &Reconciler{adapter: mt}
+	impl := promreconciler.NewImpl(ctx, r, func(*controller.Impl) controller.Options {
+		return controller.Options{
+			AgentName:         controllerAgentName,
+			SkipStatusUpdates: true,
+		}
+	})
+
+	prometheusinformer.Get(ctx).Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
+
+	return impl
+}
+
+// ReconcileKind runs, restarts or stops the receive adapter of the source.
+func (r *Reconciler) ReconcileKind(ctx context.Context, source *v1alpha1.PrometheusSource) pkgreconciler.Event {
+	return r.adapter.Update(ctx, source)
+}
+
+// ObserveDeletion stops the receive adapter of the deleted source.
+func (r *Reconciler) ObserveDeletion(ctx context.Context, key types.NamespacedName) error {
+	r.adapter.Remove(key)
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mtadapter
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// serverLimits bound the queries running concurrently against each
+// Prometheus server, shared by the receive adapters of every source querying
+// it.
+type serverLimits struct {
+	max int
+
+	mu   sync.Mutex
+	sems map[string]chan struct{}
+}
+
+func newServerLimits(max int) *serverLimits {
+	return &serverLimits{
+		max:  max,
+		sems: make(map[string]chan struct{}),
+	}
+}
+
+// transport returns the function wrapping the transport of the queries
+// against the server, nil if the queries are not bounded.
+func (l *serverLimits) transport(serverURL string) func(http.RoundTripper) http.RoundTripper {
+	if l.max <= 0 {
+		return nil
+	}
+	sem := l.semaphore(serverKey(serverURL))
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &limitedTransport{sem: sem, next: next}
+	}
+}
+
+func (l *serverLimits) semaphore(key string) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[key]
+	if !ok {
+		sem = make(chan struct{}, l.max)
+		l.sems[key] = sem
+	}
+	return sem
+}
+
+// serverKey identifies the server by the scheme and host of its URL, the
+// sources querying it through different paths share its limit.
+func serverKey(serverURL string) string {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return serverURL
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// limitedTransport holds a slot of the semaphore of the server from the
+// request of a query until its response is read.
+type limitedTransport struct {
+	sem  chan struct{}
+	next http.RoundTripper
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-t.sem }
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releasingBody releases the slot of the query once its response is closed.
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
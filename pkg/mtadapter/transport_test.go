@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mtadapter
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func okTransport(*http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestServerLimits(t *testing.T) {
+	if got := newServerLimits(0).transport("http://prometheus.example.com"); got != nil {
+		t.Error("transport() wraps the transport of unbounded queries")
+	}
+
+	l := newServerLimits(1)
+	first := l.transport("http://prometheus.example.com/api")(roundTripperFunc(okTransport))
+	second := l.transport("http://prometheus.example.com/other")(roundTripperFunc(okTransport))
+	other := l.transport("http://thanos.example.com")(roundTripperFunc(okTransport))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://prometheus.example.com/api", nil)
+	resp, err := first.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+
+	// The response of the first query is not read yet.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := second.RoundTrip(req.WithContext(ctx)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Another server has its own limit.
+	otherResp, err := other.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	otherResp.Body.Close()
+
+	resp.Body.Close()
+	resp, err = second.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	resp.Body.Close()
+
+	// The slot of a failed query is released.
+	failing := l.transport("http://prometheus.example.com")(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))
+	if _, err := failing.RoundTrip(req); err == nil {
+		t.Error("RoundTrip() = nil, want an error")
+	}
+	resp, err = first.RoundTrip(req)
+	if err != nil {
+		t.Fatal("RoundTrip() =", err)
+	}
+	resp.Body.Close()
+}
@@ -19,17 +19,29 @@ package reconciler
 import (
 	"context"
 
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
+	apisconfig "knative.dev/eventing-prometheus/pkg/apis/config"
 	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
 	"knative.dev/eventing/pkg/reconciler/source"
 	"knative.dev/pkg/configmap"
 	"knative.dev/pkg/controller"
+	"knative.dev/pkg/logging"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
 	prometheusinformer "knative.dev/eventing-prometheus/pkg/client/injection/informers/sources/v1alpha1/prometheussource"
 	promreconciler "knative.dev/eventing-prometheus/pkg/client/injection/reconciler/sources/v1alpha1/prometheussource"
 	kubeclient "knative.dev/pkg/client/injection/kube/client"
 	deploymentinformer "knative.dev/pkg/client/injection/kube/informers/apps/v1/deployment"
+	cronjobinformer "knative.dev/pkg/client/injection/kube/informers/batch/v1/cronjob"
+	serviceaccountinformer "knative.dev/pkg/client/injection/kube/informers/core/v1/serviceaccount"
+	roleinformer "knative.dev/pkg/client/injection/kube/informers/rbac/v1/role"
+	rolebindinginformer "knative.dev/pkg/client/injection/kube/informers/rbac/v1/rolebinding"
 )
 
 const (
@@ -45,14 +57,36 @@ func NewController(
 	cmw configmap.Watcher,
 ) *controller.Impl {
 	deploymentInformer := deploymentinformer.Get(ctx)
+	cronJobInformer := cronjobinformer.Get(ctx)
+	serviceAccountInformer := serviceaccountinformer.Get(ctx)
+	roleInformer := roleinformer.Get(ctx)
+	roleBindingInformer := rolebindinginformer.Get(ctx)
 	prometheusSourceInformer := prometheusinformer.Get(ctx)
+	eventInformer := getEventInformer(ctx)
+	podInformer := getPodInformer(ctx)
+	leaseInformer := getLeaseInformer(ctx)
 
 	r := &Reconciler{
-		kubeClientSet:    kubeclient.Get(ctx),
-		deploymentLister: deploymentInformer.Lister(),
-		configs:          source.WatchConfigurations(ctx, controllerAgentName, cmw),
+		kubeClientSet:        kubeclient.Get(ctx),
+		deploymentLister:     deploymentInformer.Lister(),
+		cronJobLister:        cronJobInformer.Lister(),
+		serviceAccountLister: serviceAccountInformer.Lister(),
+		roleLister:           roleInformer.Lister(),
+		roleBindingLister:    roleBindingInformer.Lister(),
+		eventLister:          eventInformer.Lister(),
+		podLister:            podInformer.Lister(),
+		leaseLister:          leaseInformer.Lister(),
+		configs:              source.WatchConfigurations(ctx, controllerAgentName, cmw),
 	}
-	impl := promreconciler.NewImpl(ctx, r)
+	impl := promreconciler.NewImpl(ctx, r, func(impl *controller.Impl) controller.Options {
+		// The server policy enforced at admission time is checked again on
+		// every reconciliation, as it may have changed since.
+		store := apisconfig.NewStore(logging.FromContext(ctx).Named("config-store"), func(string, interface{}) {
+			impl.GlobalResync(prometheusSourceInformer.Informer())
+		})
+		store.WatchConfigs(cmw)
+		return controller.Options{ConfigStore: store}
+	})
 	r.sinkResolver = resolver.NewURIResolverFromTracker(ctx, impl.Tracker)
 
 	prometheusSourceInformer.Informer().AddEventHandler(controller.HandleAll(impl.Enqueue))
@@ -62,5 +96,58 @@ func NewController(
 		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
 	})
 
+	// The status of the CronJob of a source with the cronJob run mode changes
+	// when its Jobs finish.
+	cronJobInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterController(&v1alpha1.PrometheusSource{}),
+		Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+	})
+
+	for _, informer := range []cache.SharedIndexInformer{serviceAccountInformer.Informer(), roleInformer.Informer(), roleBindingInformer.Informer()} {
+		informer.AddEventHandler(cache.FilteringResourceEventHandler{
+			FilterFunc: controller.FilterController(&v1alpha1.PrometheusSource{}),
+			Handler:    controller.HandleAll(impl.EnqueueControllerOf),
+		})
+	}
+
+	// The conditions of a source follow the Kubernetes events its receive
+	// adapter records on it.
+	eventInformer.Informer().AddEventHandler(controller.HandleAll(func(obj interface{}) {
+		if e, ok := obj.(*corev1.Event); ok && e.InvolvedObject.Kind == "PrometheusSource" {
+			impl.EnqueueKey(types.NamespacedName{Namespace: e.InvolvedObject.Namespace, Name: e.InvolvedObject.Name})
+		}
+	}))
+
+	// The pods of the receive adapter start without the mute-until
+	// annotation of their source.
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: impl.EnqueueLabelOfNamespaceScopedResource("", resources.SourceNameLabel),
+	})
+
+	// The Leases are renewed every few seconds, the leader election of a
+	// source only changes with their holder.
+	leaseInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterController(&v1alpha1.PrometheusSource{}),
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc: impl.EnqueueControllerOf,
+			UpdateFunc: func(oldObj, newObj interface{}) {
+				oldLease, newLease := oldObj.(*coordinationv1.Lease), newObj.(*coordinationv1.Lease)
+				if !equality.Semantic.DeepEqual(oldLease.Spec.HolderIdentity, newLease.Spec.HolderIdentity) {
+					impl.EnqueueControllerOf(newObj)
+				}
+			},
+			DeleteFunc: impl.EnqueueControllerOf,
+		},
+	})
+
+	// The sources with the shared run mode are deployed when the shared
+	// adapter is available.
+	deploymentInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: controller.FilterWithNameAndNamespace(system.Namespace(), resources.SharedAdapterName),
+		Handler: controller.HandleAll(func(interface{}) {
+			impl.GlobalResync(prometheusSourceInformer.Informer())
+		}),
+	})
+
 	return impl
 }
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeinformers "k8s.io/client-go/informers"
+	coordinationv1informers "k8s.io/client-go/informers/coordination/v1"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	kubeclient "knative.dev/pkg/client/injection/kube/client"
+	"knative.dev/pkg/controller"
+	"knative.dev/pkg/injection"
+	"knative.dev/pkg/logging"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
+)
+
+// The informers of the Kubernetes events, the pods and the Leases of the
+// receive adapters only watch the objects of the receive adapters. They are
+// registered with injection, so that they are started, and their caches
+// synced, before the controller starts.
+func init() {
+	injection.Default.RegisterInformer(withEventInformer)
+	injection.Default.RegisterInformer(withPodInformer)
+	injection.Default.RegisterInformer(withLeaseInformer)
+}
+
+type eventInformerKey struct{}
+type podInformerKey struct{}
+type leaseInformerKey struct{}
+
+// adapterInformerFactory returns an informer factory of the objects matching
+// the list options.
+func adapterInformerFactory(ctx context.Context, tweak func(*metav1.ListOptions)) kubeinformers.SharedInformerFactory {
+	return kubeinformers.NewSharedInformerFactoryWithOptions(kubeclient.Get(ctx), controller.GetResyncPeriod(ctx),
+		kubeinformers.WithTweakListOptions(tweak))
+}
+
+func withEventInformer(ctx context.Context) (context.Context, controller.Informer) {
+	// Only the Kubernetes events recorded by the receive adapters are watched.
+	inf := adapterInformerFactory(ctx, func(opts *metav1.ListOptions) {
+		opts.FieldSelector = fields.OneTermEqualSelector("source", v1alpha1.AdapterEventComponent).String()
+	}).Core().V1().Events()
+	return context.WithValue(ctx, eventInformerKey{}, inf), inf.Informer()
+}
+
+func withPodInformer(ctx context.Context) (context.Context, controller.Informer) {
+	// Only the pods and the Leases of the receive adapters are watched.
+	inf := adapterInformerFactory(ctx, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = resources.Selector().String()
+	}).Core().V1().Pods()
+	return context.WithValue(ctx, podInformerKey{}, inf), inf.Informer()
+}
+
+func withLeaseInformer(ctx context.Context) (context.Context, controller.Informer) {
+	inf := adapterInformerFactory(ctx, func(opts *metav1.ListOptions) {
+		opts.LabelSelector = resources.Selector().String()
+	}).Coordination().V1().Leases()
+	return context.WithValue(ctx, leaseInformerKey{}, inf), inf.Informer()
+}
+
+// getEventInformer extracts the informer of the Kubernetes events of the
+// receive adapters from the context.
+func getEventInformer(ctx context.Context) corev1informers.EventInformer {
+	untyped := ctx.Value(eventInformerKey{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic("Unable to fetch the event informer from context.")
+	}
+	return untyped.(corev1informers.EventInformer)
+}
+
+// getPodInformer extracts the informer of the pods of the receive adapters
+// from the context.
+func getPodInformer(ctx context.Context) corev1informers.PodInformer {
+	untyped := ctx.Value(podInformerKey{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic("Unable to fetch the pod informer from context.")
+	}
+	return untyped.(corev1informers.PodInformer)
+}
+
+// getLeaseInformer extracts the informer of the Leases of the receive
+// adapters from the context.
+func getLeaseInformer(ctx context.Context) coordinationv1informers.LeaseInformer {
+	untyped := ctx.Value(leaseInformerKey{})
+	if untyped == nil {
+		logging.FromContext(ctx).Panic("Unable to fetch the Lease informer from context.")
+	}
+	return untyped.(coordinationv1informers.LeaseInformer)
+}
@@ -18,7 +18,9 @@ package reconciler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"knative.dev/eventing/pkg/utils"
 
@@ -30,17 +32,25 @@ import (
 	"github.com/robfig/cron"
 	"go.uber.org/zap"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	coordinationv1listers "k8s.io/client-go/listers/coordination/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
 	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
 	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 	"knative.dev/pkg/logging"
 	pkgreconciler "knative.dev/pkg/reconciler"
 	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
 
 	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
 	promreconciler "knative.dev/eventing-prometheus/pkg/client/injection/reconciler/sources/v1alpha1/prometheussource"
@@ -51,6 +61,26 @@ const (
 	prometheussourceDeploymentCreated = "PrometheusSourceDeploymentCreated"
 	prometheussourceDeploymentUpdated = "PrometheusSourceDeploymentUpdated"
 	prometheussourceDeploymentDeleted = "PrometheusSourceDeploymentDeleted"
+	prometheussourceCronJobCreated    = "PrometheusSourceCronJobCreated"
+	prometheussourceCronJobUpdated    = "PrometheusSourceCronJobUpdated"
+	prometheussourceCronJobDeleted    = "PrometheusSourceCronJobDeleted"
+	prometheussourceRBACCreated       = "PrometheusSourceRBACCreated"
+	prometheussourceRBACUpdated       = "PrometheusSourceRBACUpdated"
+	prometheussourceRBACDeleted       = "PrometheusSourceRBACDeleted"
+	prometheussourceServerNotAllowed  = "PrometheusSourceServerNotAllowed"
+	prometheussourceQueryNotScopable  = "PrometheusSourceQueryNotScopable"
+)
+
+const (
+	// rateLimitedWindow is how long a source stays rate limited after its
+	// receive adapter last suppressed events, unless its rate limit interval
+	// is longer.
+	rateLimitedWindow = 5 * time.Minute
+
+	// resultLimitExceededWindow is how long a source stays marked exceeding
+	// its result limits after its receive adapter last read a query result
+	// exceeding them.
+	resultLimitExceededWindow = 5 * time.Minute
 )
 
 type envConfig struct {
@@ -62,10 +92,21 @@ type Reconciler struct {
 	kubeClientSet kubernetes.Interface
 
 	// listers index properties about resources
-	deploymentLister appsv1listers.DeploymentLister
+	deploymentLister     appsv1listers.DeploymentLister
+	cronJobLister        batchv1listers.CronJobLister
+	serviceAccountLister corev1listers.ServiceAccountLister
+	roleLister           rbacv1listers.RoleLister
+	roleBindingLister    rbacv1listers.RoleBindingLister
+	// eventLister lists the Kubernetes events recorded by the receive
+	// adapters on their sources, podLister the pods of the receive adapters.
+	eventLister corev1listers.EventLister
+	podLister   corev1listers.PodLister
+	// leaseLister lists the Leases the replicas of the receive adapters elect
+	// the one evaluating the query with.
+	leaseLister coordinationv1listers.LeaseLister
 
 	sinkResolver *resolver.URIResolver
-	configs      *source.ConfigWatcher
+	configs      source.ConfigAccessor
 }
 
 var _ promreconciler.Interface = (*Reconciler)(nil)
@@ -95,6 +136,30 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, source *v1alpha1.Prometh
 	}
 	source.Status.MarkSink(sinkURI)
 
+	if err := r.resolveDeadLetterSink(ctx, source); err != nil {
+		source.Status.MarkDeadLetterSinkNotResolved("NotFound", "%v", err)
+		return err
+	}
+
+	serverURL, err := r.resolveServerURL(ctx, source)
+	if err != nil {
+		source.Status.MarkServerNotResolved("NotFound", "%v", err)
+		return err
+	}
+
+	if fe := source.ValidateServerPolicy(ctx, serverURL); fe != nil {
+		source.Status.MarkServerNotAllowed("PolicyViolation", "%v", fe)
+		return r.rejectSource(ctx, source, prometheussourceServerNotAllowed, "Rejected by the cluster server policy: %v", fe)
+	}
+	source.Status.MarkServerAllowed()
+
+	promQL, err := source.ScopedPromQL(ctx)
+	if err != nil {
+		source.Status.MarkInvalidQuery("NotScopable", "%v", err)
+		return r.rejectSource(ctx, source, prometheussourceQueryNotScopable, "Query cannot be restricted to the namespace: %v", err)
+	}
+	source.Status.MarkValidQuery()
+
 	_, err = cron.ParseStandard(source.Spec.Schedule)
 	if err != nil {
 		source.Status.MarkInvalidSchedule("Invalid", "Reason: "+err.Error())
@@ -102,23 +167,287 @@ func (r *Reconciler) ReconcileKind(ctx context.Context, source *v1alpha1.Prometh
 	}
 	source.Status.MarkValidSchedule()
 
-	ra, err := r.createReceiveAdapter(ctx, source, sinkURI)
-	if err != nil {
-		logging.FromContext(ctx).Errorw("Unable to create the receive adapter", zap.Error(err))
-		return err
+	if source.Spec.Suspend {
+		source.Status.MarkSuspended(metav1.Now())
+	} else {
+		source.Status.MarkResumed(metav1.Now())
+	}
+
+	// The shared adapter configures the receive adapters it runs from the
+	// status.
+	source.Status.ServerURL, _ = apis.ParseURL(serverURL)
+	source.Status.PromQL = promQL
+
+	adapterArgs := r.makeReceiveAdapterArgs(ctx, source, sinkURI, serverURL, promQL)
+	switch source.Spec.RunMode {
+	case v1alpha1.RunModeCronJob:
+		if err := r.reconcileReceiveAdapterRBAC(ctx, source); err != nil {
+			return err
+		}
+		cj, err := r.createReceiveAdapterCronJob(ctx, source, adapterArgs)
+		if err != nil {
+			logging.FromContext(ctx).Errorw("Unable to create the receive adapter CronJob", zap.Error(err))
+			return err
+		}
+		if err := r.deleteReceiveAdapter(ctx, source); err != nil {
+			return err
+		}
+		if err := r.annotateReceiveAdapterPods(ctx, source); err != nil {
+			return err
+		}
+		source.Status.PropagateCronJobStatus(cj)
+		source.Status.MarkNoLeaderElection()
+	case v1alpha1.RunModeShared:
+		if err := r.deleteReceiveAdapter(ctx, source); err != nil {
+			return err
+		}
+		if err := r.deleteReceiveAdapterCronJob(ctx, source); err != nil {
+			return err
+		}
+		// The shared adapter records the events on the source itself.
+		if err := r.deleteReceiveAdapterRBAC(ctx, source); err != nil {
+			return err
+		}
+		if err := r.propagateSharedAdapterAvailability(source); err != nil {
+			return err
+		}
+		source.Status.MarkNoCronJob()
+		source.Status.MarkNoLeaderElection()
+	default:
+		if err := r.reconcileReceiveAdapterRBAC(ctx, source); err != nil {
+			return err
+		}
+		ra, err := r.createReceiveAdapter(ctx, source, adapterArgs)
+		if err != nil {
+			logging.FromContext(ctx).Errorw("Unable to create the receive adapter", zap.Error(err))
+			return err
+		}
+		if err := r.deleteReceiveAdapterCronJob(ctx, source); err != nil {
+			return err
+		}
+		if err := r.annotateReceiveAdapterPods(ctx, source); err != nil {
+			return err
+		}
+		// Update source status
+		source.Status.PropagateDeploymentAvailability(ra)
+		source.Status.MarkNoCronJob()
+		if err := r.propagateLeaderElection(source); err != nil {
+			return err
+		}
 	}
-	// Update source status// Update source status
-	source.Status.PropagateDeploymentAvailability(ra)
 
 	source.Status.CloudEventAttributes = []duckv1.CloudEventAttributes{{
-		Type:   v1alpha1.PromQLPrometheusSourceEventType,
+		Type:   source.Spec.GetEventType(),
 		Source: r.makeEventSource(source),
 	}}
+	if source.Spec.Limits != nil && source.Spec.Limits.OverflowPolicy == v1alpha1.OverflowPolicyDrop {
+		source.Status.CloudEventAttributes = append(source.Status.CloudEventAttributes, duckv1.CloudEventAttributes{
+			Type:   source.Spec.GetErrorEventType(),
+			Source: r.makeEventSource(source),
+		})
+	}
+	if source.Spec.RateLimit != nil {
+		source.Status.CloudEventAttributes = append(source.Status.CloudEventAttributes, duckv1.CloudEventAttributes{
+			Type:   source.Spec.GetRateLimitedEventType(),
+			Source: r.makeEventSource(source),
+		})
+	}
+	if source.Spec.OnEmptyResult == v1alpha1.EmptyResultPolicyEmitNoData {
+		source.Status.CloudEventAttributes = append(source.Status.CloudEventAttributes, duckv1.CloudEventAttributes{
+			Type:   source.Spec.GetNoDataEventType(),
+			Source: r.makeEventSource(source),
+		}, duckv1.CloudEventAttributes{
+			Type:   source.Spec.GetDataReturnedEventType(),
+			Source: r.makeEventSource(source),
+		})
+	}
+
+	// The conditions derived from the Kubernetes events recorded by the
+	// receive adapter turn back once the events are old enough, which is when
+	// the source is reconciled again.
+	limitsRecheck, err := r.propagateResultLimits(source)
+	if err != nil {
+		return err
+	}
+	rateLimitRecheck, err := r.propagateRateLimit(source)
+	if err != nil {
+		return err
+	}
+	if recheck := limitsRecheck; recheck > 0 || rateLimitRecheck > 0 {
+		if recheck == 0 || (rateLimitRecheck > 0 && rateLimitRecheck < recheck) {
+			recheck = rateLimitRecheck
+		}
+		return controller.NewRequeueAfter(recheck)
+	}
+	return nil
+}
+
+// propagateResultLimits marks the source exceeding its result limits if its
+// receive adapter recently recorded a query result exceeding them. It returns
+// how long until the condition of a source exceeding its limits turns back,
+// zero otherwise.
+func (r *Reconciler) propagateResultLimits(source *v1alpha1.PrometheusSource) (time.Duration, error) {
+	if source.Spec.Limits == nil {
+		source.Status.MarkNoResultLimits()
+		return 0, nil
+	}
+	last, lastTime, err := r.lastAdapterEvent(source, v1alpha1.ResultLimitExceededReason)
+	if err != nil {
+		return 0, err
+	}
+	if last != nil {
+		if remaining := resultLimitExceededWindow - time.Since(lastTime); remaining > 0 {
+			source.Status.MarkResultLimitExceeded("LimitExceeded", "%s", last.Message)
+			return remaining, nil
+		}
+	}
+	source.Status.MarkWithinResultLimits()
+	return 0, nil
+}
+
+// lastAdapterEvent returns the latest Kubernetes event with the reason the
+// receive adapter recorded on the source, and when it last occurred, nil if
+// there is none.
+func (r *Reconciler) lastAdapterEvent(source *v1alpha1.PrometheusSource, reason string) (*corev1.Event, time.Time, error) {
+	events, err := r.eventLister.Events(source.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error listing the events of the source: %v", err)
+	}
+	var last *corev1.Event
+	var lastTime time.Time
+	for _, e := range events {
+		if e.InvolvedObject.UID != source.UID || e.Reason != reason {
+			continue
+		}
+		t := e.LastTimestamp.Time
+		if t.IsZero() {
+			t = e.EventTime.Time
+		}
+		if last == nil || t.After(lastTime) {
+			last, lastTime = e, t
+		}
+	}
+	return last, lastTime, nil
+}
+
+// propagateLeaderElection marks whether a replica of the receive adapter of
+// the source holds its Lease, without which none evaluates the query.
+func (r *Reconciler) propagateLeaderElection(source *v1alpha1.PrometheusSource) error {
+	if source.Spec.Suspend || !resources.ElectsLeader(source) {
+		source.Status.MarkNoLeaderElection()
+		return nil
+	}
+	name := resources.MakeReceiveAdapterName(source)
+	lease, err := r.leaseLister.Leases(source.Namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		// The receive adapter creates the Lease once it runs.
+		if source.Status.GetCondition(v1alpha1.PrometheusConditionDeployed).IsTrue() {
+			source.Status.MarkNoLeader("LeaseNotFound", "The receive adapter did not create the Lease '%s'.", name)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting the receive adapter Lease: %v", err)
+	}
+
+	var holder string
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	switch {
+	case holder == "":
+		source.Status.MarkNoLeader("NoLeader", "No replica holds the Lease '%s'.", name)
+	case lease.Spec.RenewTime != nil && lease.Spec.LeaseDurationSeconds != nil &&
+		time.Since(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds)*time.Second)) > 0:
+		source.Status.MarkNoLeader("LeaseExpired", "The replica '%s' has not renewed the Lease '%s' since %s.",
+			holder, name, lease.Spec.RenewTime.UTC().Format(time.RFC3339))
+	default:
+		source.Status.MarkLeaderElected(holder)
+	}
+	return nil
+}
 
+// propagateSharedAdapterAvailability marks the source with the shared run
+// mode deployed when the shared adapter, which watches it, is available.
+func (r *Reconciler) propagateSharedAdapterAvailability(source *v1alpha1.PrometheusSource) error {
+	ra, err := r.deploymentLister.Deployments(system.Namespace()).Get(resources.SharedAdapterName)
+	if apierrors.IsNotFound(err) {
+		source.Status.MarkNotDeployed("SharedAdapterNotFound", "The shared adapter Deployment '%s/%s' was not found.",
+			system.Namespace(), resources.SharedAdapterName)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting the shared adapter: %v", err)
+	}
+	source.Status.PropagateDeploymentAvailability(ra)
 	return nil
 }
 
-func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.PrometheusSource, sinkURI *apis.URL) (*appsv1.Deployment, error) {
+// propagateRateLimit marks the source rate limited if its receive adapter
+// recently recorded that it suppressed events exceeding the rate limit. It
+// returns how long until the condition of a rate limited source turns back,
+// zero otherwise.
+func (r *Reconciler) propagateRateLimit(source *v1alpha1.PrometheusSource) (time.Duration, error) {
+	if source.Spec.RateLimit == nil {
+		source.Status.MarkNoRateLimit()
+		return 0, nil
+	}
+	last, lastTime, err := r.lastAdapterEvent(source, v1alpha1.RateLimitedReason)
+	if err != nil {
+		return 0, err
+	}
+	window := rateLimitedWindow
+	if interval := source.Spec.RateLimit.Interval; interval != nil && interval.Duration > window {
+		window = interval.Duration
+	}
+	if last != nil {
+		if remaining := window - time.Since(lastTime); remaining > 0 {
+			source.Status.MarkRateLimited("EventsSuppressed", "%s", last.Message)
+			return remaining, nil
+		}
+	}
+	source.Status.MarkWithinRateLimit()
+	return 0, nil
+}
+
+// resolveDeadLetterSink resolves the dead letter sink of the delivery spec of
+// the source, if it has one, into its status.
+func (r *Reconciler) resolveDeadLetterSink(ctx context.Context, source *v1alpha1.PrometheusSource) error {
+	if source.Spec.Delivery == nil || source.Spec.Delivery.DeadLetterSink == nil {
+		source.Status.MarkNoDeadLetterSink()
+		return nil
+	}
+	dest := source.Spec.Delivery.DeadLetterSink.DeepCopy()
+	if dest.Ref != nil && dest.Ref.Namespace == "" {
+		dest.Ref.Namespace = source.GetNamespace()
+	}
+	uri, err := r.sinkResolver.URIFromDestinationV1(ctx, *dest, source)
+	if err != nil {
+		return err
+	}
+	source.Status.MarkDeadLetterSinkResolved(uri)
+	return nil
+}
+
+// resolveServerURL returns the URL of the Prometheus server of the source,
+// resolving its server reference if it has one.
+func (r *Reconciler) resolveServerURL(ctx context.Context, source *v1alpha1.PrometheusSource) (string, error) {
+	if source.Spec.ServerRef == nil {
+		return source.Spec.ServerURL, nil
+	}
+	ref := source.Spec.ServerRef.DeepCopy()
+	if ref.Namespace == "" {
+		ref.Namespace = source.GetNamespace()
+	}
+	serverURL, err := r.sinkResolver.URIFromDestinationV1(ctx, duckv1.Destination{Ref: ref}, source)
+	if err != nil {
+		return "", err
+	}
+	source.Status.MarkServerResolved()
+	return serverURL.String(), nil
+}
+
+// makeReceiveAdapterArgs returns the arguments of the receive adapter of the
+// source, whichever its run mode.
+func (r *Reconciler) makeReceiveAdapterArgs(ctx context.Context, src *v1alpha1.PrometheusSource, sinkURI *apis.URL, serverURL, promQL string) *resources.ReceiveAdapterArgs {
 	eventSource := r.makeEventSource(src)
 	logging.FromContext(ctx).Debug("event source", zap.Any("source", eventSource))
 
@@ -134,8 +463,17 @@ func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.Pro
 		Labels:         resources.Labels(src.Name),
 		SinkURI:        sinkURI.String(),
 		AdditionalEnvs: r.configs.ToEnvVars(),
+		ServerURL:      serverURL,
+		PromQL:         promQL,
+	}
+	if src.Status.DeadLetterSinkURI != nil {
+		adapterArgs.DeadLetterSinkURI = src.Status.DeadLetterSinkURI.String()
 	}
-	expected := resources.MakeReceiveAdapter(&adapterArgs)
+	return &adapterArgs
+}
+
+func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.PrometheusSource, adapterArgs *resources.ReceiveAdapterArgs) (*appsv1.Deployment, error) {
+	expected := resources.MakeReceiveAdapter(adapterArgs)
 
 	ra, err := r.kubeClientSet.AppsV1().Deployments(src.Namespace).Get(ctx, expected.Name, metav1.GetOptions{})
 	if apierrors.IsNotFound(err) {
@@ -154,8 +492,9 @@ func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.Pro
 		return nil, fmt.Errorf("error getting receive adapter: %v", err)
 	} else if !metav1.IsControlledBy(ra, src) {
 		return nil, fmt.Errorf("deployment %q is not owned by PrometheusSource %q", ra.Name, src.Name)
-	} else if r.podSpecChanged(ra.Spec.Template.Spec, expected.Spec.Template.Spec) {
+	} else if r.podSpecChanged(ra.Spec.Template.Spec, expected.Spec.Template.Spec) || !equality.Semantic.DeepEqual(ra.Spec.Replicas, expected.Spec.Replicas) {
 		ra.Spec.Template.Spec = expected.Spec.Template.Spec
+		ra.Spec.Replicas = expected.Spec.Replicas
 		if ra, err = r.kubeClientSet.AppsV1().Deployments(src.Namespace).Update(ctx, ra, metav1.UpdateOptions{}); err != nil {
 			return ra, err
 		}
@@ -167,6 +506,251 @@ func (r *Reconciler) createReceiveAdapter(ctx context.Context, src *v1alpha1.Pro
 	return ra, nil
 }
 
+// createReceiveAdapterCronJob creates or updates the CronJob running the
+// receive adapter of a source with the cronJob run mode.
+func (r *Reconciler) createReceiveAdapterCronJob(ctx context.Context, src *v1alpha1.PrometheusSource, adapterArgs *resources.ReceiveAdapterArgs) (*batchv1.CronJob, error) {
+	expected := resources.MakeReceiveAdapterCronJob(adapterArgs)
+
+	cj, err := r.cronJobLister.CronJobs(src.Namespace).Get(expected.Name)
+	if apierrors.IsNotFound(err) {
+		cj, err = r.kubeClientSet.BatchV1().CronJobs(src.Namespace).Create(ctx, expected, metav1.CreateOptions{})
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceCronJobCreated, "CronJob created, error: %v", err)
+		return cj, err
+	} else if err != nil {
+		return nil, fmt.Errorf("error getting receive adapter CronJob: %v", err)
+	} else if !metav1.IsControlledBy(cj, src) {
+		return nil, fmt.Errorf("cronjob %q is not owned by PrometheusSource %q", cj.Name, src.Name)
+	} else if r.cronJobSpecChanged(cj.Spec, expected.Spec) {
+		cj = cj.DeepCopy()
+		cj.Spec.Schedule = expected.Spec.Schedule
+		cj.Spec.StartingDeadlineSeconds = expected.Spec.StartingDeadlineSeconds
+		cj.Spec.ConcurrencyPolicy = expected.Spec.ConcurrencyPolicy
+		cj.Spec.Suspend = expected.Spec.Suspend
+		cj.Spec.JobTemplate = expected.Spec.JobTemplate
+		if cj, err = r.kubeClientSet.BatchV1().CronJobs(src.Namespace).Update(ctx, cj, metav1.UpdateOptions{}); err != nil {
+			return cj, err
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceCronJobUpdated, "CronJob updated")
+		return cj, nil
+	} else {
+		logging.FromContext(ctx).Debug("Reusing existing receive adapter CronJob", zap.Any("cronJob", cj))
+	}
+	return cj, nil
+}
+
+// annotateReceiveAdapterPods sets the mute-until annotation of the source on
+// the pods of its receive adapter, or removes it, without rolling them out.
+// The receive adapter reads it from the annotations of its pod.
+func (r *Reconciler) annotateReceiveAdapterPods(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	pods, err := r.podLister.Pods(src.Namespace).List(labels.SelectorFromSet(resources.Labels(src.Name)))
+	if err != nil {
+		return fmt.Errorf("error listing the receive adapter pods: %v", err)
+	}
+	value, ok := resources.MakeReceiveAdapterPodAnnotations(src)[v1alpha1.MuteUntilAnnotation]
+	for _, pod := range pods {
+		if current, has := pod.Annotations[v1alpha1.MuteUntilAnnotation]; ok == has && value == current {
+			continue
+		}
+		// A null value removes the annotation.
+		var annotation interface{}
+		if ok {
+			annotation = value
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{v1alpha1.MuteUntilAnnotation: annotation},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = r.kubeClientSet.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error annotating the receive adapter pod %q: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// cronJobSpecChanged returns true if the CronJob running the receive adapter
+// must be updated to the expected spec.
+func (r *Reconciler) cronJobSpecChanged(oldSpec, newSpec batchv1.CronJobSpec) bool {
+	return oldSpec.Schedule != newSpec.Schedule ||
+		oldSpec.ConcurrencyPolicy != newSpec.ConcurrencyPolicy ||
+		!equality.Semantic.DeepEqual(oldSpec.StartingDeadlineSeconds, newSpec.StartingDeadlineSeconds) ||
+		!equality.Semantic.DeepEqual(oldSpec.Suspend, newSpec.Suspend) ||
+		!equality.Semantic.DeepDerivative(newSpec.JobTemplate.ObjectMeta, oldSpec.JobTemplate.ObjectMeta) ||
+		!equality.Semantic.DeepEqual(newSpec.JobTemplate.Spec.Template.Annotations, oldSpec.JobTemplate.Spec.Template.Annotations) ||
+		r.podSpecChanged(oldSpec.JobTemplate.Spec.Template.Spec, newSpec.JobTemplate.Spec.Template.Spec)
+}
+
+// reconcileReceiveAdapterRBAC creates or updates the Role and RoleBinding
+// granting the service account the receive adapter runs as what it needs,
+// and the service account of its own of a source without one.
+func (r *Reconciler) reconcileReceiveAdapterRBAC(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	if src.Spec.ServiceAccountName == "" {
+		if err := r.reconcileReceiveAdapterServiceAccount(ctx, src); err != nil {
+			return err
+		}
+	} else if err := r.deleteReceiveAdapterServiceAccount(ctx, src); err != nil {
+		return err
+	}
+
+	expectedRole := resources.MakeReceiveAdapterRole(src)
+	role, err := r.roleLister.Roles(src.Namespace).Get(expectedRole.Name)
+	if apierrors.IsNotFound(err) {
+		if _, err := r.kubeClientSet.RbacV1().Roles(src.Namespace).Create(ctx, expectedRole, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("error creating receive adapter Role: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACCreated, "Role created: \"%s/%s\"", src.Namespace, expectedRole.Name)
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter Role: %v", err)
+	} else if !metav1.IsControlledBy(role, src) {
+		return fmt.Errorf("role %q is not owned by PrometheusSource %q", role.Name, src.Name)
+	} else if !equality.Semantic.DeepEqual(role.Rules, expectedRole.Rules) {
+		role = role.DeepCopy()
+		role.Rules = expectedRole.Rules
+		if _, err := r.kubeClientSet.RbacV1().Roles(src.Namespace).Update(ctx, role, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error updating receive adapter Role: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACUpdated, "Role updated: \"%s/%s\"", src.Namespace, role.Name)
+	}
+
+	expectedBinding := resources.MakeReceiveAdapterRoleBinding(src)
+	binding, err := r.roleBindingLister.RoleBindings(src.Namespace).Get(expectedBinding.Name)
+	if apierrors.IsNotFound(err) {
+		if _, err := r.kubeClientSet.RbacV1().RoleBindings(src.Namespace).Create(ctx, expectedBinding, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("error creating receive adapter RoleBinding: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACCreated, "RoleBinding created: \"%s/%s\"", src.Namespace, expectedBinding.Name)
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter RoleBinding: %v", err)
+	} else if !metav1.IsControlledBy(binding, src) {
+		return fmt.Errorf("rolebinding %q is not owned by PrometheusSource %q", binding.Name, src.Name)
+	} else if !equality.Semantic.DeepEqual(binding.Subjects, expectedBinding.Subjects) {
+		binding = binding.DeepCopy()
+		binding.Subjects = expectedBinding.Subjects
+		if _, err := r.kubeClientSet.RbacV1().RoleBindings(src.Namespace).Update(ctx, binding, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("error updating receive adapter RoleBinding: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACUpdated, "RoleBinding updated: \"%s/%s\"", src.Namespace, binding.Name)
+	}
+	return nil
+}
+
+// reconcileReceiveAdapterServiceAccount creates the service account of its
+// own the receive adapter of the source runs as.
+func (r *Reconciler) reconcileReceiveAdapterServiceAccount(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	expected := resources.MakeReceiveAdapterServiceAccount(src)
+	sa, err := r.serviceAccountLister.ServiceAccounts(src.Namespace).Get(expected.Name)
+	if apierrors.IsNotFound(err) {
+		if _, err := r.kubeClientSet.CoreV1().ServiceAccounts(src.Namespace).Create(ctx, expected, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("error creating receive adapter ServiceAccount: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACCreated, "ServiceAccount created: \"%s/%s\"", src.Namespace, expected.Name)
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter ServiceAccount: %v", err)
+	} else if !metav1.IsControlledBy(sa, src) {
+		return fmt.Errorf("serviceaccount %q is not owned by PrometheusSource %q", sa.Name, src.Name)
+	}
+	return nil
+}
+
+// deleteReceiveAdapterServiceAccount deletes the service account of its own
+// of the receive adapter of the source, if any, when it no longer runs as it.
+func (r *Reconciler) deleteReceiveAdapterServiceAccount(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	name := resources.MakeReceiveAdapterName(src)
+	sa, err := r.serviceAccountLister.ServiceAccounts(src.Namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error getting receive adapter ServiceAccount: %v", err)
+	} else if err == nil && metav1.IsControlledBy(sa, src) {
+		if err := r.kubeClientSet.CoreV1().ServiceAccounts(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting receive adapter ServiceAccount: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACDeleted, "ServiceAccount deleted: \"%s/%s\"", src.Namespace, name)
+	}
+	return nil
+}
+
+// deleteReceiveAdapterRBAC deletes the Role, RoleBinding and service account
+// of the receive adapter of the source, if any, when it no longer has a
+// receive adapter of its own.
+func (r *Reconciler) deleteReceiveAdapterRBAC(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	name := resources.MakeReceiveAdapterName(src)
+	binding, err := r.roleBindingLister.RoleBindings(src.Namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error getting receive adapter RoleBinding: %v", err)
+	} else if err == nil && metav1.IsControlledBy(binding, src) {
+		if err := r.kubeClientSet.RbacV1().RoleBindings(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting receive adapter RoleBinding: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACDeleted, "RoleBinding deleted: \"%s/%s\"", src.Namespace, name)
+	}
+	role, err := r.roleLister.Roles(src.Namespace).Get(name)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error getting receive adapter Role: %v", err)
+	} else if err == nil && metav1.IsControlledBy(role, src) {
+		if err := r.kubeClientSet.RbacV1().Roles(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("error deleting receive adapter Role: %v", err)
+		}
+		controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceRBACDeleted, "Role deleted: \"%s/%s\"", src.Namespace, name)
+	}
+	return r.deleteReceiveAdapterServiceAccount(ctx, src)
+}
+
+// rejectSource deletes the receive adapter of a source the cluster policies
+// no longer allow, records why on the source and returns a permanent error.
+func (r *Reconciler) rejectSource(ctx context.Context, src *v1alpha1.PrometheusSource, reason, messageFormat string, err error) error {
+	if err := r.deleteReceiveAdapter(ctx, src); err != nil {
+		return err
+	}
+	if err := r.deleteReceiveAdapterCronJob(ctx, src); err != nil {
+		return err
+	}
+	controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeWarning, reason, messageFormat, err)
+	return controller.NewPermanentError(err)
+}
+
+// deleteReceiveAdapter deletes the receive adapter of the source, if any, so
+// that it stops querying Prometheus.
+func (r *Reconciler) deleteReceiveAdapter(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	name := resources.MakeReceiveAdapterName(src)
+	ra, err := r.deploymentLister.Deployments(src.Namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter: %v", err)
+	} else if !metav1.IsControlledBy(ra, src) {
+		return nil
+	}
+	if err := r.kubeClientSet.AppsV1().Deployments(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting receive adapter: %v", err)
+	}
+	controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceDeploymentDeleted, "Deployment deleted: \"%s/%s\"", src.Namespace, name)
+	return nil
+}
+
+// deleteReceiveAdapterCronJob deletes the CronJob running the receive adapter
+// of the source, if any, when it no longer has the cronJob run mode.
+func (r *Reconciler) deleteReceiveAdapterCronJob(ctx context.Context, src *v1alpha1.PrometheusSource) error {
+	name := resources.MakeReceiveAdapterName(src)
+	cj, err := r.cronJobLister.CronJobs(src.Namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("error getting receive adapter CronJob: %v", err)
+	} else if !metav1.IsControlledBy(cj, src) {
+		return nil
+	}
+	// The Jobs of the CronJob are deleted with it.
+	propagation := metav1.DeletePropagationBackground
+	if err := r.kubeClientSet.BatchV1().CronJobs(src.Namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("error deleting receive adapter CronJob: %v", err)
+	}
+	controller.GetEventRecorder(ctx).Eventf(src, corev1.EventTypeNormal, prometheussourceCronJobDeleted, "CronJob deleted: \"%s/%s\"", src.Namespace, name)
+	return nil
+}
+
 func (r *Reconciler) podSpecChanged(oldPodSpec corev1.PodSpec, newPodSpec corev1.PodSpec) bool {
 	if !equality.Semantic.DeepDerivative(newPodSpec, oldPodSpec) {
 		return true
@@ -184,5 +768,8 @@ func (r *Reconciler) podSpecChanged(oldPodSpec corev1.PodSpec, newPodSpec corev1
 
 // makeEventSource computes the Cloud Event source attribute for the given source
 func (r *Reconciler) makeEventSource(src *v1alpha1.PrometheusSource) string {
+	if src.Spec.EventSource != "" {
+		return src.Spec.EventSource
+	}
 	return src.Namespace + "/" + src.Name
 }
@@ -0,0 +1,638 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgotesting "k8s.io/client-go/testing"
+	eventingduckv1 "knative.dev/eventing/pkg/apis/duck/v1"
+	"knative.dev/eventing/pkg/reconciler/source"
+	"knative.dev/eventing/pkg/utils"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+	"knative.dev/pkg/client/injection/ducks/duck/v1/addressable"
+	_ "knative.dev/pkg/client/injection/ducks/duck/v1/addressable/fake"
+	fakekubeclient "knative.dev/pkg/client/injection/kube/client/fake"
+	"knative.dev/pkg/configmap"
+	"knative.dev/pkg/controller"
+	logtesting "knative.dev/pkg/logging/testing"
+	"knative.dev/pkg/ptr"
+	. "knative.dev/pkg/reconciler/testing"
+	"knative.dev/pkg/resolver"
+	"knative.dev/pkg/system"
+	_ "knative.dev/pkg/system/testing"
+	"knative.dev/pkg/tracker"
+
+	"knative.dev/eventing-prometheus/pkg/apis/config"
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	fakeprometheusclient "knative.dev/eventing-prometheus/pkg/client/injection/client/fake"
+	promreconciler "knative.dev/eventing-prometheus/pkg/client/injection/reconciler/sources/v1alpha1/prometheussource"
+	"knative.dev/eventing-prometheus/pkg/reconciler/resources"
+	. "knative.dev/eventing-prometheus/pkg/reconciler/testing"
+)
+
+const (
+	sourceName = "test-prometheus-source"
+	sourceUID  = "1234"
+	testNS     = "testnamespace"
+
+	testImage     = "github.com/knative/test/image"
+	testServerURL = "https://prometheus.monitoring.svc:9090"
+	testPromQL    = `up{job="prometheus"}`
+	testSchedule  = "* * * * *"
+	testHolder    = "prometheussource-replica-0"
+)
+
+var (
+	sinkURI           = apis.HTTP("sink.example.com")
+	deadLetterSinkURI = apis.HTTP("dls.example.com")
+	serverURL, _      = apis.ParseURL(testServerURL)
+	eventAttributes   = duckv1.CloudEventAttributes{
+		Type:   v1alpha1.PromQLPrometheusSourceEventType,
+		Source: testNS + "/" + sourceName,
+	}
+	scheduleTime = metav1.NewTime(time.Date(2022, 1, 24, 13, 0, 0, 0, time.UTC))
+)
+
+func TestReconcile(t *testing.T) {
+	os.Setenv("PROMETHEUS_RA_IMAGE", testImage)
+
+	deploymentSpec := makeSpec()
+	replicatedSpec := makeSpec()
+	replicatedSpec.Replicas = ptr.Int32(2)
+	cronJobSpec := makeSpec()
+	cronJobSpec.RunMode = v1alpha1.RunModeCronJob
+	sharedSpec := makeSpec()
+	sharedSpec.RunMode = v1alpha1.RunModeShared
+	suspendedSpec := makeSpec()
+	suspendedSpec.Suspend = true
+	deadLetterSpec := makeSpec()
+	deadLetterSpec.Delivery = &eventingduckv1.DeliverySpec{
+		DeadLetterSink: &duckv1.Destination{URI: deadLetterSinkURI},
+	}
+	rateLimitSpec := makeSpec()
+	rateLimitSpec.RateLimit = &v1alpha1.PrometheusSourceRateLimit{Events: 10}
+	unscopableSpec := makeSpec()
+	unscopableSpec.PromQL = `up{namespace="other"}`
+
+	rescheduledSpec := makeSpec()
+	rescheduledSpec.RunMode = v1alpha1.RunModeCronJob
+	rescheduledSpec.Schedule = "*/5 * * * *"
+
+	restricted := policyContext(map[string]string{"allowed-servers": "https://prometheus.other.svc"}, nil)
+	scoping := policyContext(nil, map[string]string{"namespace-scoping": "true"})
+	notAllowed := makeSource(deploymentSpec).ValidateServerPolicy(restricted, testServerURL)
+	_, notScopable := makeSource(unscopableSpec).ScopedPromQL(scoping)
+
+	table := TableTest{{
+		Name: "bad workqueue key",
+		// Make sure Reconcile handles bad keys.
+		Key: "too/many/parts",
+	}, {
+		Name: "key not found",
+		// Make sure Reconcile handles good keys that don't exist.
+		Key: "foo/not-found",
+	}, {
+		Name: "deployment created",
+		Objects: []runtime.Object{
+			makeSource(deploymentSpec),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			serviceAccountCreatedEvent(),
+			roleCreatedEvent(),
+			roleBindingCreatedEvent(),
+			deprecatedDeploymentDeletedEvent(),
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentCreated, "Deployment created, error: <nil>"),
+		},
+		WantCreates: []runtime.Object{
+			resources.MakeReceiveAdapterServiceAccount(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(deploymentSpec)),
+			makeDeployment(deploymentSpec, false),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", deprecatedDeploymentName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(deploymentSpec,
+				WithPrometheusSourceDeployment(makeDeployment(deploymentSpec, false)),
+			),
+		}},
+	}, {
+		Name: "replicated deployment available with an elected leader",
+		Objects: []runtime.Object{
+			makeSource(replicatedSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(replicatedSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(replicatedSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(replicatedSpec)),
+			makeDeployment(replicatedSpec, true),
+			makeLease(),
+		},
+		Key: testNS + "/" + sourceName,
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(replicatedSpec,
+				WithPrometheusSourceDeployment(makeDeployment(replicatedSpec, true)),
+				WithPrometheusSourceLeaderElected(testHolder),
+			),
+		}},
+	}, {
+		Name: "dead letter sink resolved",
+		Objects: []runtime.Object{
+			makeSource(deadLetterSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(deadLetterSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(deadLetterSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(deadLetterSpec)),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			deprecatedDeploymentDeletedEvent(),
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentCreated, "Deployment created, error: <nil>"),
+		},
+		WantCreates: []runtime.Object{
+			makeDeployment(deadLetterSpec, false),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", deprecatedDeploymentName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(deadLetterSpec,
+				WithPrometheusSourceDeployment(makeDeployment(deadLetterSpec, false)),
+			),
+		}},
+	}, {
+		Name: "suspended deployment scaled down",
+		Objects: []runtime.Object{
+			makeSource(suspendedSpec, WithPrometheusSourceSuspended(scheduleTime)),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(suspendedSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(suspendedSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(suspendedSpec)),
+			makeDeployment(deploymentSpec, true),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentUpdated, "Deployment updated"),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeDeployment(suspendedSpec, true),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(suspendedSpec,
+				WithPrometheusSourceSuspended(scheduleTime),
+				WithPrometheusSourceDeployment(makeDeployment(suspendedSpec, true)),
+			),
+		}},
+	}, {
+		Name: "rate limited",
+		Objects: []runtime.Object{
+			makeSource(rateLimitSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(rateLimitSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(rateLimitSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(rateLimitSpec)),
+			makeDeployment(rateLimitSpec, true),
+			makeAdapterEvent(v1alpha1.RateLimitedReason, "Suppressed 3 events exceeding the rate limit.", time.Now().Add(-time.Minute)),
+		},
+		Key: testNS + "/" + sourceName,
+		// The source is reconciled again when the condition turns back.
+		WantErr: true,
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(rateLimitSpec,
+				WithPrometheusSourceDeployment(makeDeployment(rateLimitSpec, true)),
+				WithPrometheusSourceEventAttributes(eventAttributes, duckv1.CloudEventAttributes{
+					Type:   rateLimitSpec.GetRateLimitedEventType(),
+					Source: testNS + "/" + sourceName,
+				}),
+				WithPrometheusSourceRateLimited("Suppressed 3 events exceeding the rate limit."),
+			),
+		}},
+	}, {
+		Name: "rate limit window expired",
+		Objects: []runtime.Object{
+			makeSource(rateLimitSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(rateLimitSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(rateLimitSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(rateLimitSpec)),
+			makeDeployment(rateLimitSpec, true),
+			makeAdapterEvent(v1alpha1.RateLimitedReason, "Suppressed 3 events exceeding the rate limit.", time.Now().Add(-time.Hour)),
+		},
+		Key: testNS + "/" + sourceName,
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(rateLimitSpec,
+				WithPrometheusSourceDeployment(makeDeployment(rateLimitSpec, true)),
+				WithPrometheusSourceEventAttributes(eventAttributes, duckv1.CloudEventAttributes{
+					Type:   rateLimitSpec.GetRateLimitedEventType(),
+					Source: testNS + "/" + sourceName,
+				}),
+				WithPrometheusSourceWithinRateLimit,
+			),
+		}},
+	}, {
+		Name: "deployment switched to a cronjob",
+		Objects: []runtime.Object{
+			makeSource(cronJobSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(deploymentSpec)),
+			makeDeployment(deploymentSpec, true),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceCronJobCreated, "CronJob created, error: <nil>"),
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentDeleted, "Deployment deleted: \"%s/%s\"", testNS, adapterName()),
+		},
+		WantCreates: []runtime.Object{
+			makeCronJob(cronJobSpec, batchv1.CronJobStatus{}),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", adapterName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(cronJobSpec,
+				WithPrometheusSourceCronJob(makeCronJob(cronJobSpec, batchv1.CronJobStatus{})),
+			),
+		}},
+	}, {
+		Name: "cronjob updated",
+		Objects: []runtime.Object{
+			makeSource(cronJobSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(cronJobSpec)),
+			makeCronJob(rescheduledSpec, runningCronJobStatus()),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceCronJobUpdated, "CronJob updated"),
+		},
+		WantUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeCronJob(cronJobSpec, runningCronJobStatus()),
+		}},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(cronJobSpec,
+				WithPrometheusSourceCronJob(makeCronJob(cronJobSpec, runningCronJobStatus())),
+			),
+		}},
+	}, {
+		Name: "cronjob last run succeeded",
+		Objects: []runtime.Object{
+			makeSource(cronJobSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(cronJobSpec)),
+			makeCronJob(cronJobSpec, succeededCronJobStatus()),
+		},
+		Key: testNS + "/" + sourceName,
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(cronJobSpec,
+				WithPrometheusSourceCronJob(makeCronJob(cronJobSpec, succeededCronJobStatus())),
+			),
+		}},
+	}, {
+		Name: "cronjob switched to a deployment",
+		Objects: []runtime.Object{
+			makeSource(deploymentSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(cronJobSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(cronJobSpec)),
+			makeCronJob(cronJobSpec, succeededCronJobStatus()),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			deprecatedDeploymentDeletedEvent(),
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentCreated, "Deployment created, error: <nil>"),
+			Eventf(corev1.EventTypeNormal, prometheussourceCronJobDeleted, "CronJob deleted: \"%s/%s\"", testNS, adapterName()),
+		},
+		WantCreates: []runtime.Object{
+			makeDeployment(deploymentSpec, false),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", deprecatedDeploymentName()),
+			deleteAction("cronjobs", adapterName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(deploymentSpec,
+				WithPrometheusSourceDeployment(makeDeployment(deploymentSpec, false)),
+			),
+		}},
+	}, {
+		Name: "shared adapter available",
+		Objects: []runtime.Object{
+			makeSource(sharedSpec),
+			makeSharedAdapter(),
+		},
+		Key: testNS + "/" + sourceName,
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(sharedSpec,
+				WithPrometheusSourceDeployment(makeSharedAdapter()),
+			),
+		}},
+	}, {
+		Name: "deployment switched to the missing shared adapter",
+		Objects: []runtime.Object{
+			makeSource(sharedSpec),
+			resources.MakeReceiveAdapterServiceAccount(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRole(makeSource(deploymentSpec)),
+			resources.MakeReceiveAdapterRoleBinding(makeSource(deploymentSpec)),
+			makeDeployment(deploymentSpec, true),
+		},
+		Key: testNS + "/" + sourceName,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentDeleted, "Deployment deleted: \"%s/%s\"", testNS, adapterName()),
+			Eventf(corev1.EventTypeNormal, prometheussourceRBACDeleted, "RoleBinding deleted: \"%s/%s\"", testNS, adapterName()),
+			Eventf(corev1.EventTypeNormal, prometheussourceRBACDeleted, "Role deleted: \"%s/%s\"", testNS, adapterName()),
+			Eventf(corev1.EventTypeNormal, prometheussourceRBACDeleted, "ServiceAccount deleted: \"%s/%s\"", testNS, adapterName()),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", adapterName()),
+			deleteAction("rolebindings", adapterName()),
+			deleteAction("roles", adapterName()),
+			deleteAction("serviceaccounts", adapterName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeReconciledSource(sharedSpec,
+				WithPrometheusSourceNotDeployed("SharedAdapterNotFound", "The shared adapter Deployment '%s/%s' was not found.",
+					system.Namespace(), resources.SharedAdapterName),
+			),
+		}},
+	}, {
+		Name: "server not allowed",
+		Ctx:  restricted,
+		Objects: []runtime.Object{
+			makeSource(deploymentSpec),
+			makeDeployment(deploymentSpec, true),
+		},
+		Key:     testNS + "/" + sourceName,
+		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceDeploymentDeleted, "Deployment deleted: \"%s/%s\"", testNS, adapterName()),
+			Eventf(corev1.EventTypeWarning, prometheussourceServerNotAllowed, "Rejected by the cluster server policy: %v", notAllowed),
+			Eventf(corev1.EventTypeWarning, "InternalError", "%v", notAllowed),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("deployments", adapterName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeSource(deploymentSpec,
+				WithInitPrometheusSourceConditions,
+				WithPrometheusSourceSink(sinkURI),
+				WithPrometheusSourceServerNotAllowed("%v", notAllowed),
+			),
+		}},
+	}, {
+		Name: "query not scopable",
+		Ctx:  scoping,
+		Objects: []runtime.Object{
+			makeSource(unscopableSpec),
+			makeCronJob(cronJobSpec, succeededCronJobStatus()),
+		},
+		Key:     testNS + "/" + sourceName,
+		WantErr: true,
+		WantEvents: []string{
+			Eventf(corev1.EventTypeNormal, prometheussourceCronJobDeleted, "CronJob deleted: \"%s/%s\"", testNS, adapterName()),
+			Eventf(corev1.EventTypeWarning, prometheussourceQueryNotScopable, "Query cannot be restricted to the namespace: %v", notScopable),
+			Eventf(corev1.EventTypeWarning, "InternalError", "%v", notScopable),
+		},
+		WantDeletes: []clientgotesting.DeleteActionImpl{
+			deleteAction("cronjobs", adapterName()),
+		},
+		WantStatusUpdates: []clientgotesting.UpdateActionImpl{{
+			Object: makeSource(unscopableSpec,
+				WithInitPrometheusSourceConditions,
+				WithPrometheusSourceSink(sinkURI),
+				WithPrometheusSourceServerAllowed,
+				WithPrometheusSourceInvalidQuery("%v", notScopable),
+			),
+		}},
+	}}
+
+	logger := logtesting.TestLogger(t)
+	table.Test(t, MakeFactory(func(ctx context.Context, listers *Listers, cmw configmap.Watcher) controller.Reconciler {
+		ctx = addressable.WithDuck(ctx)
+		r := &Reconciler{
+			kubeClientSet:        fakekubeclient.Get(ctx),
+			deploymentLister:     listers.GetDeploymentLister(),
+			cronJobLister:        listers.GetCronJobLister(),
+			serviceAccountLister: listers.GetServiceAccountLister(),
+			roleLister:           listers.GetRoleLister(),
+			roleBindingLister:    listers.GetRoleBindingLister(),
+			eventLister:          listers.GetEventLister(),
+			podLister:            listers.GetPodLister(),
+			leaseLister:          listers.GetLeaseLister(),
+			sinkResolver:         resolver.NewURIResolverFromTracker(ctx, tracker.New(func(types.NamespacedName) {}, 0)),
+			configs:              &source.EmptyVarsGenerator{},
+		}
+		return promreconciler.NewReconciler(ctx, logger,
+			fakeprometheusclient.Get(ctx), listers.GetPrometheusSourceLister(),
+			controller.GetEventRecorder(ctx), r)
+	}, logger))
+}
+
+// policyContext attaches the cluster server and query policies parsed from
+// the data to the context.
+func policyContext(serverPolicy, queryPolicy map[string]string) context.Context {
+	cfg := config.FromContextOrDefaults(context.Background())
+	if serverPolicy != nil {
+		cfg.ServerPolicy, _ = config.NewServerPolicyFromMap(serverPolicy)
+	}
+	if queryPolicy != nil {
+		cfg.QueryPolicy, _ = config.NewQueryPolicyFromMap(queryPolicy)
+	}
+	return config.ToContext(context.Background(), cfg)
+}
+
+func makeSpec() v1alpha1.PrometheusSourceSpec {
+	return v1alpha1.PrometheusSourceSpec{
+		ServerURL: testServerURL,
+		PromQL:    testPromQL,
+		Schedule:  testSchedule,
+		Sink:      &duckv1.Destination{URI: sinkURI},
+	}
+}
+
+func makeSource(spec v1alpha1.PrometheusSourceSpec, o ...PrometheusSourceOption) *v1alpha1.PrometheusSource {
+	return NewPrometheusSource(sourceName, testNS, append([]PrometheusSourceOption{
+		WithPrometheusSourceUID(sourceUID),
+		WithPrometheusSourceSpec(spec),
+	}, o...)...)
+}
+
+// makeReconciledSource returns the source with the status the reconciler
+// sets before propagating the status of the receive adapter, followed by the
+// options.
+func makeReconciledSource(spec v1alpha1.PrometheusSourceSpec, o ...PrometheusSourceOption) *v1alpha1.PrometheusSource {
+	reconciled := []PrometheusSourceOption{
+		WithInitPrometheusSourceConditions,
+		WithPrometheusSourceSink(sinkURI),
+	}
+	if spec.Delivery != nil {
+		reconciled = append(reconciled, WithPrometheusSourceDeadLetterSink(deadLetterSinkURI))
+	}
+	reconciled = append(reconciled,
+		WithPrometheusSourceServerAllowed,
+		WithPrometheusSourceValidQuery,
+		WithPrometheusSourceValidSchedule,
+		WithPrometheusSourceQuery(serverURL, spec.PromQL),
+		WithPrometheusSourceEventAttributes(eventAttributes),
+	)
+	return makeSource(spec, append(reconciled, o...)...)
+}
+
+func makeReceiveAdapterArgs(spec v1alpha1.PrometheusSourceSpec) *resources.ReceiveAdapterArgs {
+	args := &resources.ReceiveAdapterArgs{
+		EventSource:    testNS + "/" + sourceName,
+		Image:          testImage,
+		Source:         makeSource(spec),
+		Labels:         resources.Labels(sourceName),
+		SinkURI:        sinkURI.String(),
+		AdditionalEnvs: (&source.EmptyVarsGenerator{}).ToEnvVars(),
+		ServerURL:      testServerURL,
+		PromQL:         spec.PromQL,
+	}
+	if spec.Delivery != nil {
+		args.DeadLetterSinkURI = deadLetterSinkURI.String()
+	}
+	return args
+}
+
+func makeDeployment(spec v1alpha1.PrometheusSourceSpec, available bool) *appsv1.Deployment {
+	d := resources.MakeReceiveAdapter(makeReceiveAdapterArgs(spec))
+	if available {
+		d.Status.Conditions = []appsv1.DeploymentCondition{{
+			Type:   appsv1.DeploymentAvailable,
+			Status: corev1.ConditionTrue,
+		}}
+	}
+	return d
+}
+
+func makeCronJob(spec v1alpha1.PrometheusSourceSpec, status batchv1.CronJobStatus) *batchv1.CronJob {
+	cj := resources.MakeReceiveAdapterCronJob(makeReceiveAdapterArgs(spec))
+	cj.Status = status
+	return cj
+}
+
+func runningCronJobStatus() batchv1.CronJobStatus {
+	return batchv1.CronJobStatus{
+		Active:           []corev1.ObjectReference{{Name: fmt.Sprintf("%s-%d", adapterName(), scheduleTime.Unix()/60)}},
+		LastScheduleTime: &scheduleTime,
+	}
+}
+
+func succeededCronJobStatus() batchv1.CronJobStatus {
+	return batchv1.CronJobStatus{
+		LastScheduleTime:   &scheduleTime,
+		LastSuccessfulTime: &scheduleTime,
+	}
+}
+
+func makeSharedAdapter() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: system.Namespace(),
+			Name:      resources.SharedAdapterName,
+		},
+		Status: appsv1.DeploymentStatus{
+			Conditions: []appsv1.DeploymentCondition{{
+				Type:   appsv1.DeploymentAvailable,
+				Status: corev1.ConditionTrue,
+			}},
+		},
+	}
+}
+
+func makeLease() *coordinationv1.Lease {
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNS,
+			Name:      adapterName(),
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.String(testHolder),
+			LeaseDurationSeconds: ptr.Int32(3600),
+			RenewTime:            &metav1.MicroTime{Time: time.Now()},
+		},
+	}
+}
+
+// makeAdapterEvent returns a Kubernetes event the receive adapter recorded
+// on the source.
+func makeAdapterEvent(reason, message string, last time.Time) *corev1.Event {
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: testNS,
+			Name:      sourceName + "." + reason,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:      "PrometheusSource",
+			Namespace: testNS,
+			Name:      sourceName,
+			UID:       sourceUID,
+		},
+		Reason:        reason,
+		Message:       message,
+		LastTimestamp: metav1.NewTime(last),
+		Source:        corev1.EventSource{Component: v1alpha1.AdapterEventComponent},
+	}
+}
+
+func adapterName() string {
+	return resources.MakeReceiveAdapterName(makeSource(makeSpec()))
+}
+
+func deprecatedDeploymentName() string {
+	return utils.GenerateFixedName(makeSource(makeSpec()), "prometheussource-"+sourceName)
+}
+
+func deleteAction(resource, name string) clientgotesting.DeleteActionImpl {
+	return clientgotesting.DeleteActionImpl{
+		ActionImpl: clientgotesting.ActionImpl{
+			Namespace: testNS,
+			Verb:      "delete",
+			Resource:  schema.GroupVersionResource{Resource: resource},
+		},
+		Name: name,
+	}
+}
+
+func serviceAccountCreatedEvent() string {
+	return Eventf(corev1.EventTypeNormal, prometheussourceRBACCreated, "ServiceAccount created: \"%s/%s\"", testNS, adapterName())
+}
+
+func roleCreatedEvent() string {
+	return Eventf(corev1.EventTypeNormal, prometheussourceRBACCreated, "Role created: \"%s/%s\"", testNS, adapterName())
+}
+
+func roleBindingCreatedEvent() string {
+	return Eventf(corev1.EventTypeNormal, prometheussourceRBACCreated, "RoleBinding created: \"%s/%s\"", testNS, adapterName())
+}
+
+func deprecatedDeploymentDeletedEvent() string {
+	return Eventf(corev1.EventTypeNormal, prometheussourceDeploymentDeleted, "Deprecated deployment removed: \"%s/%s\"", testNS, deprecatedDeploymentName())
+}
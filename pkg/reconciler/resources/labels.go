@@ -16,15 +16,26 @@ limitations under the License.
 
 package resources
 
+import "k8s.io/apimachinery/pkg/labels"
+
 const (
 	// controllerAgentName is the string used by this controller to identify
 	// itself when creating events.
 	controllerAgentName = "prometheus-source-controller"
+
+	// SourceNameLabel is the label holding the name of the source of the
+	// resources created for it.
+	SourceNameLabel = "knative-eventing-source-name"
 )
 
 func Labels(name string) map[string]string {
 	return map[string]string{
-		"knative-eventing-source":      controllerAgentName,
-		"knative-eventing-source-name": name,
+		"knative-eventing-source": controllerAgentName,
+		SourceNameLabel:           name,
 	}
 }
+
+// Selector selects the resources created for all the sources.
+func Selector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{"knative-eventing-source": controllerAgentName})
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package resources
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/rickb777/date/period"
 	v1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -27,6 +32,21 @@ import (
 	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
 )
 
+// outboxVolumeName and outboxMountPath are the volume holding the outbox of
+// the receive adapter and where it is mounted.
+const (
+	outboxVolumeName = "outbox"
+	outboxMountPath  = "/var/run/prometheus/outbox"
+)
+
+// podInfoVolumeName and podInfoMountPath are the downward API volume holding the annotations of the pod, among
+// which the mute-until annotation of the source, and where it is mounted.
+const (
+	podInfoVolumeName = "podinfo"
+	podInfoMountPath  = "/etc/podinfo"
+	annotationsFile   = "annotations"
+)
+
 // ReceiveAdapterArgs are the arguments needed to create a Prometheus Receive Adapter.
 // Every field is required.
 type ReceiveAdapterArgs struct {
@@ -36,16 +56,47 @@ type ReceiveAdapterArgs struct {
 	Labels         map[string]string
 	SinkURI        string
 	AdditionalEnvs []corev1.EnvVar
+	// ServerURL is the URL of the Prometheus server, the server reference
+	// of the source resolved if it has one.
+	ServerURL string
+	// PromQL is the query the Receive Adapter runs, the query of the source
+	// restricted to its namespace when namespace scoping is enforced.
+	PromQL string
+	// DeadLetterSinkURI is the resolved dead letter sink of the delivery
+	// spec of the source, empty if it has none.
+	DeadLetterSinkURI string
+}
+
+// SharedAdapterName is the name of the Deployment of the shared, multi-tenant, adapter in the system namespace,
+// which runs the Receive Adapters of the sources with the shared run mode.
+const SharedAdapterName = "prometheus-mt-adapter"
+
+// MakeReceiveAdapterName returns the name of the Receive Adapter Deployment of the source.
+func MakeReceiveAdapterName(source *v1alpha1.PrometheusSource) string {
+	return kmeta.ChildName(fmt.Sprintf("prometheussource-%s", source.Name), string(source.UID))
+}
+
+// ElectsLeader returns whether the replicas of the Receive Adapter Deployment of the source elect the one
+// evaluating the query with a Lease, which only a source with more than one replica needs.
+func ElectsLeader(source *v1alpha1.PrometheusSource) bool {
+	spec := &source.Spec
+	return (spec.RunMode == "" || spec.RunMode == v1alpha1.RunModeDeployment) && spec.Replicas != nil && *spec.Replicas > 1
 }
 
 // MakeReceiveAdapter generates (but does not insert into K8s) the Receive Adapter Deployment for
 // Prometheus sources.
 func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
 	replicas := int32(1)
-	ret := &v1.Deployment{
+	if args.Source.Spec.Replicas != nil {
+		replicas = *args.Source.Spec.Replicas
+	}
+	if args.Source.Spec.Suspend {
+		replicas = 0
+	}
+	return &v1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: args.Source.Namespace,
-			Name:      kmeta.ChildName(fmt.Sprintf("prometheussource-%s", args.Source.Name), string(args.Source.UID)),
+			Name:      MakeReceiveAdapterName(args.Source),
 			Labels:    args.Labels,
 			OwnerReferences: []metav1.OwnerReference{
 				*kmeta.NewControllerRef(args.Source),
@@ -56,35 +107,47 @@ func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
 				MatchLabels: args.Labels,
 			},
 			Replicas: &replicas,
-			Template: corev1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: args.Labels,
-				},
-				Spec: corev1.PodSpec{
-					ServiceAccountName: args.Source.Spec.ServiceAccountName,
-					Containers: []corev1.Container{
-						{
-							Name:  "receive-adapter",
-							Image: args.Image,
-							Env: append(
-								makeEnv(args.EventSource, args.SinkURI, &args.Source.Spec),
-								args.AdditionalEnvs...,
-							),
-						},
-					},
+			Template: makePodTemplate(args),
+		},
+	}
+}
+
+// makePodTemplate returns the template of the pods running the Receive Adapter.
+func makePodTemplate(args *ReceiveAdapterArgs) corev1.PodTemplateSpec {
+	ret := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: args.Labels,
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: MakeReceiveAdapterServiceAccountName(args.Source),
+			Containers: []corev1.Container{
+				{
+					Name:  "receive-adapter",
+					Image: args.Image,
+					Env: append(
+						append(MakeReceiveAdapterEnv(args), corev1.EnvVar{
+							Name:  "PROMETHEUS_ANNOTATIONS_FILE",
+							Value: podInfoMountPath + "/" + annotationsFile,
+						}),
+						args.AdditionalEnvs...,
+					),
 				},
 			},
 		},
 	}
 
+	if args.Source.Spec.Resources != nil {
+		ret.Spec.Containers[0].Resources = *args.Source.Spec.Resources
+	}
+
 	if args.Source.Spec.CACertConfigMap != "" {
-		ret.Spec.Template.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
+		ret.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{
 			{
 				Name:      "openshift-service-serving-signer-cabundle",
 				MountPath: "/etc/" + args.Source.Spec.CACertConfigMap + "/",
 			},
 		}
-		ret.Spec.Template.Spec.Volumes = []corev1.Volume{
+		ret.Spec.Volumes = []corev1.Volume{
 			{
 				Name: args.Source.Spec.CACertConfigMap,
 				VolumeSource: corev1.VolumeSource{
@@ -97,22 +160,71 @@ func MakeReceiveAdapter(args *ReceiveAdapterArgs) *v1.Deployment {
 			},
 		}
 	}
+
+	// The receive adapter reads the mute-until annotation, which the controller sets on its pods, from the
+	// annotations of its pod on every evaluation, so that setting it does not roll the pods out.
+	ret.Spec.Volumes = append(ret.Spec.Volumes, corev1.Volume{
+		Name: podInfoVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{{
+					Path:     annotationsFile,
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations"},
+				}},
+			},
+		},
+	})
+	ret.Spec.Containers[0].VolumeMounts = append(ret.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      podInfoVolumeName,
+		MountPath: podInfoMountPath,
+		ReadOnly:  true,
+	})
+
+	if outbox := args.Source.Spec.Outbox; outbox != nil {
+		volume := corev1.Volume{Name: outboxVolumeName}
+		if outbox.VolumeClaimName != "" {
+			volume.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: outbox.VolumeClaimName,
+			}
+		} else {
+			volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		}
+		ret.Spec.Volumes = append(ret.Spec.Volumes, volume)
+		ret.Spec.Containers[0].VolumeMounts = append(ret.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      outboxVolumeName,
+			MountPath: outboxMountPath,
+		})
+	}
 	return ret
 }
 
-func makeEnv(eventSource, sinkURI string, spec *v1alpha1.PrometheusSourceSpec) []corev1.EnvVar {
-	return []corev1.EnvVar{{
+// MakeReceiveAdapterPodAnnotations returns the annotations the controller sets on the pods of the Receive Adapter of
+// the source: its mute-until annotation, if any.
+func MakeReceiveAdapterPodAnnotations(source *v1alpha1.PrometheusSource) map[string]string {
+	muteUntil, ok := source.Annotations[v1alpha1.MuteUntilAnnotation]
+	if !ok {
+		return nil
+	}
+	return map[string]string{v1alpha1.MuteUntilAnnotation: muteUntil}
+}
+
+// MakeReceiveAdapterEnv returns the environment configuring the Receive Adapter of the source, which the shared
+// adapter also configures the Receive Adapters of the sources with the shared run mode with.
+func MakeReceiveAdapterEnv(args *ReceiveAdapterArgs) []corev1.EnvVar {
+	source := args.Source
+	spec := &source.Spec
+	env := []corev1.EnvVar{{
 		Name:  "SINK_URI",
-		Value: sinkURI,
+		Value: args.SinkURI,
 	}, {
 		Name:  "EVENT_SOURCE",
-		Value: eventSource,
+		Value: args.EventSource,
 	}, {
 		Name:  "PROMETHEUS_SERVER_URL",
-		Value: spec.ServerURL,
+		Value: args.ServerURL,
 	}, {
 		Name:  "PROMETHEUS_PROM_QL",
-		Value: spec.PromQL,
+		Value: args.PromQL,
 	}, {
 		Name:  "PROMETHEUS_AUTH_TOKEN_FILE",
 		Value: spec.AuthTokenFile,
@@ -122,9 +234,21 @@ func makeEnv(eventSource, sinkURI string, spec *v1alpha1.PrometheusSourceSpec) [
 	}, {
 		Name:  "PROMETHEUS_SCHEDULE",
 		Value: spec.Schedule,
+	}, {
+		Name:  "PROMETHEUS_TIME_ZONE",
+		Value: spec.TimeZone,
 	}, {
 		Name:  "PROMETHEUS_STEP",
 		Value: spec.Step,
+	}, {
+		Name:  "PROMETHEUS_QUERY_TIMEOUT",
+		Value: spec.QueryTimeout,
+	}, {
+		Name:  "PROMETHEUS_EVENT_TYPE",
+		Value: spec.GetEventType(),
+	}, {
+		Name:  "PROMETHEUS_EVENT_SUBJECT",
+		Value: spec.Subject,
 	}, {
 		Name: "NAMESPACE",
 		ValueFrom: &corev1.EnvVarSource{
@@ -132,8 +256,231 @@ func makeEnv(eventSource, sinkURI string, spec *v1alpha1.PrometheusSourceSpec) [
 				FieldPath: "metadata.namespace",
 			},
 		},
+	}, {
+		Name:  "NAME",
+		Value: source.Name,
+	}, {
+		Name:  "PROMETHEUS_SOURCE_UID",
+		Value: string(source.UID),
 	}, {
 		Name:  "METRICS_DOMAIN",
 		Value: "knative.dev/eventing",
 	}}
+
+	if limits := spec.Limits; limits != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_MAX_SERIES",
+			Value: strconv.FormatInt(limits.MaxSeries, 10),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_MAX_SAMPLES",
+			Value: strconv.FormatInt(limits.MaxSamples, 10),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_MAX_RESPONSE_BYTES",
+			Value: strconv.FormatInt(limits.MaxResponseBytes, 10),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_OVERFLOW_POLICY",
+			Value: string(limits.OverflowPolicy),
+		})
+	}
+	if spec.CloudEventOverrides != nil {
+		// The adapter library applies the overrides to every event sent.
+		ceOverrides, err := json.Marshal(spec.CloudEventOverrides)
+		if err == nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "K_CE_OVERRIDES",
+				Value: string(ceOverrides),
+			})
+		}
+	}
+	if len(spec.LabelExtensions) > 0 {
+		extensions := make([]string, 0, len(spec.LabelExtensions))
+		for i := range spec.LabelExtensions {
+			e := &spec.LabelExtensions[i]
+			extensions = append(extensions, e.GetName()+":"+e.Label)
+		}
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_LABEL_EXTENSIONS",
+			Value: strings.Join(extensions, ","),
+		})
+	}
+	if len(spec.PartitionKeyLabels) > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_PARTITION_KEY_LABELS",
+			Value: strings.Join(spec.PartitionKeyLabels, ","),
+		})
+	}
+	if tmpl := spec.Template; tmpl != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_TEMPLATE_TEXT",
+			Value: tmpl.Text,
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_TEMPLATE_JSON",
+			Value: tmpl.JSON,
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_DATA_CONTENT_TYPE",
+			Value: tmpl.DataContentType,
+		})
+	}
+	if len(spec.RelabelConfigs) > 0 {
+		relabelConfigs, err := json.Marshal(spec.RelabelConfigs)
+		if err == nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_RELABEL_CONFIGS",
+				Value: string(relabelConfigs),
+			})
+		}
+	}
+	if spec.DataFormat != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_DATA_FORMAT",
+			Value: string(spec.DataFormat),
+		})
+	}
+	if spec.SeriesPerEvent > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_SERIES_PER_EVENT",
+			Value: strconv.FormatInt(spec.SeriesPerEvent, 10),
+		})
+	}
+	if spec.OnEmptyResult != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_ON_EMPTY_RESULT",
+			Value: string(spec.OnEmptyResult),
+		})
+	}
+	if spec.NoDataThreshold > 0 {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_NO_DATA_THRESHOLD",
+			Value: strconv.FormatInt(spec.NoDataThreshold, 10),
+		})
+	}
+	if delivery := spec.Delivery; delivery != nil {
+		if delivery.Retry != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_DELIVERY_RETRY",
+				Value: strconv.FormatInt(int64(*delivery.Retry), 10),
+			})
+		}
+		if delivery.BackoffPolicy != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_DELIVERY_BACKOFF_POLICY",
+				Value: string(*delivery.BackoffPolicy),
+			})
+		}
+		if delivery.BackoffDelay != nil {
+			// The delay is an ISO 8601 duration, validated by the webhook.
+			if p, err := period.Parse(*delivery.BackoffDelay); err == nil {
+				env = append(env, corev1.EnvVar{
+					Name:  "PROMETHEUS_DELIVERY_BACKOFF_DELAY",
+					Value: p.DurationApprox().String(),
+				})
+			}
+		}
+	}
+	if outbox := spec.Outbox; outbox != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_OUTBOX_DIR",
+			Value: outboxMountPath,
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_OUTBOX_MAX_EVENTS",
+			Value: strconv.FormatInt(outbox.MaxEvents, 10),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_OUTBOX_MAX_BYTES",
+			Value: strconv.FormatInt(outbox.MaxBytes, 10),
+		})
+		if outbox.MaxAge != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_OUTBOX_MAX_AGE",
+				Value: outbox.MaxAge.Duration.String(),
+			})
+		}
+	}
+	if rateLimit := spec.RateLimit; rateLimit != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_RATE_LIMIT_EVENTS",
+			Value: strconv.FormatInt(rateLimit.Events, 10),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_RATE_LIMIT_BURST",
+			Value: strconv.FormatInt(rateLimit.Burst, 10),
+		})
+		if rateLimit.Interval != nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_RATE_LIMIT_INTERVAL",
+				Value: rateLimit.Interval.Duration.String(),
+			})
+		}
+	}
+	if spec.AlignToStep {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_ALIGN_TO_STEP",
+			Value: "true",
+		})
+	}
+	if spec.EvaluationDelay != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_EVALUATION_DELAY",
+			Value: spec.EvaluationDelay.Duration.String(),
+		})
+	}
+	if spec.Jitter != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_JITTER",
+			Value: spec.Jitter.Duration.String(),
+		})
+	}
+	if spec.StartingDeadline != nil {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_STARTING_DEADLINE",
+			Value: spec.StartingDeadline.Duration.String(),
+		})
+	}
+	// The receive adapter catches up from the suspension of the source when
+	// it starts after it was resumed.
+	if status := &source.Status; !spec.Suspend && spec.RunMode != v1alpha1.RunModeCronJob && status.LastSuspendTime != nil && status.LastResumeTime != nil &&
+		status.LastResumeTime.After(status.LastSuspendTime.Time) {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_SUSPENDED_AT",
+			Value: status.LastSuspendTime.UTC().Format(time.RFC3339),
+		}, corev1.EnvVar{
+			Name:  "PROMETHEUS_RESUMED_AT",
+			Value: status.LastResumeTime.UTC().Format(time.RFC3339),
+		})
+	}
+	if len(spec.MuteWindows) > 0 {
+		muteWindows, err := json.Marshal(spec.MuteWindows)
+		if err == nil {
+			env = append(env, corev1.EnvVar{
+				Name:  "PROMETHEUS_MUTE_WINDOWS",
+				Value: string(muteWindows),
+			})
+		}
+	}
+	if spec.RunMode == v1alpha1.RunModeCronJob {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_RUN_ONCE",
+			Value: "true",
+		})
+	}
+	// The replicas of the receive adapter elect the one evaluating the query
+	// with a Lease named after its Deployment.
+	if ElectsLeader(source) {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_LEASE_NAME",
+			Value: MakeReceiveAdapterName(source),
+		}, corev1.EnvVar{
+			Name: "POD_NAME",
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "metadata.name",
+				},
+			},
+		})
+	}
+	if args.DeadLetterSinkURI != "" {
+		env = append(env, corev1.EnvVar{
+			Name:  "PROMETHEUS_DEAD_LETTER_SINK",
+			Value: args.DeadLetterSinkURI,
+		})
+	}
+	return env
 }
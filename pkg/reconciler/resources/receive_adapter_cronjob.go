@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	"math"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+	"knative.dev/pkg/ptr"
+)
+
+// MakeReceiveAdapterCronJob generates (but does not insert into K8s) the Receive Adapter CronJob for
+// Prometheus sources with the cronJob run mode. Its Jobs evaluate the query once, at the tick of the
+// schedule they are created for, and exit.
+func MakeReceiveAdapterCronJob(args *ReceiveAdapterArgs) *batchv1.CronJob {
+	spec := &args.Source.Spec
+	template := makePodTemplate(args)
+	// The Job retries a failed evaluation itself.
+	template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	// The CronJob names its Jobs after the tick they are created for, which they evaluate.
+	template.Spec.Containers[0].Env = append(template.Spec.Containers[0].Env, corev1.EnvVar{
+		Name: "PROMETHEUS_JOB_NAME",
+		ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['job-name']"},
+		},
+	})
+	// The pods of the Jobs to come start with the mute-until annotation, updating the Job template rolls
+	// nothing out.
+	template.Annotations = MakeReceiveAdapterPodAnnotations(args.Source)
+
+	ret := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: args.Source.Namespace,
+			Name:      MakeReceiveAdapterName(args.Source),
+			Labels:    args.Labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(args.Source),
+			},
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule: spec.Schedule,
+			// Like the Deployment, the query is evaluated one tick at a time.
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			Suspend:           ptr.Bool(spec.Suspend),
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: args.Labels,
+				},
+				Spec: batchv1.JobSpec{
+					Template: template,
+				},
+			},
+		},
+	}
+	if spec.StartingDeadline != nil {
+		ret.Spec.StartingDeadlineSeconds = ptr.Int64(int64(math.Ceil(spec.StartingDeadline.Seconds())))
+	}
+	return ret
+}
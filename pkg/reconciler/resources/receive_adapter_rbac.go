@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resources
+
+import (
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"knative.dev/pkg/kmeta"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// MakeReceiveAdapterServiceAccountName returns the service account the Receive Adapter of the source runs as: the
+// service account of the source, or a service account of its own if the source has none, so that the Role of the
+// Receive Adapter is not granted to the default service account of the namespace.
+func MakeReceiveAdapterServiceAccountName(source *v1alpha1.PrometheusSource) string {
+	if source.Spec.ServiceAccountName != "" {
+		return source.Spec.ServiceAccountName
+	}
+	return MakeReceiveAdapterName(source)
+}
+
+// MakeReceiveAdapterServiceAccount generates (but does not insert into K8s) the service account of its own the
+// Receive Adapter of a source without a service account runs as.
+func MakeReceiveAdapterServiceAccount(source *v1alpha1.PrometheusSource) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: source.Namespace,
+			Name:      MakeReceiveAdapterName(source),
+			Labels:    Labels(source.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(source),
+			},
+		},
+	}
+}
+
+// MakeReceiveAdapterRole generates (but does not insert into K8s) the Role granting the Receive Adapter of the
+// source what it needs in the namespace of the source: recording Kubernetes events on the source and, when it has
+// more than one replica, electing the replica evaluating the query with its Lease.
+func MakeReceiveAdapterRole(source *v1alpha1.PrometheusSource) *rbacv1.Role {
+	rules := []rbacv1.PolicyRule{{
+		APIGroups: []string{""},
+		Resources: []string{"events"},
+		Verbs:     []string{"create", "patch"},
+	}}
+	if ElectsLeader(source) {
+		// The creation of a Lease cannot be restricted to its name.
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{coordinationv1.GroupName},
+			Resources: []string{"leases"},
+			Verbs:     []string{"create"},
+		}, rbacv1.PolicyRule{
+			APIGroups:     []string{coordinationv1.GroupName},
+			Resources:     []string{"leases"},
+			ResourceNames: []string{MakeReceiveAdapterName(source)},
+			Verbs:         []string{"get", "update"},
+		})
+	}
+	return &rbacv1.Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: source.Namespace,
+			Name:      MakeReceiveAdapterName(source),
+			Labels:    Labels(source.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(source),
+			},
+		},
+		Rules: rules,
+	}
+}
+
+// MakeReceiveAdapterRoleBinding generates (but does not insert into K8s) the RoleBinding granting the Role of the
+// Receive Adapter to the service account it runs as.
+func MakeReceiveAdapterRoleBinding(source *v1alpha1.PrometheusSource) *rbacv1.RoleBinding {
+	return &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: source.Namespace,
+			Name:      MakeReceiveAdapterName(source),
+			Labels:    Labels(source.Name),
+			OwnerReferences: []metav1.OwnerReference{
+				*kmeta.NewControllerRef(source),
+			},
+		},
+		Subjects: []rbacv1.Subject{{
+			Kind:      rbacv1.ServiceAccountKind,
+			Namespace: source.Namespace,
+			Name:      MakeReceiveAdapterServiceAccountName(source),
+		}},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     MakeReceiveAdapterName(source),
+		},
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakekubeclientset "k8s.io/client-go/kubernetes/fake"
+	appsv1listers "k8s.io/client-go/listers/apps/v1"
+	batchv1listers "k8s.io/client-go/listers/batch/v1"
+	coordinationv1listers "k8s.io/client-go/listers/coordination/v1"
+	corev1listers "k8s.io/client-go/listers/core/v1"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"knative.dev/pkg/reconciler/testing"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+	fakeprometheusclientset "knative.dev/eventing-prometheus/pkg/client/clientset/versioned/fake"
+	sourcelisters "knative.dev/eventing-prometheus/pkg/client/listers/sources/v1alpha1"
+)
+
+var clientSetSchemes = []func(*runtime.Scheme) error{
+	fakekubeclientset.AddToScheme,
+	fakeprometheusclientset.AddToScheme,
+}
+
+// Listers serves the objects of a table test to the listers of the
+// reconciler.
+type Listers struct {
+	sorter testing.ObjectSorter
+}
+
+// NewScheme returns the scheme of the objects the reconciler handles.
+func NewScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+
+	for _, addTo := range clientSetSchemes {
+		addTo(scheme)
+	}
+	return scheme
+}
+
+// NewListers returns the listers of the objects.
+func NewListers(objs []runtime.Object) Listers {
+	ls := Listers{
+		sorter: testing.NewObjectSorter(NewScheme()),
+	}
+
+	ls.sorter.AddObjects(objs...)
+
+	return ls
+}
+
+func (l *Listers) indexerFor(obj runtime.Object) cache.Indexer {
+	return l.sorter.IndexerForObjectType(obj)
+}
+
+// GetKubeObjects returns the Kubernetes objects, which the fake Kubernetes
+// client is seeded with.
+func (l *Listers) GetKubeObjects() []runtime.Object {
+	return l.sorter.ObjectsForSchemeFunc(fakekubeclientset.AddToScheme)
+}
+
+// GetPrometheusObjects returns the PrometheusSources, which the fake
+// PrometheusSource client is seeded with.
+func (l *Listers) GetPrometheusObjects() []runtime.Object {
+	return l.sorter.ObjectsForSchemeFunc(fakeprometheusclientset.AddToScheme)
+}
+
+func (l *Listers) GetPrometheusSourceLister() sourcelisters.PrometheusSourceLister {
+	return sourcelisters.NewPrometheusSourceLister(l.indexerFor(&v1alpha1.PrometheusSource{}))
+}
+
+func (l *Listers) GetDeploymentLister() appsv1listers.DeploymentLister {
+	return appsv1listers.NewDeploymentLister(l.indexerFor(&appsv1.Deployment{}))
+}
+
+func (l *Listers) GetCronJobLister() batchv1listers.CronJobLister {
+	return batchv1listers.NewCronJobLister(l.indexerFor(&batchv1.CronJob{}))
+}
+
+func (l *Listers) GetRoleLister() rbacv1listers.RoleLister {
+	return rbacv1listers.NewRoleLister(l.indexerFor(&rbacv1.Role{}))
+}
+
+func (l *Listers) GetRoleBindingLister() rbacv1listers.RoleBindingLister {
+	return rbacv1listers.NewRoleBindingLister(l.indexerFor(&rbacv1.RoleBinding{}))
+}
+
+func (l *Listers) GetEventLister() corev1listers.EventLister {
+	return corev1listers.NewEventLister(l.indexerFor(&corev1.Event{}))
+}
+
+func (l *Listers) GetPodLister() corev1listers.PodLister {
+	return corev1listers.NewPodLister(l.indexerFor(&corev1.Pod{}))
+}
+
+func (l *Listers) GetServiceAccountLister() corev1listers.ServiceAccountLister {
+	return corev1listers.NewServiceAccountLister(l.indexerFor(&corev1.ServiceAccount{}))
+}
+
+func (l *Listers) GetLeaseLister() coordinationv1listers.LeaseLister {
+	return coordinationv1listers.NewLeaseLister(l.indexerFor(&coordinationv1.Lease{}))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Knative Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testing
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"knative.dev/eventing-prometheus/pkg/apis/sources/v1alpha1"
+)
+
+// PrometheusSourceOption enables further configuration of a PrometheusSource.
+type PrometheusSourceOption func(*v1alpha1.PrometheusSource)
+
+// NewPrometheusSource creates a PrometheusSource with PrometheusSourceOptions.
+func NewPrometheusSource(name, namespace string, o ...PrometheusSourceOption) *v1alpha1.PrometheusSource {
+	s := &v1alpha1.PrometheusSource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+	for _, opt := range o {
+		opt(s)
+	}
+	return s
+}
+
+func WithPrometheusSourceUID(uid string) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.UID = types.UID(uid)
+	}
+}
+
+func WithPrometheusSourceSpec(spec v1alpha1.PrometheusSourceSpec) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Spec = spec
+	}
+}
+
+func WithInitPrometheusSourceConditions(s *v1alpha1.PrometheusSource) {
+	s.Status.InitializeConditions()
+}
+
+func WithPrometheusSourceSink(uri *apis.URL) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkSink(uri)
+	}
+}
+
+func WithPrometheusSourceDeadLetterSink(uri *apis.URL) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkDeadLetterSinkResolved(uri)
+	}
+}
+
+func WithPrometheusSourceServerAllowed(s *v1alpha1.PrometheusSource) {
+	s.Status.MarkServerAllowed()
+}
+
+func WithPrometheusSourceServerNotAllowed(messageFormat string, messageA ...interface{}) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkServerNotAllowed("PolicyViolation", messageFormat, messageA...)
+	}
+}
+
+func WithPrometheusSourceValidQuery(s *v1alpha1.PrometheusSource) {
+	s.Status.MarkValidQuery()
+}
+
+func WithPrometheusSourceInvalidQuery(messageFormat string, messageA ...interface{}) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkInvalidQuery("NotScopable", messageFormat, messageA...)
+	}
+}
+
+func WithPrometheusSourceValidSchedule(s *v1alpha1.PrometheusSource) {
+	s.Status.MarkValidSchedule()
+}
+
+// WithPrometheusSourceSuspended marks the source suspended at the time.
+func WithPrometheusSourceSuspended(t metav1.Time) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkSuspended(t)
+	}
+}
+
+// WithPrometheusSourceQuery sets the server URL and the query the receive
+// adapter runs in the status.
+func WithPrometheusSourceQuery(serverURL *apis.URL, promQL string) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.ServerURL = serverURL
+		s.Status.PromQL = promQL
+	}
+}
+
+func WithPrometheusSourceDeployment(d *appsv1.Deployment) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.PropagateDeploymentAvailability(d)
+	}
+}
+
+func WithPrometheusSourceNotDeployed(reason, messageFormat string, messageA ...interface{}) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkNotDeployed(reason, messageFormat, messageA...)
+	}
+}
+
+func WithPrometheusSourceCronJob(cj *batchv1.CronJob) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.PropagateCronJobStatus(cj)
+	}
+}
+
+func WithPrometheusSourceLeaderElected(holder string) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkLeaderElected(holder)
+	}
+}
+
+func WithPrometheusSourceEventAttributes(attrs ...duckv1.CloudEventAttributes) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.CloudEventAttributes = attrs
+	}
+}
+
+func WithPrometheusSourceRateLimited(messageFormat string, messageA ...interface{}) PrometheusSourceOption {
+	return func(s *v1alpha1.PrometheusSource) {
+		s.Status.MarkRateLimited("EventsSuppressed", messageFormat, messageA...)
+	}
+}
+
+func WithPrometheusSourceWithinRateLimit(s *v1alpha1.PrometheusSource) {
+	s.Status.MarkWithinRateLimit()
+}